@@ -0,0 +1,252 @@
+package lashes
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/client"
+	"golang.org/x/time/rate"
+)
+
+// minDecreaseInterval stops a burst of throttled responses that were all in
+// flight together from cutting the same limit many times over
+const minDecreaseInterval = time.Second
+
+// blockSweepInterval is how often expired sit-out windows are cleared out
+const blockSweepInterval = time.Minute
+
+// AdaptiveRateConfig tunes how rate limits react to throttling responses.
+// Limits are cut multiplicatively on a 429, or a 503 with Retry-After, and
+// recover additively while requests succeed (AIMD).
+type AdaptiveRateConfig struct {
+	// DecreaseFactor multiplies the limit on each throttling response
+	DecreaseFactor float64
+
+	// IncreaseStep is added to the limit, in requests per second, after each
+	// RecoveryInterval of successful requests. Zero uses a tenth of the original limit.
+	IncreaseStep float64
+
+	// RecoveryInterval is the minimum time between two increases
+	RecoveryInterval time.Duration
+
+	// MinRequestsPerSecond is the floor the limit never drops below
+	MinRequestsPerSecond float64
+
+	// DefaultPenalty is how long a proxy sits out for a host after a 429
+	// without a Retry-After header. A negative value disables it.
+	DefaultPenalty time.Duration
+}
+
+// DefaultAdaptiveRateConfig returns sensible defaults for adaptive rate limiting
+func DefaultAdaptiveRateConfig() AdaptiveRateConfig {
+	return AdaptiveRateConfig{
+		DecreaseFactor:       0.5,
+		RecoveryInterval:     time.Second * 10,
+		MinRequestsPerSecond: 0.01,
+		DefaultPenalty:       time.Second * 5,
+	}
+}
+
+// withDefaults fills in unset fields from DefaultAdaptiveRateConfig
+func (c AdaptiveRateConfig) withDefaults() AdaptiveRateConfig {
+	defaults := DefaultAdaptiveRateConfig()
+	if c.DecreaseFactor <= 0 || c.DecreaseFactor >= 1 {
+		c.DecreaseFactor = defaults.DecreaseFactor
+	}
+	if c.RecoveryInterval <= 0 {
+		c.RecoveryInterval = defaults.RecoveryInterval
+	}
+	if c.MinRequestsPerSecond <= 0 {
+		c.MinRequestsPerSecond = defaults.MinRequestsPerSecond
+	}
+	if c.DefaultPenalty == 0 {
+		c.DefaultPenalty = defaults.DefaultPenalty
+	}
+	return c
+}
+
+// adaptiveState tracks a limit that has been cut below its configured value
+type adaptiveState struct {
+	limiter    *rate.Limiter
+	base       rate.Limit
+	lastChange time.Time
+}
+
+// adaptiveLimits applies AIMD adjustments and sit-out windows to limiters.
+// Both are keyed like host limiters, with an empty host for a proxy's own budget.
+type adaptiveLimits struct {
+	config AdaptiveRateConfig
+
+	mu      sync.Mutex
+	states  map[string]*adaptiveState
+	blocked map[string]time.Time
+	swept   time.Time
+}
+
+// newAdaptiveLimits creates an empty set of adjustments
+func newAdaptiveLimits(config AdaptiveRateConfig) *adaptiveLimits {
+	return &adaptiveLimits{
+		config:  config.withDefaults(),
+		states:  make(map[string]*adaptiveState),
+		blocked: make(map[string]time.Time),
+		swept:   time.Now(),
+	}
+}
+
+// adjusted reports whether the limit stored under key is currently reduced
+func (a *adaptiveLimits) adjusted(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.states[key]
+	return ok
+}
+
+// block starts or extends a sit-out window for key
+func (a *adaptiveLimits) block(key string, penalty time.Duration) {
+	if penalty <= 0 {
+		return
+	}
+
+	now := time.Now()
+	until := now.Add(penalty)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if until.After(a.blocked[key]) {
+		a.blocked[key] = until
+	}
+
+	// Windows for hosts that are never asked about again would otherwise stay forever
+	if now.Sub(a.swept) >= blockSweepInterval {
+		a.swept = now
+		for key, until := range a.blocked {
+			if !until.After(now) {
+				delete(a.blocked, key)
+			}
+		}
+	}
+}
+
+// drop forgets the adjustment of a limiter that has been evicted
+func (a *adaptiveLimits) drop(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.states, key)
+}
+
+// decrease cuts the rate of the limiter stored under key
+func (a *adaptiveLimits) decrease(key string, limiter *rate.Limiter) {
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	current := limiter.Limit()
+	if current == rate.Inf || current <= 0 {
+		// Nothing to scale; the sit-out window is the only reaction
+		return
+	}
+
+	state, ok := a.states[key]
+	if !ok || state.limiter != limiter {
+		state = &adaptiveState{limiter: limiter, base: current}
+		a.states[key] = state
+	} else if now.Sub(state.lastChange) < minDecreaseInterval {
+		return
+	}
+
+	reduced := max(current*rate.Limit(a.config.DecreaseFactor), rate.Limit(a.config.MinRequestsPerSecond))
+	limiter.SetLimitAt(now, reduced)
+	state.lastChange = now
+}
+
+// increase raises a reduced limit by one step once per recovery interval,
+// forgetting the adjustment when it is back at its original value
+func (a *adaptiveLimits) increase(key string) {
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	state, ok := a.states[key]
+	if !ok || now.Sub(state.lastChange) < a.config.RecoveryInterval {
+		return
+	}
+
+	step := rate.Limit(a.config.IncreaseStep)
+	if step <= 0 {
+		step = state.base / 10
+	}
+
+	raised := min(state.limiter.Limit()+step, state.base)
+	state.limiter.SetLimitAt(now, raised)
+	state.lastChange = now
+
+	if raised >= state.base {
+		delete(a.states, key)
+	}
+}
+
+// blockedFor returns how much of a sit-out window is left for a proxy and host,
+// counting windows that apply to the proxy as a whole
+func (a *adaptiveLimits) blockedFor(proxyID, host string) time.Duration {
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var remaining time.Duration
+	for _, key := range []string{hostLimiterKey(proxyID, ""), hostLimiterKey(proxyID, host)} {
+		until, ok := a.blocked[key]
+		if !ok {
+			continue
+		}
+		if !until.After(now) {
+			delete(a.blocked, key)
+			continue
+		}
+		remaining = max(remaining, until.Sub(now))
+	}
+	return remaining
+}
+
+// forget drops every adjustment belonging to a proxy
+func (a *adaptiveLimits) forget(proxyID string) {
+	prefix := hostLimiterKey(proxyID, "")
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for key := range a.states {
+		if strings.HasPrefix(key, prefix) {
+			delete(a.states, key)
+		}
+	}
+	for key := range a.blocked {
+		if strings.HasPrefix(key, prefix) {
+			delete(a.blocked, key)
+		}
+	}
+}
+
+// isThrottled reports whether a response asks the client to slow down,
+// and for how long if the server said so
+func isThrottled(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	retryAfter, hasRetryAfter := client.RetryAfter(resp)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return retryAfter, true
+	case http.StatusServiceUnavailable:
+		// A bare 503 is as likely to be a broken proxy as a throttling server
+		return retryAfter, hasRetryAfter
+	default:
+		return 0, false
+	}
+}
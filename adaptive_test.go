@@ -0,0 +1,171 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestIsThrottled(t *testing.T) {
+	testCases := []struct {
+		name          string
+		status        int
+		retryAfter    string
+		wantThrottled bool
+		wantDelay     time.Duration
+	}{
+		{"429 without Retry-After", http.StatusTooManyRequests, "", true, 0},
+		{"429 with Retry-After", http.StatusTooManyRequests, "3", true, 3 * time.Second},
+		{"503 without Retry-After", http.StatusServiceUnavailable, "", false, 0},
+		{"503 with Retry-After", http.StatusServiceUnavailable, "2", true, 2 * time.Second},
+		{"200", http.StatusOK, "", false, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tc.status, Header: http.Header{}}
+			if tc.retryAfter != "" {
+				resp.Header.Set("Retry-After", tc.retryAfter)
+			}
+
+			delay, throttled := isThrottled(resp)
+			if throttled != tc.wantThrottled || delay != tc.wantDelay {
+				t.Errorf("isThrottled() = %v, %v; want %v, %v", delay, throttled, tc.wantDelay, tc.wantThrottled)
+			}
+		})
+	}
+}
+
+func TestAdaptiveRateLimit(t *testing.T) {
+	t.Run("Multiplicative decrease and additive recovery", func(t *testing.T) {
+		limiter := NewProxyRateLimiterFromConfig(RateLimitConfig{
+			RequestsPerSecond: 10,
+			Burst:             10,
+			Adaptive: AdaptiveRateConfig{
+				DecreaseFactor:   0.5,
+				RecoveryInterval: 5 * time.Millisecond,
+				DefaultPenalty:   -1,
+			},
+		})
+		proxy := &Proxy{ID: "p"}
+
+		limiter.ReportThrottled(proxy.ID, "", 0)
+		limiter.ReportThrottled(proxy.ID, "", 0)
+		if got := limiter.GetLimiter(proxy.ID).Limit(); got != 5 {
+			t.Fatalf("limit after throttling = %v, want 5 (one cut per burst of 429s)", got)
+		}
+
+		// Recovery waits for the interval to pass
+		limiter.ReportSuccess(proxy.ID, "")
+		if got := limiter.GetLimiter(proxy.ID).Limit(); got != 5 {
+			t.Errorf("limit right after throttling = %v, want 5", got)
+		}
+
+		for want := rate.Limit(6); want <= 10; want++ {
+			time.Sleep(10 * time.Millisecond)
+			limiter.ReportSuccess(proxy.ID, "")
+			if got := limiter.GetLimiter(proxy.ID).Limit(); got != want {
+				t.Fatalf("limit during recovery = %v, want %v", got, want)
+			}
+		}
+		if limiter.adaptive.adjusted(hostLimiterKey(proxy.ID, "")) {
+			t.Error("adjustment kept after the limit recovered")
+		}
+		if !limiter.ready(proxy, "") {
+			t.Error("ready() = false, want true without a sit-out window")
+		}
+	})
+
+	t.Run("Throttled proxies sit out for the host", func(t *testing.T) {
+		limiter := NewProxyRateLimiterFromConfig(RateLimitConfig{})
+		proxy := &Proxy{ID: "p"}
+
+		limiter.ReportThrottled(proxy.ID, "a.test", 50*time.Millisecond)
+
+		if limiter.ready(proxy, "a.test") || limiter.allow(proxy, "a.test") {
+			t.Error("proxy is selectable for the throttling host, want it to sit out")
+		}
+		if !limiter.ready(proxy, "b.test") {
+			t.Error("proxy is not selectable for another host")
+		}
+		if delay, ok := limiter.delay(proxy, "a.test"); !ok || delay <= 0 || delay > 50*time.Millisecond {
+			t.Errorf("delay() = %v, %v; want the rest of the sit-out window", delay, ok)
+		}
+
+		time.Sleep(60 * time.Millisecond)
+		if !limiter.ready(proxy, "a.test") {
+			t.Error("proxy still sitting out after the window passed")
+		}
+	})
+
+	t.Run("Adjustments and sit-outs stay bounded", func(t *testing.T) {
+		limiter := NewProxyRateLimiterFromConfig(RateLimitConfig{
+			HostRequestsPerSecond: 10,
+			HostBurst:             10,
+			MaxHostLimiters:       2,
+		})
+
+		for _, host := range []string{"a.test", "b.test", "c.test", "d.test"} {
+			limiter.ReportThrottled("p", host, time.Millisecond)
+		}
+
+		limiter.adaptive.mu.Lock()
+		states := len(limiter.adaptive.states)
+		limiter.adaptive.swept = time.Time{}
+		limiter.adaptive.mu.Unlock()
+		if states > limiter.hosts.len() {
+			t.Errorf("%d adjustments kept for %d host limiters", states, limiter.hosts.len())
+		}
+
+		// Expired windows go on the next sweep, even for hosts never asked about again
+		time.Sleep(5 * time.Millisecond)
+		limiter.ReportThrottled("p", "e.test", time.Minute)
+
+		limiter.adaptive.mu.Lock()
+		defer limiter.adaptive.mu.Unlock()
+		if len(limiter.adaptive.blocked) != 1 {
+			t.Errorf("%d sit-out windows kept, want only the unexpired one", len(limiter.adaptive.blocked))
+		}
+	})
+
+	t.Run("Rotating client reacts to Retry-After", func(t *testing.T) {
+		ctx := context.Background()
+
+		opts := DefaultOptions()
+		opts.ValidateOnStart = false
+		opts.MaxRetries = 0
+		opts.RateLimit = &RateLimitConfig{}
+
+		r, err := newRotator(opts)
+		if err != nil {
+			t.Fatalf("newRotator failed: %v", err)
+		}
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		t.Cleanup(srv.Close)
+		if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+
+		resp, err := r.RotatingClient().Get("http://a.test/")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if _, err := r.nextProxy(ctx, selection{TargetHost: "a.test"}); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("selection for the throttling host: error = %v, want %v", err, ErrRateLimited)
+		}
+		if _, err := r.nextProxy(ctx, selection{TargetHost: "b.test"}); err != nil {
+			t.Errorf("selection for another host failed: %v", err)
+		}
+	})
+}
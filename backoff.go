@@ -0,0 +1,113 @@
+package lashes
+
+import (
+	"crypto/rand"
+	"math"
+	"math/big"
+	"time"
+)
+
+// BackoffStrategy defines how retry delays should be calculated
+type BackoffStrategy interface {
+	// NextDelay returns the delay to wait before the next retry attempt
+	NextDelay(attempt int) time.Duration
+}
+
+// SequenceBackoff is implemented by backoff strategies whose next delay depends
+// on the previous one. The rotating client calls NextDelayAfter instead of
+// NextDelay, passing the delay it returned for the request's last retry, or
+// zero before the first.
+type SequenceBackoff interface {
+	BackoffStrategy
+
+	// NextDelayAfter returns the delay to wait before the next retry attempt
+	NextDelayAfter(attempt int, previous time.Duration) time.Duration
+}
+
+// ConstantBackoff waits the same delay before every retry
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+// NextDelay implements BackoffStrategy
+func (b ConstantBackoff) NextDelay(attempt int) time.Duration {
+	return b.Delay
+}
+
+// ExponentialBackoff multiplies the delay by Multiplier after every attempt,
+// capped at Max. A zero Multiplier defaults to 2.
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+}
+
+// NextDelay implements BackoffStrategy
+func (b ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	multiplier := b.Multiplier
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(attempt-1))
+	return capDelay(delay, b.Max)
+}
+
+// DecorrelatedJitterBackoff picks each delay at random between Base and three
+// times the previous delay, capped at Max, so delays grow like exponential
+// backoff while clients that failed together drift apart instead of retrying
+// in lockstep.
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+var _ SequenceBackoff = DecorrelatedJitterBackoff{}
+
+// NextDelay implements BackoffStrategy. Without the previous delay it can only
+// pick a delay as if the previous one was Base; use NextDelayAfter to get
+// growing delays.
+func (b DecorrelatedJitterBackoff) NextDelay(attempt int) time.Duration {
+	return b.NextDelayAfter(attempt, 0)
+}
+
+// NextDelayAfter implements SequenceBackoff
+func (b DecorrelatedJitterBackoff) NextDelayAfter(attempt int, previous time.Duration) time.Duration {
+	previous = max(previous, b.Base)
+
+	ceiling := capDelay(float64(previous)*3, b.Max)
+	if ceiling <= b.Base {
+		return ceiling
+	}
+
+	return b.Base + randomDuration(ceiling-b.Base+1)
+}
+
+// capDelay converts a computed delay to a duration no larger than max (if set)
+func capDelay(delay float64, max time.Duration) time.Duration {
+	if max > 0 && delay > float64(max) {
+		return max
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// randomDuration returns a random duration in [0, n) using crypto/rand
+func randomDuration(n time.Duration) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		// Fall back to the midpoint rather than failing a retry
+		return n / 2
+	}
+	return time.Duration(v.Int64())
+}
@@ -0,0 +1,83 @@
+package lashes
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConstantBackoff(t *testing.T) {
+	b := ConstantBackoff{Delay: 250 * time.Millisecond}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if got := b.NextDelay(attempt); got != 250*time.Millisecond {
+			t.Errorf("NextDelay(%d) = %v, want %v", attempt, got, 250*time.Millisecond)
+		}
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff{
+		Initial: 100 * time.Millisecond,
+		Max:     time.Second,
+	}
+
+	testCases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 100 * time.Millisecond},
+		{attempt: 2, want: 200 * time.Millisecond},
+		{attempt: 3, want: 400 * time.Millisecond},
+		{attempt: 4, want: 800 * time.Millisecond},
+		{attempt: 5, want: time.Second}, // capped
+	}
+
+	for _, tc := range testCases {
+		if got := b.NextDelay(tc.attempt); got != tc.want {
+			t.Errorf("NextDelay(%d) = %v, want %v", tc.attempt, got, tc.want)
+		}
+	}
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	b := DecorrelatedJitterBackoff{
+		Base: 10 * time.Millisecond,
+		Max:  200 * time.Millisecond,
+	}
+
+	for i := 0; i < 20; i++ {
+		var previous time.Duration
+		for attempt := 1; attempt <= 10; attempt++ {
+			ceiling := min(3*max(previous, b.Base), b.Max)
+			got := b.NextDelayAfter(attempt, previous)
+			if got < b.Base || got > ceiling {
+				t.Fatalf("NextDelayAfter(%d, %v) = %v, want between %v and %v", attempt, previous, got, b.Base, ceiling)
+			}
+			previous = got
+		}
+	}
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		if got := b.NextDelay(attempt); got < b.Base || got > 3*b.Base {
+			t.Fatalf("NextDelay(%d) = %v, want between %v and %v", attempt, got, b.Base, 3*b.Base)
+		}
+	}
+}
+
+// recordingBackoff records the previous delays it is given
+type recordingBackoff struct {
+	mu       sync.Mutex
+	previous []time.Duration
+}
+
+func (b *recordingBackoff) NextDelay(attempt int) time.Duration {
+	return b.NextDelayAfter(attempt, 0)
+}
+
+func (b *recordingBackoff) NextDelayAfter(attempt int, previous time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.previous = append(b.previous, previous)
+	return time.Duration(attempt) * time.Millisecond
+}
@@ -0,0 +1,323 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/repository"
+)
+
+// DefaultBanTTL is how long a ban lasts when no TTL is given
+const DefaultBanTTL = time.Minute * 30
+
+// Ban keeps a proxy away from a target domain and its subdomains until it expires
+type Ban = domain.Ban
+
+// BanManager bans proxies from individual target domains, so a proxy
+// blocked by one site keeps serving the others
+type BanManager interface {
+	// BanProxy keeps a proxy away from a domain and its subdomains for ttl,
+	// or DefaultBanTTL if ttl is zero. Banning again replaces the ban.
+	BanProxy(ctx context.Context, proxyID, domain string, ttl time.Duration, reason string) error
+
+	// UnbanProxy lifts the ban on a proxy for a domain
+	UnbanProxy(ctx context.Context, proxyID, domain string) error
+
+	// ListBans returns the bans currently in force
+	ListBans(ctx context.Context) ([]*Ban, error)
+}
+
+// Ban related errors
+var (
+	ErrBanNotFound      = repository.ErrBanNotFound
+	ErrInvalidBanDomain = errors.New("ban domain cannot be empty")
+)
+
+var _ BanManager = (*rotator)(nil)
+
+// BanDetectionConfig decides which responses show that a site has banned the
+// proxy that made the request. Detected bans apply to the request's host.
+type BanDetectionConfig struct {
+	// StatusCodes ban the proxy when a response has one of them.
+	// Defaults to 403; an empty, non-nil slice disables the check.
+	StatusCodes []int
+
+	// BodyPatterns are regular expressions matched against the start of
+	// the response body, such as a block page's "Access Denied". Only text,
+	// HTML, XML and JSON bodies are searched.
+	BodyPatterns []string
+
+	// RedirectPatterns are regular expressions matched against the Location of
+	// redirects, to catch captcha and challenge pages. Defaults to patterns for
+	// "captcha" and "/challenge"; an empty, non-nil slice disables the check.
+	RedirectPatterns []string
+
+	// MaxBodyBytes caps how much of the body BodyPatterns see. Defaults to 64 KiB.
+	MaxBodyBytes int
+
+	// TTL is how long a detected ban lasts. Defaults to DefaultBanTTL.
+	TTL time.Duration
+}
+
+// DefaultBanDetectionConfig returns sensible defaults for ban detection
+func DefaultBanDetectionConfig() BanDetectionConfig {
+	return BanDetectionConfig{
+		StatusCodes:      []int{http.StatusForbidden},
+		RedirectPatterns: []string{`(?i)captcha`, `(?i)/challenge`},
+		MaxBodyBytes:     64 << 10,
+		TTL:              DefaultBanTTL,
+	}
+}
+
+// withDefaults fills in unset fields from DefaultBanDetectionConfig
+func (c BanDetectionConfig) withDefaults() BanDetectionConfig {
+	defaults := DefaultBanDetectionConfig()
+	if c.StatusCodes == nil {
+		c.StatusCodes = defaults.StatusCodes
+	}
+	if c.RedirectPatterns == nil {
+		c.RedirectPatterns = defaults.RedirectPatterns
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = defaults.MaxBodyBytes
+	}
+	if c.TTL <= 0 {
+		c.TTL = defaults.TTL
+	}
+	return c
+}
+
+// BanProxy bans a proxy from a domain
+func (r *rotator) BanProxy(ctx context.Context, proxyID, domain string, ttl time.Duration, reason string) error {
+	domain = normalizeBanDomain(domain)
+	if domain == "" {
+		return ErrInvalidBanDomain
+	}
+	if _, err := r.repo.GetByID(ctx, proxyID); err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		ttl = DefaultBanTTL
+	}
+
+	return r.bans.add(ctx, &Ban{ProxyID: proxyID, Domain: domain, Reason: reason, ExpiresAt: time.Now().Add(ttl)})
+}
+
+// UnbanProxy lifts a ban
+func (r *rotator) UnbanProxy(ctx context.Context, proxyID, domain string) error {
+	domain = normalizeBanDomain(domain)
+	if domain == "" {
+		return ErrInvalidBanDomain
+	}
+	return r.bans.remove(ctx, proxyID, domain)
+}
+
+// ListBans returns the active bans
+func (r *rotator) ListBans(ctx context.Context) ([]*Ban, error) {
+	return r.bans.active(time.Now()), nil
+}
+
+// banRegistry keeps the bans in force in memory for selection and writes
+// them through to the repository so they survive restarts
+type banRegistry struct {
+	repo domain.BanRepository
+
+	mu   sync.RWMutex
+	bans map[string][]*domain.Ban // by proxy ID
+
+	// purged is when expired bans were last deleted, in Unix nanoseconds
+	purged atomic.Int64
+}
+
+// loadBanRegistry creates a registry holding the repository's unexpired bans
+func loadBanRegistry(ctx context.Context, repo domain.BanRepository) (*banRegistry, error) {
+	stored, err := repo.ListBans(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bans: %w", err)
+	}
+
+	b := &banRegistry{repo: repo, bans: make(map[string][]*domain.Ban)}
+	now := time.Now()
+	for _, ban := range stored {
+		if !ban.Expired(now) {
+			b.bans[ban.ProxyID] = append(b.bans[ban.ProxyID], ban)
+		}
+	}
+	return b, nil
+}
+
+// banned reports whether a proxy is banned for host
+func (b *banRegistry) banned(proxyID, host string) bool {
+	if host == "" {
+		return false
+	}
+
+	now := time.Now()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, ban := range b.bans[proxyID] {
+		if !ban.Expired(now) && ban.Covers(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// add applies a ban, replacing any earlier ban on the proxy for the same domain.
+// The ban takes effect even if storing it fails.
+func (b *banRegistry) add(ctx context.Context, ban *domain.Ban) error {
+	now := time.Now()
+
+	b.mu.Lock()
+	kept := b.bans[ban.ProxyID][:0]
+	for _, existing := range b.bans[ban.ProxyID] {
+		if existing.Domain != ban.Domain && !existing.Expired(now) {
+			kept = append(kept, existing)
+		}
+	}
+	b.bans[ban.ProxyID] = append(kept, ban)
+	b.mu.Unlock()
+
+	b.purge(ctx, now)
+	return b.repo.SaveBan(ctx, ban)
+}
+
+// remove lifts a ban
+func (b *banRegistry) remove(ctx context.Context, proxyID, banDomain string) error {
+	b.mu.Lock()
+	b.bans[proxyID] = slices.DeleteFunc(b.bans[proxyID], func(ban *domain.Ban) bool {
+		return ban.Domain == banDomain
+	})
+	if len(b.bans[proxyID]) == 0 {
+		delete(b.bans, proxyID)
+	}
+	b.mu.Unlock()
+
+	return b.repo.DeleteBan(ctx, proxyID, banDomain)
+}
+
+// active returns copies of the unexpired bans
+func (b *banRegistry) active(now time.Time) []*domain.Ban {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var bans []*domain.Ban
+	for _, proxyBans := range b.bans {
+		for _, ban := range proxyBans {
+			if !ban.Expired(now) {
+				ban := *ban
+				bans = append(bans, &ban)
+			}
+		}
+	}
+	return bans
+}
+
+// forget drops every ban on a removed proxy
+func (b *banRegistry) forget(ctx context.Context, proxyID string) {
+	b.mu.Lock()
+	bans := b.bans[proxyID]
+	delete(b.bans, proxyID)
+	b.mu.Unlock()
+
+	// The storage may have removed them with the proxy; if not they expire anyway
+	for _, ban := range bans {
+		_ = b.repo.DeleteBan(ctx, proxyID, ban.Domain)
+	}
+}
+
+// purge deletes expired bans from storage at most once per DefaultBanTTL
+func (b *banRegistry) purge(ctx context.Context, now time.Time) {
+	last := b.purged.Load()
+	if now.UnixNano()-last < int64(DefaultBanTTL) || !b.purged.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+
+	// Expired bans are skipped on load, so a failed purge only costs space
+	_ = b.repo.DeleteExpiredBans(ctx, now)
+}
+
+// banDetector recognises responses showing that a site has banned a proxy
+type banDetector struct {
+	config   BanDetectionConfig
+	body     []*regexp.Regexp
+	redirect []*regexp.Regexp
+}
+
+// newBanDetector compiles the configured patterns
+func newBanDetector(config BanDetectionConfig) (*banDetector, error) {
+	d := &banDetector{config: config.withDefaults()}
+
+	var err error
+	if d.body, err = compilePatterns(d.config.BodyPatterns); err != nil {
+		return nil, err
+	}
+	if d.redirect, err = compilePatterns(d.config.RedirectPatterns); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// peekBytes returns how much of the body detect needs to see
+func (d *banDetector) peekBytes() int {
+	if len(d.body) == 0 {
+		return 0
+	}
+	return d.config.MaxBodyBytes
+}
+
+// detect returns why a response shows a ban, or "" if it doesn't.
+// body is the start of the response body.
+func (d *banDetector) detect(resp *http.Response, body []byte) string {
+	if slices.Contains(d.config.StatusCodes, resp.StatusCode) {
+		return "status " + strconv.Itoa(resp.StatusCode)
+	}
+
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		location := resp.Header.Get("Location")
+		for _, pattern := range d.redirect {
+			if location != "" && pattern.MatchString(location) {
+				return "redirect to " + location
+			}
+		}
+		return ""
+	}
+
+	body = body[:min(len(body), d.config.MaxBodyBytes)]
+	for _, pattern := range d.body {
+		if pattern.Match(body) {
+			return "body matches " + pattern.String()
+		}
+	}
+	return ""
+}
+
+// compilePatterns compiles regular expressions from configuration
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ban pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// normalizeBanDomain lowercases a domain and drops a trailing dot
+func normalizeBanDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
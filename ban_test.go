@@ -0,0 +1,193 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBanDetection(t *testing.T) {
+	ctx := context.Background()
+
+	newBanRotator := func(t *testing.T, config BanDetectionConfig, blocked func(w http.ResponseWriter, req *http.Request)) (*rotator, *int32, *int32) {
+		t.Helper()
+
+		opts := DefaultOptions()
+		opts.ValidateOnStart = false
+		opts.RetryDelay = time.Millisecond
+		opts.BanDetection = &config
+
+		r, err := newRotator(opts)
+		if err != nil {
+			t.Fatalf("newRotator failed: %v", err)
+		}
+
+		var blockedHits, okHits int32
+		okBody := func(w http.ResponseWriter, req *http.Request) {
+			if _, err := io.WriteString(w, "welcome"); err != nil {
+				t.Errorf("write failed: %v", err)
+			}
+		}
+		for _, srv := range []*httptest.Server{
+			newBlockingProxyServer(t, "blocked", &blockedHits, blocked),
+			newBlockingProxyServer(t, "ok", &okHits, okBody),
+		} {
+			if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+				t.Fatalf("AddProxy failed: %v", err)
+			}
+		}
+		return r, &blockedHits, &okHits
+	}
+
+	get := func(t *testing.T, r *rotator, url string) (string, string) {
+		t.Helper()
+		resp, err := r.RotatingClient().Get(url)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("reading body failed: %v", err)
+		}
+		return resp.Header.Get("X-Proxy"), string(body)
+	}
+
+	t.Run("Status code bans the proxy for the host", func(t *testing.T) {
+		r, blockedHits, _ := newBanRotator(t, BanDetectionConfig{}, func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+
+		for i := 0; i < 4; i++ {
+			if proxy, _ := get(t, r, "http://shop.example.com/"); proxy != "ok" {
+				t.Fatalf("request %d went through %q, want the unbanned proxy", i, proxy)
+			}
+		}
+		if hits := atomic.LoadInt32(blockedHits); hits > 1 {
+			t.Errorf("blocked proxy used %d times, want at most once before its ban", hits)
+		}
+
+		bans, _ := r.ListBans(ctx)
+		if len(bans) != 1 || bans[0].Domain != "shop.example.com" || bans[0].Reason != "status 403" {
+			t.Fatalf("ListBans() = %+v, want one 403 ban for shop.example.com", bans)
+		}
+
+		// Other sites can still use the proxy
+		sel := selection{TargetHost: "news.example.com"}
+		if candidates, _ := r.available(mustList(t, r), sel); len(candidates) != 2 {
+			t.Errorf("%d proxies available for another host, want 2", len(candidates))
+		}
+	})
+
+	t.Run("Body pattern", func(t *testing.T) {
+		r, _, _ := newBanRotator(t, BanDetectionConfig{BodyPatterns: []string{`(?i)access denied`}}, func(w http.ResponseWriter, req *http.Request) {
+			if _, err := io.WriteString(w, "<h1>Access Denied</h1>"); err != nil {
+				t.Errorf("write failed: %v", err)
+			}
+		})
+
+		for i := 0; i < 4; i++ {
+			proxy, body := get(t, r, "http://shop.example.com/")
+			if proxy != "ok" || body != "welcome" {
+				t.Fatalf("request %d = %q via %q, want the full body via the unbanned proxy", i, body, proxy)
+			}
+		}
+	})
+
+	t.Run("Redirect to captcha", func(t *testing.T) {
+		r, _, _ := newBanRotator(t, BanDetectionConfig{}, func(w http.ResponseWriter, req *http.Request) {
+			http.Redirect(w, req, "http://shop.example.com/captcha?return=/", http.StatusFound)
+		})
+
+		for i := 0; i < 4; i++ {
+			if proxy, _ := get(t, r, "http://shop.example.com/"); proxy != "ok" {
+				t.Fatalf("request %d went through %q, want the unbanned proxy", i, proxy)
+			}
+		}
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.BanDetection = &BanDetectionConfig{BodyPatterns: []string{"("}}
+		if _, err := newRotator(opts); err == nil {
+			t.Error("newRotator accepted an invalid body pattern")
+		}
+	})
+}
+
+func TestBanManager(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+	for _, u := range []string{"http://a.example.com:8080", "http://b.example.com:8080"} {
+		if err := r.AddProxy(ctx, u, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+	proxies := mustList(t, r)
+	banned := proxies[0]
+
+	if err := r.BanProxy(ctx, banned.ID, "Example.COM.", time.Hour, "manual"); err != nil {
+		t.Fatalf("BanProxy failed: %v", err)
+	}
+	if err := r.BanProxy(ctx, banned.ID, " ", time.Hour, "manual"); !errors.Is(err, ErrInvalidBanDomain) {
+		t.Errorf("BanProxy with empty domain error = %v, want %v", err, ErrInvalidBanDomain)
+	}
+	if err := r.BanProxy(ctx, "missing", "example.com", time.Hour, "manual"); err == nil {
+		t.Error("BanProxy accepted an unknown proxy")
+	}
+
+	// The ban covers subdomains, and only the banned proxy
+	for i := 0; i < 5; i++ {
+		proxy, err := r.GetProxyWith(ctx, Criteria{TargetHost: "www.example.com"})
+		if err != nil {
+			t.Fatalf("GetProxyWith failed: %v", err)
+		}
+		if proxy.ID == banned.ID {
+			t.Fatal("GetProxyWith returned a proxy banned for the host")
+		}
+	}
+	if _, err := r.GetProxyWith(ctx, Criteria{TargetHost: "example.org", ExcludeIDs: []string{proxies[1].ID}}); err != nil {
+		t.Errorf("banned proxy unavailable for another domain: %v", err)
+	}
+
+	if err := r.UnbanProxy(ctx, banned.ID, "example.com"); err != nil {
+		t.Fatalf("UnbanProxy failed: %v", err)
+	}
+	if err := r.UnbanProxy(ctx, banned.ID, "example.com"); !errors.Is(err, ErrBanNotFound) {
+		t.Errorf("UnbanProxy error = %v, want %v", err, ErrBanNotFound)
+	}
+	if bans, _ := r.ListBans(ctx); len(bans) != 0 {
+		t.Errorf("ListBans() = %+v, want none", bans)
+	}
+
+	// Expired bans no longer apply
+	if err := r.bans.add(ctx, &Ban{ProxyID: banned.ID, Domain: "example.com", ExpiresAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatalf("adding ban failed: %v", err)
+	}
+	if r.bans.banned(banned.ID, "example.com") {
+		t.Error("expired ban still applies")
+	}
+}
+
+// mustList returns the rotator's proxies
+func mustList(t *testing.T, r *rotator) []*Proxy {
+	t.Helper()
+	proxies, err := r.List(context.Background())
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	return proxies
+}
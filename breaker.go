@@ -1,12 +1,61 @@
 package lashes
 
 import (
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/greysquirr3l/lashes/internal/breaker"
 )
 
+// BreakerMode selects how a circuit breaker decides to open
+type BreakerMode = breaker.Mode
+
+// Circuit breaker modes
+const (
+	// BreakerModeConsecutive opens after MaxFailures failures in a row
+	BreakerModeConsecutive = breaker.ModeConsecutive
+	// BreakerModeCountWindow opens on the failure rate of the last WindowSize calls
+	BreakerModeCountWindow = breaker.ModeCountWindow
+	// BreakerModeTimeWindow opens on the failure rate over the last WindowDuration
+	BreakerModeTimeWindow = breaker.ModeTimeWindow
+)
+
+// CircuitState is the state of a circuit breaker
+type CircuitState = breaker.State
+
+// Circuit breaker states
+const (
+	CircuitClosed   = breaker.StateClosed
+	CircuitOpen     = breaker.StateOpen
+	CircuitHalfOpen = breaker.StateHalfOpen
+)
+
+// GlobalBreakerID identifies the global breaker in snapshots and state-change hooks
+const GlobalBreakerID = "*"
+
+// StateChangeHook is called when a circuit breaker changes state
+type StateChangeHook func(proxyID string, from, to CircuitState)
+
+// CircuitBreakerStatus is a point-in-time view of one circuit breaker
+type CircuitBreakerStatus struct {
+	// ProxyID is the proxy the breaker guards, or GlobalBreakerID
+	ProxyID string
+
+	// State is the breaker's current state
+	State CircuitState
+
+	// Failures is the consecutive failure count, or the failures in the
+	// current window for the failure-rate modes
+	Failures int
+
+	// Since is when the breaker entered its current state
+	Since time.Time
+
+	// NextRetry is when an open breaker admits its next trial request; zero otherwise
+	NextRetry time.Time
+}
+
 // CircuitBreakerConfig configures the circuit breaker behavior
 type CircuitBreakerConfig struct {
 	// MaxFailures is the threshold of failures before opening the circuit
@@ -20,6 +69,41 @@ type CircuitBreakerConfig struct {
 
 	// EnableGlobalBreaker enables a circuit breaker for the entire proxy pool
 	EnableGlobalBreaker bool
+
+	// Mode selects consecutive-failure counting or a sliding failure-rate window.
+	// MaxFailures only applies to BreakerModeConsecutive.
+	Mode BreakerMode
+
+	// WindowSize is the number of recent calls considered by BreakerModeCountWindow
+	WindowSize int
+
+	// WindowDuration is the period considered by BreakerModeTimeWindow
+	WindowDuration time.Duration
+
+	// FailureRateThreshold is the failure ratio (0-1] that opens a windowed breaker
+	FailureRateThreshold float64
+
+	// MinimumRequests is the number of calls a window needs before its rate
+	// is trusted. BreakerModeCountWindow caps it at WindowSize.
+	MinimumRequests int
+
+	// SlowCallThreshold counts calls slower than this as failures; zero disables it
+	SlowCallThreshold time.Duration
+}
+
+// breakerConfig converts the settings into a per-proxy breaker configuration
+func (c CircuitBreakerConfig) breakerConfig() breaker.Config {
+	return breaker.Config{
+		MaxFailures:          c.MaxFailures,
+		ResetTimeout:         c.ResetTimeout,
+		MaxHalfOpenRequests:  c.MaxHalfOpenRequests,
+		Mode:                 c.Mode,
+		WindowSize:           c.WindowSize,
+		WindowDuration:       c.WindowDuration,
+		FailureRateThreshold: c.FailureRateThreshold,
+		MinimumRequests:      c.MinimumRequests,
+		SlowCallThreshold:    c.SlowCallThreshold,
+	}
 }
 
 // DefaultCircuitBreakerConfig returns sensible defaults for circuit breakers
@@ -38,6 +122,9 @@ type CircuitBreakerManager struct {
 	globalBreaker *breaker.CircuitBreaker
 	config        CircuitBreakerConfig
 	mu            sync.RWMutex
+
+	hooks   []StateChangeHook
+	hooksMu sync.RWMutex
 }
 
 // NewCircuitBreakerManager creates a new circuit breaker manager
@@ -48,77 +135,260 @@ func NewCircuitBreakerManager(config CircuitBreakerConfig) *CircuitBreakerManage
 	}
 
 	if config.EnableGlobalBreaker {
-		mgr.globalBreaker = breaker.NewCircuitBreaker(breaker.Config{
-			MaxFailures:         config.MaxFailures * 3, // Higher threshold for global breaker
-			ResetTimeout:        config.ResetTimeout,
-			MaxHalfOpenRequests: config.MaxHalfOpenRequests,
-		})
+		globalConfig := config.breakerConfig()
+		globalConfig.MaxFailures *= 3     // Higher threshold for global breaker
+		globalConfig.MinimumRequests *= 3 // and more volume before trusting its rate
+		if globalConfig.Mode == BreakerModeCountWindow {
+			// The window must hold the extra volume or the breaker never opens
+			if globalConfig.WindowSize <= 0 {
+				globalConfig.WindowSize = breaker.DefaultWindowSize
+			}
+			globalConfig.WindowSize *= 3
+		}
+		mgr.globalBreaker = mgr.newBreaker(GlobalBreakerID, globalConfig)
 	}
 
 	return mgr
 }
 
-// Allow checks if a request should be allowed through for a proxy
-func (m *CircuitBreakerManager) Allow(proxyID string) bool {
-	// Check global circuit breaker first
-	if m.globalBreaker != nil && !m.globalBreaker.Allow() {
-		return false
+// newBreaker creates a breaker that reports its transitions to the manager's hooks
+func (m *CircuitBreakerManager) newBreaker(id string, cfg breaker.Config) *breaker.CircuitBreaker {
+	cfg.OnStateChange = func(from, to breaker.State) {
+		m.notify(id, from, to)
+	}
+	return breaker.NewCircuitBreaker(cfg)
+}
+
+// breakerFor returns the breaker for a proxy, or the global breaker for
+// GlobalBreakerID. Per-proxy breakers are created on demand when create is set.
+func (m *CircuitBreakerManager) breakerFor(proxyID string, create bool) *breaker.CircuitBreaker {
+	if proxyID == GlobalBreakerID {
+		return m.globalBreaker
 	}
 
 	m.mu.RLock()
 	cb, exists := m.breakers[proxyID]
 	m.mu.RUnlock()
 
-	if !exists {
-		m.mu.Lock()
-		// Double-check after acquiring write lock
-		if cb, exists = m.breakers[proxyID]; !exists {
-			// Create new circuit breaker if it doesn't exist
-			cb = breaker.NewCircuitBreaker(breaker.Config{
-				MaxFailures:         m.config.MaxFailures,
-				ResetTimeout:        m.config.ResetTimeout,
-				MaxHalfOpenRequests: m.config.MaxHalfOpenRequests,
-			})
-			m.breakers[proxyID] = cb
-		}
-		m.mu.Unlock()
+	if exists || !create {
+		return cb
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Double-check after acquiring write lock
+	if cb, exists = m.breakers[proxyID]; !exists {
+		cb = m.newBreaker(proxyID, m.config.breakerConfig())
+		m.breakers[proxyID] = cb
+	}
+	return cb
+}
+
+// OnStateChange registers a hook that is called whenever a breaker changes state.
+// Hooks run synchronously on the goroutine that caused the transition, outside
+// the manager's locks, so they must not block for long.
+func (m *CircuitBreakerManager) OnStateChange(hook StateChangeHook) {
+	m.hooksMu.Lock()
+	defer m.hooksMu.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// notify calls every registered state-change hook
+func (m *CircuitBreakerManager) notify(proxyID string, from, to CircuitState) {
+	m.hooksMu.RLock()
+	hooks := m.hooks
+	m.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(proxyID, from, to)
 	}
+}
 
-	return cb.Allow()
+// Allow checks if a request should be allowed through for a proxy
+func (m *CircuitBreakerManager) Allow(proxyID string) bool {
+	_, allowed := m.acquire(proxyID)
+	return allowed
+}
+
+// acquire is like Allow, and returns a function that gives back the half-open
+// trial slots the request took, for when it ends up not being sent
+func (m *CircuitBreakerManager) acquire(proxyID string) (release func(), allowed bool) {
+	cb := m.breakerFor(proxyID, true)
+	if m.globalBreaker == nil {
+		allowed, trial := cb.Acquire()
+		return releaser(cb, trial), allowed
+	}
+
+	// Don't take a global trial slot for a proxy whose breaker refuses anyway
+	if !cb.Ready() {
+		return func() {}, false
+	}
+
+	allowed, globalTrial := m.globalBreaker.Acquire()
+	if !allowed {
+		return func() {}, false
+	}
+
+	allowed, trial := cb.Acquire()
+	if !allowed {
+		// Another request took the proxy's last trial slot first
+		releaser(m.globalBreaker, globalTrial)()
+		return func() {}, false
+	}
+
+	return func() {
+		releaser(cb, trial)()
+		releaser(m.globalBreaker, globalTrial)()
+	}, true
+}
+
+// releaser returns a function that gives back a trial slot if one was taken
+func releaser(cb *breaker.CircuitBreaker, trial bool) func() {
+	if !trial {
+		return func() {}
+	}
+	return cb.Release
+}
+
+// Ready reports whether Allow would currently permit a request for a proxy.
+// Unlike Allow, it never creates a breaker or takes a half-open slot.
+func (m *CircuitBreakerManager) Ready(proxyID string) bool {
+	if _, ready := m.globalReady(); !ready {
+		return false
+	}
+
+	cb := m.breakerFor(proxyID, false)
+	return cb == nil || cb.Ready()
+}
+
+// globalReady reports whether the global breaker admits requests and,
+// if it doesn't, how long it will stay open
+func (m *CircuitBreakerManager) globalReady() (time.Duration, bool) {
+	if m.globalBreaker == nil || m.globalBreaker.Ready() {
+		return 0, true
+	}
+	return m.globalBreaker.RetryAfter(), false
 }
 
 // RecordSuccess records a successful request for a proxy
 func (m *CircuitBreakerManager) RecordSuccess(proxyID string) {
+	m.Record(proxyID, true, 0)
+}
+
+// RecordFailure records a failed request for a proxy
+func (m *CircuitBreakerManager) RecordFailure(proxyID string) {
+	m.Record(proxyID, false, 0)
+}
+
+// Record records the outcome and latency of a request for a proxy.
+// Successful calls slower than SlowCallThreshold count as failures.
+func (m *CircuitBreakerManager) Record(proxyID string, success bool, latency time.Duration) {
 	if m.globalBreaker != nil {
-		m.globalBreaker.RecordSuccess()
+		m.globalBreaker.Record(success, latency)
 	}
 
-	m.mu.RLock()
-	cb, exists := m.breakers[proxyID]
-	m.mu.RUnlock()
+	if cb := m.breakerFor(proxyID, false); cb != nil {
+		cb.Record(success, latency)
+	}
+}
 
-	if exists {
-		cb.RecordSuccess()
+// ForceOpen opens a proxy's breaker, or the global breaker for GlobalBreakerID.
+// The breaker half-opens again after ResetTimeout like any other open breaker.
+func (m *CircuitBreakerManager) ForceOpen(proxyID string) {
+	if cb := m.breakerFor(proxyID, true); cb != nil {
+		cb.ForceOpen()
 	}
 }
 
-// RecordFailure records a failed request for a proxy
-func (m *CircuitBreakerManager) RecordFailure(proxyID string) {
+// Reset closes a proxy's breaker, or the global breaker for GlobalBreakerID,
+// and forgets its recorded failures
+func (m *CircuitBreakerManager) Reset(proxyID string) {
+	if cb := m.breakerFor(proxyID, false); cb != nil {
+		cb.Reset()
+	}
+}
+
+// ResetAll closes every breaker, including the global one
+func (m *CircuitBreakerManager) ResetAll() {
 	if m.globalBreaker != nil {
-		m.globalBreaker.RecordFailure()
+		m.globalBreaker.Reset()
 	}
 
 	m.mu.RLock()
-	cb, exists := m.breakers[proxyID]
+	breakers := make([]*breaker.CircuitBreaker, 0, len(m.breakers))
+	for _, cb := range m.breakers {
+		breakers = append(breakers, cb)
+	}
 	m.mu.RUnlock()
 
-	if exists {
-		cb.RecordFailure()
+	for _, cb := range breakers {
+		cb.Reset()
 	}
 }
 
-// EnableCircuitBreaker adds circuit breaker support to the rotator
+// forget drops the breaker of a proxy that has left the rotation
+func (m *CircuitBreakerManager) forget(proxyID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.breakers, proxyID)
+}
+
+// Snapshot returns the status of every breaker, sorted by proxy ID.
+// The global breaker, when enabled, comes first.
+func (m *CircuitBreakerManager) Snapshot() []CircuitBreakerStatus {
+	m.mu.RLock()
+	statuses := make([]CircuitBreakerStatus, 0, len(m.breakers)+1)
+	for id, cb := range m.breakers {
+		statuses = append(statuses, newCircuitBreakerStatus(id, cb))
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ProxyID < statuses[j].ProxyID
+	})
+
+	if m.globalBreaker != nil {
+		global := newCircuitBreakerStatus(GlobalBreakerID, m.globalBreaker)
+		statuses = append([]CircuitBreakerStatus{global}, statuses...)
+	}
+	return statuses
+}
+
+// newCircuitBreakerStatus converts a breaker snapshot into a status entry
+func newCircuitBreakerStatus(id string, cb *breaker.CircuitBreaker) CircuitBreakerStatus {
+	snapshot := cb.Snapshot()
+	return CircuitBreakerStatus{
+		ProxyID:   id,
+		State:     snapshot.State,
+		Failures:  snapshot.Failures,
+		Since:     snapshot.Since,
+		NextRetry: snapshot.RetryAt,
+	}
+}
+
+// CircuitBreakerProvider is implemented by rotators that can skip proxies
+// whose circuit breaker is open
+type CircuitBreakerProvider interface {
+	// EnableCircuitBreaker installs a new CircuitBreakerManager, replacing any existing one
+	EnableCircuitBreaker(config CircuitBreakerConfig) *CircuitBreakerManager
+
+	// CircuitBreakers returns the installed manager, or nil if breakers are disabled
+	CircuitBreakers() *CircuitBreakerManager
+}
+
+var _ CircuitBreakerProvider = (*rotator)(nil)
+
+// EnableCircuitBreaker adds circuit breaker support to the rotator.
+// Proxy selection skips proxies whose breaker is open, and requests made
+// through the rotating client record their outcome automatically.
 func (r *rotator) EnableCircuitBreaker(config CircuitBreakerConfig) *CircuitBreakerManager {
-	// Initialize the circuit breaker manager
-	return NewCircuitBreakerManager(config)
+	manager := NewCircuitBreakerManager(config)
+	r.breakers.Store(manager)
+	return manager
+}
+
+// CircuitBreakers returns the rotator's circuit breaker manager, or nil if none is enabled
+func (r *rotator) CircuitBreakers() *CircuitBreakerManager {
+	return r.breakers.Load()
 }
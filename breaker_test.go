@@ -1,8 +1,15 @@
 package lashes
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestCircuitBreakerManager(t *testing.T) {
@@ -72,4 +79,201 @@ func TestCircuitBreakerManager(t *testing.T) {
 		// Proxies may now be allowed again depending on the implementation
 		// We can't test this definitively without knowledge of internal timers
 	})
+
+	t.Run("Refused proxies don't use up the global trial slot", func(t *testing.T) {
+		config := DefaultCircuitBreakerConfig()
+		config.ResetTimeout = 20 * time.Millisecond
+		config.MaxHalfOpenRequests = 1
+
+		manager := NewCircuitBreakerManager(config)
+		manager.ForceOpen(GlobalBreakerID)
+		time.Sleep(30 * time.Millisecond)
+		manager.ForceOpen("proxy-1")
+
+		if manager.Allow("proxy-1") {
+			t.Fatal("Allow() for an open proxy = true, want false")
+		}
+		if !manager.Allow("proxy-2") {
+			t.Error("Allow() for proxy-2 = false, want the global trial slot")
+		}
+	})
+
+	t.Run("Global circuit breaker in count window mode", func(t *testing.T) {
+		config := DefaultCircuitBreakerConfig()
+		config.Mode = BreakerModeCountWindow
+		config.MinimumRequests = 10
+
+		manager := NewCircuitBreakerManager(config)
+
+		// Spread failures so no single proxy's breaker sees enough volume
+		for i := 0; i < 30; i++ {
+			manager.RecordFailure(fmt.Sprintf("proxy-%d", i))
+		}
+
+		if manager.Allow("proxy-new") {
+			t.Error("Allow() after 30 failures = true, want false")
+		}
+		if status := manager.Snapshot()[0]; status.ProxyID != GlobalBreakerID || status.State != CircuitOpen {
+			t.Errorf("global breaker = %+v, want open", status)
+		}
+	})
+}
+
+func TestRotatorCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Open breakers are skipped", func(t *testing.T) {
+		config := DefaultCircuitBreakerConfig()
+		config.MaxFailures = 2
+		config.EnableGlobalBreaker = false
+
+		opts := DefaultOptions()
+		opts.ValidateOnStart = false
+		opts.MaxRetries = 0
+		opts.CircuitBreaker = &config
+
+		r, err := newRotator(opts)
+		if err != nil {
+			t.Fatalf("newRotator failed: %v", err)
+		}
+
+		var deadHits int32
+		dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&deadHits, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		t.Cleanup(dead.Close)
+
+		var healthyHits int32
+		healthy := newTestProxyServer(t, "healthy", &healthyHits)
+
+		for _, srv := range []*httptest.Server{dead, healthy} {
+			if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+				t.Fatalf("AddProxy failed: %v", err)
+			}
+		}
+
+		httpClient := r.RotatingClient()
+		for i := 0; i < 8; i++ {
+			resp, err := httpClient.Get("http://target.invalid/")
+			if err != nil {
+				t.Fatalf("request %d failed: %v", i, err)
+			}
+			resp.Body.Close()
+		}
+
+		if hits := atomic.LoadInt32(&deadHits); hits != 2 {
+			t.Errorf("dead proxy hits = %d, want 2 before its breaker opened", hits)
+		}
+
+		for i := 0; i < 3; i++ {
+			proxy, err := r.GetProxy(ctx)
+			if err != nil {
+				t.Fatalf("GetProxy failed: %v", err)
+			}
+			if proxy.URL != healthy.URL {
+				t.Errorf("GetProxy() = %s, want the healthy proxy %s", proxy.URL, healthy.URL)
+			}
+		}
+	})
+
+	t.Run("Global trip returns a typed error", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.ValidateOnStart = false
+
+		r, err := newRotator(opts)
+		if err != nil {
+			t.Fatalf("newRotator failed: %v", err)
+		}
+		if err := r.AddProxy(ctx, "http://proxy.example.com:8080", HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+
+		config := DefaultCircuitBreakerConfig()
+		config.MaxFailures = 1
+		config.ResetTimeout = time.Minute
+		manager := r.EnableCircuitBreaker(config)
+		if r.CircuitBreakers() != manager {
+			t.Fatal("CircuitBreakers() did not return the enabled manager")
+		}
+
+		// The global breaker trips at three times the per-proxy threshold
+		for i := 0; i < 3; i++ {
+			manager.RecordFailure("proxy-x")
+		}
+
+		_, err = r.GetProxy(ctx)
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("GetProxy() error = %v, want %v", err, ErrCircuitOpen)
+		}
+
+		var breakerErr *CircuitBreakerError
+		if !errors.As(err, &breakerErr) || breakerErr.RetryAfter <= 0 {
+			t.Errorf("GetProxy() error = %#v, want a CircuitBreakerError with RetryAfter", err)
+		}
+	})
+}
+
+func TestCircuitBreakerManagerIntrospection(t *testing.T) {
+	config := DefaultCircuitBreakerConfig()
+	config.MaxFailures = 2
+	config.ResetTimeout = time.Minute
+
+	manager := NewCircuitBreakerManager(config)
+
+	var mu sync.Mutex
+	var changes []string
+	manager.OnStateChange(func(proxyID string, from, to CircuitState) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, fmt.Sprintf("%s:%s->%s", proxyID, from, to))
+	})
+
+	for _, id := range []string{"proxy-b", "proxy-a"} {
+		manager.Allow(id)
+	}
+	manager.RecordFailure("proxy-a")
+	manager.RecordFailure("proxy-a")
+
+	snapshot := manager.Snapshot()
+	if len(snapshot) != 3 || snapshot[0].ProxyID != GlobalBreakerID ||
+		snapshot[1].ProxyID != "proxy-a" || snapshot[2].ProxyID != "proxy-b" {
+		t.Fatalf("Snapshot() = %+v, want the global breaker then proxy-a and proxy-b", snapshot)
+	}
+	if a := snapshot[1]; a.State != CircuitOpen || a.Failures != 2 || a.NextRetry.IsZero() {
+		t.Errorf("proxy-a status = %+v, want open with 2 failures and a retry time", a)
+	}
+	if b := snapshot[2]; b.State != CircuitClosed || !b.NextRetry.IsZero() {
+		t.Errorf("proxy-b status = %+v, want closed", b)
+	}
+
+	manager.ForceOpen("proxy-b")
+	if manager.Allow("proxy-b") {
+		t.Error("Allow() after ForceOpen = true, want false")
+	}
+
+	manager.ResetAll()
+	for _, status := range manager.Snapshot() {
+		if status.State != CircuitClosed {
+			t.Errorf("%s state after ResetAll = %v, want %v", status.ProxyID, status.State, CircuitClosed)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"proxy-a:closed->open",
+		"proxy-b:closed->open",
+		"proxy-a:open->closed",
+		"proxy-b:open->closed",
+	}
+	for _, w := range want {
+		found := false
+		for _, c := range changes {
+			found = found || c == w
+		}
+		if !found {
+			t.Errorf("state changes %v are missing %q", changes, w)
+		}
+	}
 }
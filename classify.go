@@ -0,0 +1,208 @@
+package lashes
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/greysquirr3l/lashes/internal/client"
+)
+
+// Classification is the verdict a ResponseClassifier gives a response
+type Classification = client.Classification
+
+// Response classifications. Successes count for the proxy everywhere. Soft
+// failures count against it in metrics only. Bans and captchas ban the proxy
+// from the request's host and are retried through another proxy. Transient
+// errors count against the proxy's circuit breaker as well.
+const (
+	ResponseSuccess        = client.Success
+	ResponseSoftFail       = client.SoftFail
+	ResponseBan            = client.Ban
+	ResponseCaptcha        = client.Captcha
+	ResponseTransientError = client.TransientError
+)
+
+// ResponseClassifier decides what a response means for the proxy that
+// fetched it. Its verdict feeds metrics, circuit breakers, bans and
+// strategies that learn from request outcomes.
+type ResponseClassifier = client.ResponseClassifier
+
+// BodyPeeker is implemented by classifiers that need to see the start of the
+// body. The body is put back afterwards, so callers still read all of it.
+// Only text, HTML, XML and JSON bodies are peeked at; classifiers get no body
+// for HEAD requests, event streams and binary content.
+type BodyPeeker = client.BodyPeeker
+
+// ResponseClassifierFunc adapts a function to a ResponseClassifier. The
+// function is never given the body; implement BodyPeeker to see it.
+type ResponseClassifierFunc func(resp *http.Response) Classification
+
+// Classify implements ResponseClassifier
+func (f ResponseClassifierFunc) Classify(resp *http.Response, body []byte) Classification {
+	return f(resp)
+}
+
+// DefaultResponseClassifier classifies responses by status code alone.
+// Statuses below 400 are successes; server errors, 407 and 429 are transient
+// errors; other client errors are soft failures.
+func DefaultResponseClassifier() ResponseClassifier {
+	return client.StatusClassifier{}
+}
+
+// defaultBlockPageBytes is how much of the body BlockPageClassifier searches by default
+const defaultBlockPageBytes = 16 << 10
+
+// Signatures of the challenge and block pages served by common bot protection.
+// Captcha widgets also appear on ordinary login and signup forms, so widget
+// markers only count on error responses; challenge markers count on any.
+var (
+	challengeSignatures = [][]byte{
+		[]byte("/cdn-cgi/challenge-platform"),
+		[]byte("px-captcha"),
+		[]byte("captcha-delivery.com"),
+	}
+	widgetSignatures = [][]byte{
+		[]byte("g-recaptcha"),
+		[]byte("h-captcha"),
+		[]byte("hcaptcha.com"),
+		[]byte("challenges.cloudflare.com"),
+	}
+	banSignatures = [][]byte{
+		[]byte("attention required! | cloudflare"),
+		[]byte("you don't have permission to access"),
+		[]byte("access to this page has been denied"),
+		[]byte("request unsuccessful. incapsula incident id"),
+		[]byte("the owner of this website has banned"),
+	}
+)
+
+// BlockPageClassifier recognises the captcha, challenge and block pages of
+// common bot protection services, including challenges served with a 200, and
+// falls back to another classifier for everything else. A captcha widget on a
+// successful page, such as a login form, is not treated as a challenge.
+type BlockPageClassifier struct {
+	// MaxBodyBytes caps how much of the body is searched. Defaults to 16 KiB.
+	MaxBodyBytes int
+
+	// Fallback classifies responses that aren't block pages.
+	// Defaults to DefaultResponseClassifier.
+	Fallback ResponseClassifier
+}
+
+// PeekBytes implements BodyPeeker
+func (c BlockPageClassifier) PeekBytes() int {
+	n := c.MaxBodyBytes
+	if n <= 0 {
+		n = defaultBlockPageBytes
+	}
+	return max(n, peekBytes(c.Fallback))
+}
+
+// Classify implements ResponseClassifier
+func (c BlockPageClassifier) Classify(resp *http.Response, body []byte) Classification {
+	if resp.Header.Get("Cf-Mitigated") == "challenge" {
+		return ResponseCaptcha
+	}
+
+	lower := bytes.ToLower(body)
+	if containsAny(lower, challengeSignatures) {
+		return ResponseCaptcha
+	}
+	if (resp.StatusCode < 200 || resp.StatusCode > 299) && containsAny(lower, widgetSignatures) {
+		return ResponseCaptcha
+	}
+	if containsAny(lower, banSignatures) {
+		return ResponseBan
+	}
+
+	return classifyWith(c.Fallback, resp, body)
+}
+
+// containsAny reports whether body contains any of the signatures
+func containsAny(body []byte, signatures [][]byte) bool {
+	for _, signature := range signatures {
+		if bytes.Contains(body, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// HostClassifier classifies each response with the classifier configured
+// for the request's host
+type HostClassifier struct {
+	// Hosts maps exact host names such as "api.example.com", or wildcards
+	// such as "*.example.com", to the classifier for them. Exact names win
+	// over wildcards, and longer wildcards over shorter ones.
+	Hosts map[string]ResponseClassifier
+
+	// Default classifies responses from other hosts.
+	// Defaults to DefaultResponseClassifier.
+	Default ResponseClassifier
+}
+
+// PeekBytes implements BodyPeeker
+func (c HostClassifier) PeekBytes() int {
+	n := peekBytes(c.Default)
+	for _, classifier := range c.Hosts {
+		n = max(n, peekBytes(classifier))
+	}
+	return n
+}
+
+// Classify implements ResponseClassifier
+func (c HostClassifier) Classify(resp *http.Response, body []byte) Classification {
+	var host string
+	if resp.Request != nil && resp.Request.URL != nil {
+		host = resp.Request.URL.Hostname()
+	}
+	return classifyWith(c.classifierFor(host), resp, body)
+}
+
+// classifierFor returns the classifier configured for a host
+func (c HostClassifier) classifierFor(host string) ResponseClassifier {
+	if host == "" {
+		return c.Default
+	}
+
+	var match ResponseClassifier
+	matchLen := -1
+	for pattern, classifier := range c.Hosts {
+		if !hostMatches(pattern, host) {
+			continue
+		}
+		if !strings.HasPrefix(pattern, "*.") {
+			return classifier
+		}
+		if len(pattern) > matchLen {
+			match, matchLen = classifier, len(pattern)
+		}
+	}
+	if match != nil {
+		return match
+	}
+	return c.Default
+}
+
+// classifyWith classifies a response, giving the classifier as much of the
+// body as it asked for. A nil classifier uses DefaultResponseClassifier.
+func classifyWith(classifier ResponseClassifier, resp *http.Response, body []byte) Classification {
+	if classifier == nil {
+		classifier = DefaultResponseClassifier()
+	}
+
+	n := peekBytes(classifier)
+	if n == 0 {
+		return classifier.Classify(resp, nil)
+	}
+	return classifier.Classify(resp, body[:min(len(body), n)])
+}
+
+// peekBytes returns how much of the body a classifier wants to see
+func peekBytes(classifier ResponseClassifier) int {
+	if peeker, ok := classifier.(BodyPeeker); ok {
+		return peeker.PeekBytes()
+	}
+	return 0
+}
@@ -0,0 +1,264 @@
+package lashes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// classifyResponse runs a classifier over a response for the given URL and body
+func classifyResponse(t *testing.T, classifier ResponseClassifier, rawURL string, status int, header http.Header, body string) Classification {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parsing URL failed: %v", err)
+	}
+	if header == nil {
+		header = http.Header{}
+	}
+	resp := &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    &http.Request{URL: u},
+	}
+
+	return classifyWith(classifier, resp, []byte(body))
+}
+
+func TestDefaultResponseClassifier(t *testing.T) {
+	tests := []struct {
+		status int
+		want   Classification
+	}{
+		{http.StatusOK, ResponseSuccess},
+		{http.StatusFound, ResponseSuccess},
+		{http.StatusNotFound, ResponseSoftFail},
+		{http.StatusForbidden, ResponseSoftFail},
+		{http.StatusProxyAuthRequired, ResponseTransientError},
+		{http.StatusTooManyRequests, ResponseTransientError},
+		{http.StatusBadGateway, ResponseTransientError},
+	}
+
+	for _, tt := range tests {
+		if got := classifyResponse(t, DefaultResponseClassifier(), "http://example.com/", tt.status, nil, ""); got != tt.want {
+			t.Errorf("status %d classified as %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestBlockPageClassifier(t *testing.T) {
+	classifier := BlockPageClassifier{}
+
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		body   string
+		want   Classification
+	}{
+		{"Plain page", http.StatusOK, nil, "<h1>Products</h1>", ResponseSuccess},
+		{"reCAPTCHA challenge", http.StatusForbidden, nil, `<div class="g-recaptcha" data-sitekey="x">`, ResponseCaptcha},
+		{"Login form with reCAPTCHA", http.StatusOK, nil, `<form action="/login"><div class="g-recaptcha" data-sitekey="x"></div></form>`, ResponseSuccess},
+		{"Signup form with hCaptcha", http.StatusOK, nil, `<script src="https://js.hcaptcha.com/1/api.js"></script><div class="h-captcha">`, ResponseSuccess},
+		{"Challenge served with 200", http.StatusOK, nil, `<script src="/cdn-cgi/challenge-platform/h/b/orchestrate/jsch/v1"></script>`, ResponseCaptcha},
+		{"DataDome captcha served with 200", http.StatusOK, nil, `<iframe src="https://geo.captcha-delivery.com/captcha/">`, ResponseCaptcha},
+		{"Cloudflare challenge header", http.StatusForbidden, http.Header{"Cf-Mitigated": []string{"challenge"}}, "", ResponseCaptcha},
+		{"Akamai block page", http.StatusOK, nil, "<H1>Access Denied</H1>You don't have permission to access this server.", ResponseBan},
+		{"PerimeterX block page", http.StatusForbidden, nil, "<title>Access to this page has been denied.</title>", ResponseBan},
+		{"Server error falls back", http.StatusServiceUnavailable, nil, "maintenance", ResponseTransientError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyResponse(t, classifier, "http://example.com/", tt.status, tt.header, tt.body); got != tt.want {
+				t.Errorf("Classify() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// Signatures past MaxBodyBytes are not seen
+	late := strings.Repeat(" ", 100) + "px-captcha"
+	if got := classifyResponse(t, BlockPageClassifier{MaxBodyBytes: 50}, "http://example.com/", http.StatusOK, nil, late); got != ResponseSuccess {
+		t.Errorf("Classify() = %s, want %s when the signature is past MaxBodyBytes", got, ResponseSuccess)
+	}
+}
+
+func TestHostClassifier(t *testing.T) {
+	always := func(c Classification) ResponseClassifier {
+		return ResponseClassifierFunc(func(resp *http.Response) Classification { return c })
+	}
+
+	classifier := HostClassifier{
+		Hosts: map[string]ResponseClassifier{
+			"shop.example.com": always(ResponseSoftFail),
+			"*.example.com":    always(ResponseBan),
+			"*.cdn.example.com": BlockPageClassifier{
+				Fallback: always(ResponseCaptcha),
+			},
+		},
+	}
+
+	tests := []struct {
+		url  string
+		want Classification
+	}{
+		{"http://shop.example.com/", ResponseSoftFail},
+		{"http://www.example.com/", ResponseBan},
+		{"http://img.cdn.example.com/", ResponseCaptcha},
+		{"http://other.org/", ResponseSuccess},
+	}
+
+	for _, tt := range tests {
+		if got := classifyResponse(t, classifier, tt.url, http.StatusOK, nil, "ok"); got != tt.want {
+			t.Errorf("%s classified as %s, want %s", tt.url, got, tt.want)
+		}
+	}
+
+	if got := classifier.PeekBytes(); got != defaultBlockPageBytes {
+		t.Errorf("PeekBytes() = %d, want the largest member's %d", got, defaultBlockPageBytes)
+	}
+}
+
+func TestRotatingClientClassifier(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.RetryDelay = time.Millisecond
+	opts.ResponseClassifier = BlockPageClassifier{}
+	breakerConfig := DefaultCircuitBreakerConfig()
+	breakerConfig.MaxFailures = 1
+	opts.CircuitBreaker = &breakerConfig
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var blockedHits, okHits int32
+	blocked := newBlockingProxyServer(t, "blocked", &blockedHits, func(w http.ResponseWriter, req *http.Request) {
+		if _, err := io.WriteString(w, `<script src="https://captcha-delivery.com/c.js"></script>`); err != nil {
+			t.Errorf("write failed: %v", err)
+		}
+	})
+	ok := newBlockingProxyServer(t, "ok", &okHits, func(w http.ResponseWriter, req *http.Request) {
+		if _, err := io.WriteString(w, "welcome"); err != nil {
+			t.Errorf("write failed: %v", err)
+		}
+	})
+	for _, srv := range []*httptest.Server{blocked, ok} {
+		if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		resp, err := r.RotatingClient().Get("http://shop.example.com/")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("reading body failed: %v", err)
+		}
+		if resp.Header.Get("X-Proxy") != "ok" || string(body) != "welcome" {
+			t.Fatalf("request %d = %q via %q, want the full page via the unblocked proxy", i, body, resp.Header.Get("X-Proxy"))
+		}
+	}
+
+	var blockedID string
+	for _, proxy := range mustList(t, r) {
+		if proxy.URL == blocked.URL {
+			blockedID = proxy.ID
+		}
+	}
+
+	// The captcha counts as a failure and bans the proxy for the host, but
+	// says nothing about the proxy itself, so its breaker stays closed
+	metrics, err := r.GetProxyMetrics(ctx, blockedID)
+	if err != nil {
+		t.Fatalf("GetProxyMetrics failed: %v", err)
+	}
+	if metrics.ErrorCount != 1 {
+		t.Errorf("ErrorCount = %d, want 1", metrics.ErrorCount)
+	}
+	if !r.bans.banned(blockedID, "shop.example.com") {
+		t.Error("captcha didn't ban the proxy for the host")
+	}
+	if !r.breakers.Load().Ready(blockedID) {
+		t.Error("captcha opened the proxy's circuit breaker")
+	}
+}
+
+func TestRotatingClientPeeksOnlyDocuments(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.ResponseClassifier = BlockPageClassifier{}
+	opts.BanDetection = &BanDetectionConfig{BodyPatterns: []string{"g-recaptcha"}}
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	release := make(chan struct{})
+	var hits int32
+	srv := newBlockingProxyServer(t, "stream", &hits, func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			if _, err := io.WriteString(w, "data: hello\n\n"); err != nil {
+				t.Errorf("write failed: %v", err)
+			}
+			w.(http.Flusher).Flush()
+			<-release
+		case "/download":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			if _, err := io.WriteString(w, `<div class="g-recaptcha">`); err != nil {
+				t.Errorf("write failed: %v", err)
+			}
+		}
+	})
+	// Runs before the server closes, which waits for the stream handler
+	t.Cleanup(func() { close(release) })
+
+	if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+		t.Fatalf("AddProxy failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		resp, err := r.RotatingClient().Get("http://stream.example.com/events")
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Get blocked reading ahead of an event stream")
+	}
+
+	resp, err := r.RotatingClient().Get("http://stream.example.com/download")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	resp.Body.Close()
+	if bans, _ := r.ListBans(ctx); len(bans) != 0 {
+		t.Errorf("binary download was searched for block pages: %+v", bans)
+	}
+}
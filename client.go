@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/greysquirr3l/lashes/internal/client"
+	"github.com/greysquirr3l/lashes/internal/rotation"
 )
 
 // ClientOptions configures the behavior of HTTP clients created with proxy support.
@@ -65,3 +66,131 @@ func (r *rotator) GetNextClient(ctx context.Context) (*http.Client, error) {
 		FollowRedirects: true,
 	})
 }
+
+// RoundTripper returns the rotator's shared rotating transport.
+// Every request made through it is sent via the next proxy in the rotation.
+func (r *rotator) RoundTripper() http.RoundTripper {
+	return r.transport
+}
+
+// RotatingClient returns an http.Client that selects a new proxy for every request.
+func (r *rotator) RotatingClient() *http.Client {
+	return &http.Client{
+		Transport: r.transport,
+		Timeout:   r.opts.RequestTimeout,
+	}
+}
+
+// rotatorSource adapts the rotator to the client.Source interface
+type rotatorSource struct {
+	r *rotator
+}
+
+// Select returns the next proxy in the rotation for the request, or the
+// session's proxy when the request context carries one, waiting for
+// capacity when every candidate is rate limited
+func (s *rotatorSource) Select(req *http.Request, exclude []string) (*Proxy, error) {
+	criteria, _ := CriteriaFromContext(req.Context())
+	sel := selection(criteria)
+	sel.TargetHost = req.URL.Hostname()
+
+	if sessionKey, ok := SessionFromContext(req.Context()); ok {
+		// Retries stay on the session's proxy; exclude only steers a re-pin
+		return s.r.sessionProxy(req.Context(), sessionKey, sel, exclude, true)
+	}
+
+	for _, id := range exclude {
+		sel = sel.without(id)
+	}
+	return s.r.nextProxyWait(req.Context(), sel)
+}
+
+// Report records the outcome of a request in the rotator's metrics and breakers,
+// asking for a retry when the proxy turned out to be banned by the site
+func (s *rotatorSource) Report(outcome client.Outcome) bool {
+	return s.r.recordOutcome(outcome.Request.Context(), outcome)
+}
+
+// recordOutcome feeds the result of a proxied request back into the rotator.
+// It reports whether the response showed the proxy to be banned or challenged
+// for the host, in which case the request is worth retrying elsewhere.
+func (r *rotator) recordOutcome(ctx context.Context, outcome client.Outcome) (retry bool) {
+	verdict := r.classify(ctx, outcome)
+	retry = verdict == client.Ban || verdict == client.Captcha
+
+	if limiter := r.limiter.Load(); limiter != nil && outcome.Response != nil {
+		limiter.observe(outcome.Proxy.ID, outcome.Request.URL.Hostname(), outcome.Response)
+	}
+
+	// A request the caller abandoned says nothing about the proxy
+	abandoned := outcome.Err != nil && outcome.Request.Context().Err() != nil
+
+	if breakers := r.breakers.Load(); breakers != nil && !abandoned {
+		// Only failures that point at the proxy itself count against its breaker
+		breakers.Record(outcome.Proxy.ID, verdict != client.TransientError, outcome.Latency)
+	}
+
+	if !abandoned {
+		if status, changed := r.health.observe(outcome.Proxy.ID, outcome.Err); changed {
+			_ = r.saveHealth(ctx, status)
+		}
+		r.observe(rotation.Feedback{
+			ProxyID: outcome.Proxy.ID,
+			Host:    outcome.Request.URL.Hostname(),
+			Latency: outcome.Latency,
+			Success: verdict == client.Success || verdict == client.SoftFail,
+		})
+	}
+
+	if r.metrics != nil {
+		// Metrics failures must never fail the request itself
+		_ = r.metrics.RecordRequest(ctx, outcome.Proxy.ID, outcome.Latency, verdict == client.Success)
+	}
+	return retry
+}
+
+// classify decides what an outcome means for its proxy. Ban detection runs
+// first; other responses go to the response classifier. Bans and captchas
+// ban the proxy from the request's host.
+func (r *rotator) classify(ctx context.Context, outcome client.Outcome) client.Classification {
+	if outcome.Err != nil || outcome.Response == nil {
+		return client.TransientError
+	}
+
+	resp := outcome.Response
+	peek := peekBytes(r.classifier)
+	if r.detector != nil {
+		peek = max(peek, r.detector.peekBytes())
+	}
+	body := client.PeekBody(resp, peek)
+
+	if r.detector != nil {
+		if reason := r.detector.detect(resp, body); reason != "" {
+			r.banHost(ctx, outcome, reason)
+			return client.Ban
+		}
+	}
+
+	verdict := classifyWith(r.classifier, resp, body)
+	if verdict == client.Ban || verdict == client.Captcha {
+		r.banHost(ctx, outcome, "classified as "+verdict.String())
+	}
+	return verdict
+}
+
+// banHost bans an outcome's proxy from the request's host
+func (r *rotator) banHost(ctx context.Context, outcome client.Outcome, reason string) {
+	host := normalizeBanDomain(outcome.Request.URL.Hostname())
+	if host == "" {
+		return
+	}
+
+	ttl := DefaultBanTTL
+	if r.detector != nil {
+		ttl = r.detector.config.TTL
+	}
+
+	ban := &Ban{ProxyID: outcome.Proxy.ID, Domain: host, Reason: reason, ExpiresAt: time.Now().Add(ttl)}
+	// The ban takes effect even if storing it fails
+	_ = r.bans.add(ctx, ban)
+}
@@ -1,9 +1,19 @@
 package lashes
 
 import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/greysquirr3l/lashes/internal/rotation"
 )
 
 func TestDefaultClientOptions(t *testing.T) {
@@ -77,3 +87,401 @@ func TestNewClient(t *testing.T) {
 	// Can't easily test more details without mocking, but at least we confirmed
 	// the client creation succeeds with different options
 }
+
+// newTestProxyServer starts an HTTP server that acts as a forward proxy and
+// answers every request itself with its name.
+func newTestProxyServer(t *testing.T, name string, hits *int32) *httptest.Server {
+	t.Helper()
+
+	return newBlockingProxyServer(t, name, hits, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// newBlockingProxyServer answers every request the way respond says, as if a
+// site had treated the proxy that way
+func newBlockingProxyServer(t *testing.T, name string, hits *int32, respond func(w http.ResponseWriter, req *http.Request)) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.Header().Set("X-Proxy", name)
+		respond(w, req)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestRotatingClient(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var hitsA, hitsB int32
+	proxyA := newTestProxyServer(t, "a", &hitsA)
+	proxyB := newTestProxyServer(t, "b", &hitsB)
+
+	for _, srv := range []*httptest.Server{proxyA, proxyB} {
+		if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+
+	httpClient := r.RotatingClient()
+
+	seen := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		resp, err := httpClient.Get("http://target.invalid/")
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		seen[resp.Header.Get("X-Proxy")]++
+		resp.Body.Close()
+	}
+
+	if seen["a"] != 2 || seen["b"] != 2 {
+		t.Errorf("requests per proxy = %v, want 2 each", seen)
+	}
+
+	metrics, err := r.GetAllMetrics(ctx)
+	if err != nil {
+		t.Fatalf("GetAllMetrics failed: %v", err)
+	}
+
+	var total int64
+	for _, m := range metrics {
+		total += m.TotalCalls
+	}
+	if total != 4 {
+		t.Errorf("recorded calls = %d, want 4", total)
+	}
+
+	// After removal every request goes through the remaining proxy
+	if err := r.RemoveProxy(ctx, proxyA.URL); err != nil {
+		t.Fatalf("RemoveProxy failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := httpClient.Get("http://target.invalid/")
+		if err != nil {
+			t.Fatalf("request after removal failed: %v", err)
+		}
+		if got := resp.Header.Get("X-Proxy"); got != "b" {
+			t.Errorf("proxy after removal = %q, want %q", got, "b")
+		}
+		resp.Body.Close()
+	}
+	if atomic.LoadInt32(&hitsA) != 2 {
+		t.Errorf("removed proxy hits = %d, want 2", hitsA)
+	}
+}
+
+func TestRotatingClientRetries(t *testing.T) {
+	ctx := context.Background()
+
+	newRetryRotator := func(t *testing.T, opts Options, handlers ...http.HandlerFunc) *rotator {
+		t.Helper()
+
+		opts.ValidateOnStart = false
+		r, err := newRotator(opts)
+		if err != nil {
+			t.Fatalf("newRotator failed: %v", err)
+		}
+
+		for _, h := range handlers {
+			srv := httptest.NewServer(h)
+			t.Cleanup(srv.Close)
+			if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+				t.Fatalf("AddProxy failed: %v", err)
+			}
+		}
+		return r
+	}
+
+	failing := func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	t.Run("Retries through a different proxy", func(t *testing.T) {
+		var bodies []string
+		echo := func(w http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(body))
+			w.WriteHeader(http.StatusOK)
+		}
+
+		opts := DefaultOptions()
+		opts.MaxRetries = 1
+		opts.RetryDelay = time.Millisecond
+		r := newRetryRotator(t, opts, failing, echo)
+
+		// Whichever proxy is picked first, the request must end up at the healthy one
+		for i := 0; i < 2; i++ {
+			req, err := http.NewRequest(http.MethodPut, "http://target.invalid/", strings.NewReader("payload"))
+			if err != nil {
+				t.Fatalf("NewRequest failed: %v", err)
+			}
+			resp, err := r.RotatingClient().Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+		}
+
+		for _, body := range bodies {
+			if body != "payload" {
+				t.Errorf("replayed body = %q, want %q", body, "payload")
+			}
+		}
+	})
+
+	t.Run("Non-idempotent requests are not retried by default", func(t *testing.T) {
+		var calls int32
+		counting := func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		opts := DefaultOptions()
+		opts.MaxRetries = 3
+		opts.RetryDelay = time.Millisecond
+		r := newRetryRotator(t, opts, counting, counting)
+
+		resp, err := r.RotatingClient().Post("http://target.invalid/", "text/plain", strings.NewReader("x"))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if got := atomic.LoadInt32(&calls); got != 1 {
+			t.Errorf("attempts = %d, want 1", got)
+		}
+	})
+
+	t.Run("Non-idempotent retries are opt-in", func(t *testing.T) {
+		var calls int32
+		counting := func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		opts := DefaultOptions()
+		opts.MaxRetries = 2
+		opts.RetryNonIdempotent = true
+		opts.Backoff = ExponentialBackoff{Initial: time.Millisecond}
+		r := newRetryRotator(t, opts, counting, counting)
+
+		resp, err := r.RotatingClient().Post("http://target.invalid/", "text/plain", strings.NewReader("x"))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if got := atomic.LoadInt32(&calls); got != 3 {
+			t.Errorf("attempts = %d, want 3", got)
+		}
+	})
+
+	t.Run("Sequence backoffs see the previous delay", func(t *testing.T) {
+		backoff := &recordingBackoff{}
+		opts := DefaultOptions()
+		opts.MaxRetries = 3
+		opts.Backoff = backoff
+		r := newRetryRotator(t, opts, failing, failing)
+
+		resp, err := r.RotatingClient().Get("http://target.invalid/")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		backoff.mu.Lock()
+		defer backoff.mu.Unlock()
+		want := []time.Duration{0, time.Millisecond, 2 * time.Millisecond}
+		if !slices.Equal(backoff.previous, want) {
+			t.Errorf("previous delays = %v, want %v", backoff.previous, want)
+		}
+	})
+
+	t.Run("Retry-After is honoured", func(t *testing.T) {
+		throttled := func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+
+		opts := DefaultOptions()
+		opts.MaxRetries = 1
+		opts.RetryDelay = time.Millisecond
+		r := newRetryRotator(t, opts, throttled)
+
+		start := time.Now()
+		resp, err := r.RotatingClient().Get("http://target.invalid/")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("retry waited %v, want at least 1s", elapsed)
+		}
+	})
+
+	// throttledOnce answers the first request across all proxies with a 429
+	// carrying retryAfter and every later one with a 200
+	throttledOnce := func(retryAfter string, hits map[string]*int32) func(name string) http.HandlerFunc {
+		var calls int32
+		return func(name string) http.HandlerFunc {
+			hits[name] = new(int32)
+			return func(w http.ResponseWriter, req *http.Request) {
+				atomic.AddInt32(hits[name], 1)
+				if atomic.AddInt32(&calls, 1) == 1 {
+					w.Header().Set("Retry-After", retryAfter)
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}
+		}
+	}
+
+	t.Run("Default options wait out Retry-After on another proxy", func(t *testing.T) {
+		hits := map[string]*int32{}
+		handler := throttledOnce("5", hits)
+		r := newRetryRotator(t, DefaultOptions(), handler("a"), handler("b"))
+
+		start := time.Now()
+		resp, err := r.RotatingClient().Get("http://target.invalid/")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		if elapsed := time.Since(start); elapsed < 5*time.Second {
+			t.Errorf("retry waited %v, want at least 5s", elapsed)
+		}
+		for name, count := range hits {
+			if got := atomic.LoadInt32(count); got != 1 {
+				t.Errorf("proxy %s got %d requests, want 1", name, got)
+			}
+		}
+	})
+
+	t.Run("Retry-After beyond the cap falls back to the backoff", func(t *testing.T) {
+		hits := map[string]*int32{}
+		handler := throttledOnce("86400", hits)
+
+		opts := DefaultOptions()
+		opts.RetryDelay = time.Millisecond
+		r := newRetryRotator(t, opts, handler("a"), handler("b"))
+
+		start := time.Now()
+		resp, err := r.RotatingClient().Get("http://target.invalid/")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("retry waited %v, want the backoff delay", elapsed)
+		}
+		for name, count := range hits {
+			if got := atomic.LoadInt32(count); got != 1 {
+				t.Errorf("proxy %s got %d requests, want 1", name, got)
+			}
+		}
+	})
+}
+
+func TestRotatingClientProxyCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var gotAuth atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotAuth.Store(req.Header.Get("Proxy-Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	// Credentials in the fields take precedence over the URL userinfo
+	proxyURL := strings.Replace(srv.URL, "http://", "http://stale:old@", 1)
+	if err := r.repo.Create(ctx, &Proxy{
+		ID:       "with-credentials",
+		URL:      proxyURL,
+		Type:     HTTP,
+		Username: "alice",
+		Password: "secret",
+		Enabled:  true,
+	}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	resp, err := r.RotatingClient().Get("http://example.test/")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	resp.Body.Close()
+
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:secret"))
+	if got, _ := gotAuth.Load().(string); got != want {
+		t.Errorf("Proxy-Authorization = %q, want %q", got, want)
+	}
+}
+
+func TestRotatingClientConsistentHash(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.Strategy = rotation.HashStrategy
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	hits := make([]int32, 4)
+	for i := range hits {
+		srv := newTestProxyServer(t, fmt.Sprintf("proxy-%d", i), &hits[i])
+		if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+
+	httpClient := r.RotatingClient()
+	exits := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		resp, err := httpClient.Get("http://shop.example.com/")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		exits[resp.Header.Get("X-Proxy")] = true
+	}
+
+	if len(exits) > rotation.DefaultSubsetSize {
+		t.Errorf("requests to one host left through %d proxies, want at most %d", len(exits), rotation.DefaultSubsetSize)
+	}
+}
@@ -14,16 +14,28 @@ import (
 
 // Strategy name constants
 const (
-	StrategyRoundRobin = "round-robin"
-	StrategyRandom     = "random"
-	StrategyWeighted   = "weighted"
-	StrategyLeastUsed  = "least-used"
+	StrategyRoundRobin     = "round-robin"
+	StrategyRandom         = "random"
+	StrategyWeighted       = "weighted"
+	StrategyLeastUsed      = "least-used"
+	StrategyLatency        = "latency"
+	StrategyHash           = "consistent-hash"
+	StrategyThompson       = "thompson"
+	StrategyThompsonHost   = "thompson-per-host"
+	StrategySmoothWeighted = "smooth-weighted"
 
 	// Alternative names for backward compatibility
 	StrategyRoundRobinAlt = "roundrobin"
 	StrategyLeastUsedAlt  = "leastused"
 )
 
+// Circuit breaker mode names used in configuration files
+const (
+	BreakerModeNameConsecutive = "consecutive"
+	BreakerModeNameCountWindow = "count-window"
+	BreakerModeNameTimeWindow  = "time-window"
+)
+
 // Config represents the configuration for the proxy rotator
 type Config struct {
 	Storage struct {
@@ -50,6 +62,13 @@ type Config struct {
 		MaxFailures         int    `json:"max_failures"`
 		ResetTimeout        string `json:"reset_timeout"`
 		EnableGlobalBreaker bool   `json:"enable_global_breaker"`
+
+		Mode                 string  `json:"mode,omitempty"`
+		WindowSize           int     `json:"window_size,omitempty"`
+		WindowDuration       string  `json:"window_duration,omitempty"`
+		FailureRateThreshold float64 `json:"failure_rate_threshold,omitempty"`
+		MinimumRequests      int     `json:"minimum_requests,omitempty"`
+		SlowCallThreshold    string  `json:"slow_call_threshold,omitempty"`
 	} `json:"circuit_breaker"`
 }
 
@@ -108,6 +127,9 @@ func applyConfig(config Config, options Options) (Options, error) {
 	// Apply timeouts
 	options = applyTimeoutConfig(config, options)
 
+	// Apply circuit breaker
+	options = applyCircuitBreakerConfig(config, options)
+
 	// Apply other settings
 	if config.TestURL != "" {
 		options.TestURL = config.TestURL
@@ -144,19 +166,75 @@ func applyStorageConfig(config Config, options Options) Options {
 
 // applyStrategyConfig configures rotation strategy
 func applyStrategyConfig(config Config, options Options) Options {
-	switch config.Strategy {
-	case StrategyRoundRobin, StrategyRoundRobinAlt:
-		options.Strategy = rotation.RoundRobinStrategy
-	case StrategyRandom:
-		options.Strategy = rotation.RandomStrategy
-	case StrategyWeighted:
-		options.Strategy = rotation.WeightedStrategy
-	case StrategyLeastUsed, StrategyLeastUsedAlt:
-		options.Strategy = rotation.LeastUsedStrategy
+	if strategy, ok := strategyFromName(config.Strategy); ok {
+		options.Strategy = strategy
 	}
 	return options
 }
 
+// strategyFromName resolves a strategy name from a config file or the
+// environment to a built-in or registered strategy. The legacy spellings
+// "roundrobin" and "leastused" are accepted as well.
+func strategyFromName(name string) (rotation.StrategyType, bool) {
+	switch name {
+	case StrategyRoundRobinAlt:
+		name = StrategyRoundRobin
+	case StrategyLeastUsedAlt:
+		name = StrategyLeastUsed
+	}
+
+	strategy := rotation.StrategyType(name)
+	return strategy, name != "" && rotation.Registered(strategy)
+}
+
+// applyCircuitBreakerConfig enables circuit breakers when the config asks for them
+func applyCircuitBreakerConfig(config Config, options Options) Options {
+	if !config.CircuitBreaker.Enabled {
+		return options
+	}
+
+	breakerConfig := DefaultCircuitBreakerConfig()
+	breakerConfig.EnableGlobalBreaker = config.CircuitBreaker.EnableGlobalBreaker
+
+	if config.CircuitBreaker.MaxFailures > 0 {
+		breakerConfig.MaxFailures = config.CircuitBreaker.MaxFailures
+	}
+
+	if config.CircuitBreaker.ResetTimeout != "" {
+		if timeout, err := time.ParseDuration(config.CircuitBreaker.ResetTimeout); err == nil {
+			breakerConfig.ResetTimeout = timeout
+		}
+	}
+
+	switch config.CircuitBreaker.Mode {
+	case BreakerModeNameConsecutive:
+		breakerConfig.Mode = BreakerModeConsecutive
+	case BreakerModeNameCountWindow:
+		breakerConfig.Mode = BreakerModeCountWindow
+	case BreakerModeNameTimeWindow:
+		breakerConfig.Mode = BreakerModeTimeWindow
+	}
+
+	breakerConfig.WindowSize = config.CircuitBreaker.WindowSize
+	breakerConfig.FailureRateThreshold = config.CircuitBreaker.FailureRateThreshold
+	breakerConfig.MinimumRequests = config.CircuitBreaker.MinimumRequests
+
+	if config.CircuitBreaker.WindowDuration != "" {
+		if duration, err := time.ParseDuration(config.CircuitBreaker.WindowDuration); err == nil {
+			breakerConfig.WindowDuration = duration
+		}
+	}
+
+	if config.CircuitBreaker.SlowCallThreshold != "" {
+		if threshold, err := time.ParseDuration(config.CircuitBreaker.SlowCallThreshold); err == nil {
+			breakerConfig.SlowCallThreshold = threshold
+		}
+	}
+
+	options.CircuitBreaker = &breakerConfig
+	return options
+}
+
 // applyTimeoutConfig configures timeout settings
 func applyTimeoutConfig(config Config, options Options) Options {
 	if config.Timeouts.Request != "" {
@@ -217,16 +295,8 @@ func applyStorageEnvConfig(options Options) Options {
 
 // applyStrategyEnvConfig applies rotation strategy configuration from environment
 func applyStrategyEnvConfig(options Options) Options {
-	strategyEnv := os.Getenv("LASHES_STRATEGY")
-	switch strategyEnv {
-	case StrategyRoundRobin, StrategyRoundRobinAlt:
-		options.Strategy = rotation.RoundRobinStrategy
-	case StrategyRandom:
-		options.Strategy = rotation.RandomStrategy
-	case StrategyWeighted:
-		options.Strategy = rotation.WeightedStrategy
-	case StrategyLeastUsed, StrategyLeastUsedAlt:
-		options.Strategy = rotation.LeastUsedStrategy
+	if strategy, ok := strategyFromName(os.Getenv("LASHES_STRATEGY")); ok {
+		options.Strategy = strategy
 	}
 
 	// Test URL
@@ -295,15 +365,8 @@ func buildConfigFromOptions(options Options) Config {
 	}
 
 	// Strategy
-	switch options.Strategy {
-	case rotation.RoundRobinStrategy:
-		config.Strategy = StrategyRoundRobin
-	case rotation.RandomStrategy:
-		config.Strategy = StrategyRandom
-	case rotation.WeightedStrategy:
-		config.Strategy = StrategyWeighted
-	case rotation.LeastUsedStrategy:
-		config.Strategy = StrategyLeastUsed
+	if options.Strategy != "" && rotation.Registered(options.Strategy) {
+		config.Strategy = string(options.Strategy)
 	}
 
 	// Other settings
@@ -311,6 +374,33 @@ func buildConfigFromOptions(options Options) Config {
 	config.ValidateOnStart = options.ValidateOnStart
 	config.MaxRetries = options.MaxRetries
 
+	// Circuit breaker
+	if options.CircuitBreaker != nil {
+		config.CircuitBreaker.Enabled = true
+		config.CircuitBreaker.MaxFailures = options.CircuitBreaker.MaxFailures
+		config.CircuitBreaker.ResetTimeout = options.CircuitBreaker.ResetTimeout.String()
+		config.CircuitBreaker.EnableGlobalBreaker = options.CircuitBreaker.EnableGlobalBreaker
+
+		switch options.CircuitBreaker.Mode {
+		case BreakerModeConsecutive:
+			config.CircuitBreaker.Mode = BreakerModeNameConsecutive
+		case BreakerModeCountWindow:
+			config.CircuitBreaker.Mode = BreakerModeNameCountWindow
+		case BreakerModeTimeWindow:
+			config.CircuitBreaker.Mode = BreakerModeNameTimeWindow
+		}
+
+		config.CircuitBreaker.WindowSize = options.CircuitBreaker.WindowSize
+		config.CircuitBreaker.FailureRateThreshold = options.CircuitBreaker.FailureRateThreshold
+		config.CircuitBreaker.MinimumRequests = options.CircuitBreaker.MinimumRequests
+		if options.CircuitBreaker.WindowDuration > 0 {
+			config.CircuitBreaker.WindowDuration = options.CircuitBreaker.WindowDuration.String()
+		}
+		if options.CircuitBreaker.SlowCallThreshold > 0 {
+			config.CircuitBreaker.SlowCallThreshold = options.CircuitBreaker.SlowCallThreshold.String()
+		}
+	}
+
 	// Timeouts
 	config.Timeouts.Request = options.RequestTimeout.String()
 	config.Timeouts.Validation = options.ValidationTimeout.String()
@@ -92,6 +92,18 @@ func TestLoadConfig(t *testing.T) {
 	if opts.MaxRetries != 5 {
 		t.Errorf("MaxRetries = %d, want %d", opts.MaxRetries, 5)
 	}
+
+	if opts.CircuitBreaker == nil {
+		t.Fatal("CircuitBreaker options should not be nil")
+	}
+
+	if opts.CircuitBreaker.MaxFailures != 3 || opts.CircuitBreaker.ResetTimeout != time.Second*20 {
+		t.Errorf("CircuitBreaker = %+v, want 3 failures and a 20s reset timeout", *opts.CircuitBreaker)
+	}
+
+	if !opts.CircuitBreaker.EnableGlobalBreaker {
+		t.Error("CircuitBreaker.EnableGlobalBreaker = false, want true")
+	}
 }
 
 func TestLoadConfigFromEnv(t *testing.T) {
@@ -145,6 +157,23 @@ func TestLoadConfigFromEnv(t *testing.T) {
 	if opts.RequestTimeout != time.Second*15 {
 		t.Errorf("RequestTimeout = %v, want %v", opts.RequestTimeout, time.Second*15)
 	}
+
+	for name, want := range map[string]rotation.StrategyType{
+		"latency":         rotation.LatencyStrategy,
+		"smooth-weighted": rotation.SmoothWeightedStrategy,
+		"roundrobin":      rotation.RoundRobinStrategy,
+		"leastused":       rotation.LeastUsedStrategy,
+	} {
+		os.Setenv("LASHES_STRATEGY", name)
+		if opts := LoadConfigFromEnv(); opts.Strategy != want {
+			t.Errorf("LASHES_STRATEGY=%s gave Strategy %s, want %s", name, opts.Strategy, want)
+		}
+	}
+
+	os.Setenv("LASHES_STRATEGY", "no-such-strategy")
+	if opts := LoadConfigFromEnv(); opts.Strategy == "no-such-strategy" {
+		t.Error("unknown LASHES_STRATEGY was accepted")
+	}
 }
 
 func TestSaveConfig(t *testing.T) {
@@ -162,6 +191,16 @@ func TestSaveConfig(t *testing.T) {
 		RequestTimeout:    8 * time.Second,
 		ValidationTimeout: 2 * time.Second,
 		RetryDelay:        750 * time.Millisecond,
+		CircuitBreaker: &CircuitBreakerConfig{
+			MaxFailures:          5,
+			ResetTimeout:         time.Minute,
+			MaxHalfOpenRequests:  1,
+			Mode:                 BreakerModeTimeWindow,
+			WindowDuration:       30 * time.Second,
+			FailureRateThreshold: 0.4,
+			MinimumRequests:      20,
+			SlowCallThreshold:    2 * time.Second,
+		},
 	}
 
 	// Create a temporary file for saving
@@ -199,4 +238,11 @@ func TestSaveConfig(t *testing.T) {
 	if loadedOpts.TestURL != "https://save-test.example.com" {
 		t.Errorf("Loaded TestURL = %s, want %s", loadedOpts.TestURL, "https://save-test.example.com")
 	}
+
+	if cb := loadedOpts.CircuitBreaker; cb == nil {
+		t.Error("Loaded CircuitBreaker options should not be nil")
+	} else if cb.Mode != BreakerModeTimeWindow || cb.WindowDuration != 30*time.Second ||
+		cb.FailureRateThreshold != 0.4 || cb.MinimumRequests != 20 || cb.SlowCallThreshold != 2*time.Second {
+		t.Errorf("Loaded CircuitBreaker = %+v, want the saved window settings", *cb)
+	}
 }
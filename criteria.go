@@ -0,0 +1,51 @@
+package lashes
+
+import (
+	"context"
+	"slices"
+
+	"github.com/greysquirr3l/lashes/internal/rotation"
+)
+
+// Criteria narrows proxy selection by country, type, tags, success rate,
+// latency and excluded IDs, and tells strategies about the target host
+type Criteria = rotation.Criteria
+
+// TagManager labels proxies with tags that Criteria can select on
+type TagManager interface {
+	// SetProxyTags replaces a proxy's tags and stores them with the proxy
+	SetProxyTags(ctx context.Context, proxyID string, tags ...string) error
+}
+
+var _ TagManager = (*rotator)(nil)
+
+// criteriaContextKey is the context key for WithCriteria
+type criteriaContextKey struct{}
+
+// WithCriteria returns a context that makes the rotating client select
+// proxies matching the criteria. The request's host replaces TargetHost.
+func WithCriteria(ctx context.Context, criteria Criteria) context.Context {
+	return context.WithValue(ctx, criteriaContextKey{}, criteria)
+}
+
+// CriteriaFromContext returns the criteria set by WithCriteria
+func CriteriaFromContext(ctx context.Context) (Criteria, bool) {
+	criteria, ok := ctx.Value(criteriaContextKey{}).(Criteria)
+	return criteria, ok
+}
+
+// GetProxyWith returns the next proxy matching the criteria
+func (r *rotator) GetProxyWith(ctx context.Context, criteria Criteria) (*Proxy, error) {
+	return r.nextProxy(ctx, selection(criteria))
+}
+
+// SetProxyTags replaces a proxy's tags
+func (r *rotator) SetProxyTags(ctx context.Context, proxyID string, tags ...string) error {
+	proxy, err := r.repo.GetByID(ctx, proxyID)
+	if err != nil {
+		return err
+	}
+
+	proxy.Tags = slices.Clone(tags)
+	return r.repo.Update(ctx, proxy)
+}
@@ -0,0 +1,178 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/rotation"
+)
+
+// recordingStrategy remembers the criteria it was given
+type recordingStrategy struct {
+	criteria rotation.Criteria
+}
+
+func (s *recordingStrategy) Next(ctx context.Context, proxies []*domain.Proxy) (*domain.Proxy, error) {
+	return proxies[0], nil
+}
+
+func (s *recordingStrategy) NextFor(ctx context.Context, proxies []*domain.Proxy, criteria rotation.Criteria) (*domain.Proxy, error) {
+	s.criteria = criteria
+	return proxies[0], nil
+}
+
+func TestGetProxyWith(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	setup := []struct {
+		url         string
+		proxyType   ProxyType
+		country     string
+		tags        []string
+		successRate float64
+		latency     int64
+	}{
+		{"http://us-res.example.com:8080", HTTP, "US", []string{"residential", "premium"}, 0.9, 100},
+		{"http://us-dc.example.com:8080", HTTP, "US", []string{"datacenter"}, 0.5, 20},
+		{"socks5://de-res.example.com:1080", SOCKS5, "DE", []string{"residential"}, 0.95, 800},
+	}
+
+	ids := map[string]string{}
+	for _, s := range setup {
+		if err := r.AddProxy(ctx, s.url, s.proxyType); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+	proxies, _ := r.List(ctx)
+	for _, proxy := range proxies {
+		for _, s := range setup {
+			if proxy.URL != s.url {
+				continue
+			}
+			ids[s.url] = proxy.ID
+			proxy.CountryCode = s.country
+			proxy.SuccessRate = s.successRate
+			proxy.Latency = s.latency
+			if err := r.SetProxyTags(ctx, proxy.ID, s.tags...); err != nil {
+				t.Fatalf("SetProxyTags failed: %v", err)
+			}
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		criteria Criteria
+		want     []string
+	}{
+		{"Country", Criteria{CountryCode: "us"}, []string{setup[0].url, setup[1].url}},
+		{"Type", Criteria{Type: SOCKS5}, []string{setup[2].url}},
+		{"Tags", Criteria{Tags: []string{"residential", "premium"}}, []string{setup[0].url}},
+		{"Min success rate", Criteria{MinSuccessRate: 0.8}, []string{setup[0].url, setup[2].url}},
+		{"Max latency", Criteria{MaxLatency: 200 * time.Millisecond}, []string{setup[0].url, setup[1].url}},
+		{"Exclude IDs", Criteria{CountryCode: "US", ExcludeIDs: []string{ids[setup[0].url]}}, []string{setup[1].url}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			seen := map[string]bool{}
+			for i := 0; i < 6; i++ {
+				proxy, err := r.GetProxyWith(ctx, tc.criteria)
+				if err != nil {
+					t.Fatalf("GetProxyWith failed: %v", err)
+				}
+				seen[proxy.URL] = true
+			}
+			if len(seen) != len(tc.want) {
+				t.Errorf("selected %v, want %v", seen, tc.want)
+			}
+			for _, url := range tc.want {
+				if !seen[url] {
+					t.Errorf("%s was never selected, want it among %v", url, tc.want)
+				}
+			}
+		})
+	}
+
+	t.Run("No match", func(t *testing.T) {
+		_, err := r.GetProxyWith(ctx, Criteria{CountryCode: "FR"})
+		if !errors.Is(err, ErrNoProxiesAvailable) {
+			t.Errorf("GetProxyWith() error = %v, want %v", err, ErrNoProxiesAvailable)
+		}
+	})
+
+	t.Run("Strategies receive the criteria", func(t *testing.T) {
+		strategy := &recordingStrategy{}
+		orig := r.strategy
+		r.strategy = strategy
+		defer func() { r.strategy = orig }()
+
+		criteria := Criteria{TargetHost: "shop.example.com", Tags: []string{"residential"}}
+		if _, err := r.GetProxyWith(ctx, criteria); err != nil {
+			t.Fatalf("GetProxyWith failed: %v", err)
+		}
+		if strategy.criteria.TargetHost != criteria.TargetHost || len(strategy.criteria.Tags) != 1 {
+			t.Errorf("strategy got %+v, want %+v", strategy.criteria, criteria)
+		}
+	})
+}
+
+func TestRotatingClientCriteria(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var hitsA, hitsB int32
+	proxyA := newTestProxyServer(t, "a", &hitsA)
+	proxyB := newTestProxyServer(t, "b", &hitsB)
+	for _, srv := range []*httptest.Server{proxyA, proxyB} {
+		if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+
+	proxies, _ := r.List(ctx)
+	for _, proxy := range proxies {
+		if proxy.URL == proxyB.URL {
+			if err := r.SetProxyTags(ctx, proxy.ID, "premium"); err != nil {
+				t.Fatalf("SetProxyTags failed: %v", err)
+			}
+		}
+	}
+
+	reqCtx := WithCriteria(ctx, Criteria{Tags: []string{"premium"}})
+	for i := 0; i < 4; i++ {
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, "http://target.example.com/", nil)
+		if err != nil {
+			t.Fatalf("NewRequest failed: %v", err)
+		}
+		resp, err := r.RotatingClient().Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if a, b := atomic.LoadInt32(&hitsA), atomic.LoadInt32(&hitsB); a != 0 || b != 4 {
+		t.Errorf("hits = a:%d b:%d, want every request through the premium proxy", a, b)
+	}
+}
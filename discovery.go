@@ -2,10 +2,20 @@ package lashes
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/greysquirr3l/lashes/internal/client/mock"
 	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/repository"
+	"github.com/greysquirr3l/lashes/internal/scraper/providers"
+	"github.com/greysquirr3l/lashes/internal/validation"
 )
 
 // DiscoveryProvider defines an interface for services that can discover proxies
@@ -43,33 +53,274 @@ func DefaultDiscoveryOptions() DiscoveryOptions {
 	}
 }
 
-// ParseProxiesFromText parses a text list of proxies in IP:Port format
+// ParseProxiesFromText parses a proxy list in any of the common provider formats:
+// ip:port, ip:port:user:pass, user:pass@ip:port, scheme:// URLs, [ipv6]:port
+// and CSV with a header row. Comments and blank lines are skipped and entries
+// are deduplicated by host:port.
+//
+// proxyType applies to entries without a scheme. Lines that can't be parsed
+// are reported as *ParseError values joined into the returned error, while
+// the proxies that did parse are still returned.
 func ParseProxiesFromText(text string, proxyType ProxyType) ([]*Proxy, error) {
-	// Create parser based on existing internal implementation
-	proxies := []*domain.Proxy{} // Initialize as empty slice instead of nil
-	// ... parsing logic ...
+	return providers.ParseProxyList(strings.NewReader(text), proxyType)
+}
+
+// ProxyImporter adds batches of proxies to a rotator
+type ProxyImporter interface {
+	// ImportProxies adds the given proxies and reports what happened to each one
+	ImportProxies(ctx context.Context, proxies []*Proxy, opts ImportOptions) (*ImportReport, error)
+}
+
+var _ ProxyImporter = (*rotator)(nil)
+
+// ImportOptions configures ImportProxies
+type ImportOptions struct {
+	// Validate checks each proxy against TestURL before it is added
+	Validate bool
+
+	// TestURL overrides the rotator's TestURL for validation
+	TestURL string
 
-	// Convert to public types
-	result := make([]*Proxy, len(proxies))
-	for i, p := range proxies {
-		result[i] = p
+	// Workers is the maximum number of proxies validated concurrently
+	Workers int
+}
+
+// DefaultImportOptions returns sensible defaults for importing proxies
+func DefaultImportOptions() ImportOptions {
+	return ImportOptions{
+		Validate: true,
+		Workers:  10,
 	}
+}
 
-	return result, nil
+// ImportEntry records what happened to a single proxy during an import
+type ImportEntry struct {
+	// Proxy is the stored proxy for added entries and the input otherwise
+	Proxy  *Proxy
+	Reason string
+	Err    error
 }
 
-// ImportProxies adds multiple proxies to the rotator
-func (r *rotator) ImportProxies(ctx context.Context, proxies []*Proxy) (int, error) {
-	var imported int
+// ImportReport lists the outcome of every proxy passed to ImportProxies
+type ImportReport struct {
+	Added            []ImportEntry
+	Duplicates       []ImportEntry
+	Invalid          []ImportEntry
+	ValidationFailed []ImportEntry
+}
+
+// Total returns the number of proxies covered by the report
+func (r *ImportReport) Total() int {
+	return len(r.Added) + len(r.Duplicates) + len(r.Invalid) + len(r.ValidationFailed)
+}
+
+// ImportProxies adds multiple proxies to the rotator. Proxies are copied, so
+// the inputs are never modified; their credentials, country, weight and other
+// fields are kept, and each stored copy is enabled and given an ID if it has
+// none. Proxies whose host:port is already in the rotator or earlier in the
+// batch are reported as duplicates. When opts.Validate is set, proxies are
+// validated concurrently by up to opts.Workers goroutines before being added.
+//
+// Problems with individual proxies are recorded in the report; the returned
+// error is only set if the import as a whole failed or ctx was cancelled.
+func (r *rotator) ImportProxies(ctx context.Context, proxies []*Proxy, opts ImportOptions) (*ImportReport, error) {
+	report := &ImportReport{}
+
+	existing, err := r.repo.List(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to list proxies: %w", err)
+	}
 
-	for _, proxy := range proxies {
-		err := r.AddProxy(ctx, proxy.URL, proxy.Type)
+	seen := make(map[string]bool, len(existing)+len(proxies))
+	for _, proxy := range existing {
+		if key, err := importKey(proxy); err == nil {
+			seen[key] = true
+		}
+	}
+
+	// Normalise and deduplicate before doing any network work
+	var candidates []*Proxy
+	for _, input := range proxies {
+		proxy, err := r.prepareImport(input)
 		if err != nil {
-			// Continue with other proxies even if some fail
+			report.Invalid = append(report.Invalid, ImportEntry{Proxy: input, Reason: err.Error(), Err: err})
 			continue
 		}
-		imported++
+
+		key, err := importKey(proxy)
+		if err != nil {
+			report.Invalid = append(report.Invalid, ImportEntry{Proxy: input, Reason: err.Error(), Err: err})
+			continue
+		}
+		if seen[key] {
+			report.Duplicates = append(report.Duplicates, ImportEntry{
+				Proxy:  input,
+				Reason: fmt.Sprintf("duplicate host:port %s", key),
+			})
+			continue
+		}
+		seen[key] = true
+
+		candidates = append(candidates, proxy)
+	}
+
+	var validationErrs []error
+	if opts.Validate {
+		validationErrs = r.validateImports(ctx, candidates, opts)
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+
+	for i, proxy := range candidates {
+		if validationErrs != nil && validationErrs[i] != nil {
+			report.ValidationFailed = append(report.ValidationFailed, ImportEntry{
+				Proxy:  proxy,
+				Reason: validationErrs[i].Error(),
+				Err:    validationErrs[i],
+			})
+			continue
+		}
+
+		if err := r.repo.Create(ctx, proxy); err != nil {
+			switch {
+			case errors.Is(err, repository.ErrDuplicateID):
+				report.Duplicates = append(report.Duplicates, ImportEntry{Proxy: proxy, Reason: err.Error(), Err: err})
+			case errors.Is(err, repository.ErrInvalidProxy):
+				report.Invalid = append(report.Invalid, ImportEntry{Proxy: proxy, Reason: err.Error(), Err: err})
+			default:
+				return report, fmt.Errorf("failed to store proxy: %w", err)
+			}
+			continue
+		}
+
+		report.Added = append(report.Added, ImportEntry{Proxy: proxy})
+	}
+
+	return report, nil
+}
+
+// prepareImport returns a normalised copy of a proxy ready to be stored
+func (r *rotator) prepareImport(input *Proxy) (*Proxy, error) {
+	if input == nil {
+		return nil, fmt.Errorf("%w: nil proxy", ErrInvalidProxy)
+	}
+
+	parsedURL, err := mock.ParseURL(input.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProxy, err)
+	}
+	if parsedURL.Hostname() == "" {
+		return nil, fmt.Errorf("%w: missing host in %q", ErrInvalidProxy, parsedURL.Redacted())
+	}
+
+	proxy := *input
+
+	if proxy.Type == "" {
+		proxyType, ok := domain.ProxyTypeFromScheme(parsedURL.Scheme)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot infer proxy type from scheme %q", ErrInvalidProxy, parsedURL.Scheme)
+		}
+		proxy.Type = proxyType
+	}
+
+	switch proxy.Type {
+	case HTTP, SOCKS4, SOCKS5:
+	default:
+		return nil, fmt.Errorf("%w: unsupported proxy type %q", ErrInvalidProxy, proxy.Type)
+	}
+
+	now := time.Now()
+	if proxy.ID == "" {
+		proxy.ID = uuid.New().String()
+	}
+	if proxy.MaxRetries == 0 {
+		proxy.MaxRetries = r.opts.MaxRetries
+	}
+	if proxy.Timeout == 0 {
+		proxy.Timeout = r.opts.RequestTimeout
+	}
+	if proxy.CreatedAt.IsZero() {
+		proxy.CreatedAt = now
+	}
+	proxy.UpdatedAt = now
+	proxy.Enabled = true
+
+	return &proxy, nil
+}
+
+// validateImports validates proxies concurrently, returning one error per
+// proxy (nil when it passed). Proxies not reached before ctx is done are left nil.
+func (r *rotator) validateImports(ctx context.Context, proxies []*Proxy, opts ImportOptions) []error {
+	errs := make([]error, len(proxies))
+	if len(proxies) == 0 {
+		return errs
 	}
 
-	return imported, nil
+	testURL := opts.TestURL
+	if testURL == "" {
+		testURL = r.opts.TestURL
+	}
+
+	validator := validation.NewValidator(validation.Config{
+		Timeout:    r.opts.ValidationTimeout,
+		RetryCount: r.opts.MaxRetries,
+		TestURL:    testURL,
+	})
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(proxies) {
+		workers = len(proxies)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = validateImport(ctx, validator, proxies[i])
+			}
+		}()
+	}
+
+feed:
+	for i := range proxies {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return errs
+}
+
+// validateImport validates a single proxy and records its latency
+func validateImport(ctx context.Context, validator validation.Validator, proxy *Proxy) error {
+	valid, latency, err := validator.Validate(ctx, proxy)
+	if err != nil {
+		return NewValidationError(proxy.ID, proxy.URL, err.Error(), 0)
+	}
+	if !valid {
+		return NewValidationError(proxy.ID, proxy.URL, "", 0)
+	}
+
+	proxy.Latency = latency.Milliseconds()
+	return nil
+}
+
+// importKey returns the lowercase host:port used to detect duplicate proxies
+func importKey(proxy *Proxy) (string, error) {
+	parsedURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidProxy, err)
+	}
+	return strings.ToLower(parsedURL.Host), nil
 }
@@ -35,6 +35,18 @@
 //	// Make a request using the proxy
 //	resp, err := client.Get("https://api.ipify.org?format=json")
 //
+// # Per-Request Rotation
+//
+// Client pins a single proxy for the lifetime of the returned http.Client.
+// For long-lived clients, RotatingClient selects a new proxy on every request
+// and reuses connections per proxy:
+//
+//	client := rotator.RotatingClient()
+//	for _, u := range urls {
+//		resp, err := client.Get(u) // each request uses the next proxy
+//		...
+//	}
+//
 // # Rotation Strategies
 //
 // Lashes supports four proxy rotation strategies:
@@ -60,10 +72,12 @@
 //	// Configure health check options
 //	healthOpts := lashes.DefaultHealthCheckOptions()
 //	healthOpts.Interval = time.Minute * 5
+//	healthOpts.RunImmediately = true
 //
 //	// Start periodic health checking
 //	ctx := context.Background()
-//	rotator.StartHealthCheck(ctx, healthOpts)
+//	checker, err := rotator.(lashes.HealthManager).StartHealthCheck(ctx, healthOpts)
+//	defer checker.Stop()
 //
 // # Customization
 //
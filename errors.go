@@ -3,6 +3,9 @@ package lashes
 import (
 	"errors"
 	"fmt"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/scraper/providers"
 )
 
 // Base error types
@@ -24,6 +27,15 @@ var (
 
 	// ErrValidationFailed is returned when proxy validation fails
 	ErrValidationFailed = errors.New("proxy validation failed")
+
+	// ErrCircuitOpen is returned when the global circuit breaker is rejecting requests
+	ErrCircuitOpen = errors.New("circuit breaker open")
+
+	// ErrRateLimited is returned when every available proxy has used up its rate limit
+	ErrRateLimited = errors.New("all proxies are rate limited")
+
+	// ErrHealthCheckStopped is returned when a stopped health checker is asked to run
+	ErrHealthCheckStopped = errors.New("health checker stopped")
 )
 
 // ValidationError provides detailed information about proxy validation failures
@@ -60,3 +72,25 @@ func NewValidationError(proxyID, proxyURL, reason string, statusCode int) *Valid
 		StatusCode: statusCode,
 	}
 }
+
+// CircuitBreakerError is returned when the global circuit breaker has tripped
+type CircuitBreakerError struct {
+	// RetryAfter is how long the breaker will stay open, if known
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *CircuitBreakerError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("global circuit breaker open, retry after %s", e.RetryAfter.Round(time.Millisecond))
+	}
+	return "global circuit breaker open"
+}
+
+// Is allows this error to be matched with errors.Is() against ErrCircuitOpen
+func (e *CircuitBreakerError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// ParseError describes a proxy list line that ParseProxiesFromText could not parse
+type ParseError = providers.ParseError
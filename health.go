@@ -2,9 +2,11 @@ package lashes
 
 import (
 	"context"
-	"fmt"
-	"sync"
+	"errors"
 	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/repository"
 )
 
 // HealthCheckOptions configures health check behavior
@@ -18,11 +20,24 @@ type HealthCheckOptions struct {
 	// HealthURL is the URL used for health checks
 	HealthURL string
 
-	// MaxFailures is the number of failures before marking a proxy as inactive
+	// MaxFailures is the number of failed checks in a row that quarantines a
+	// proxy. Proxies that have failed fewer are suspect but stay in rotation.
 	MaxFailures int
 
 	// Parallel is the number of concurrent health checks
 	Parallel int
+
+	// RunImmediately runs the first check as soon as the checker starts
+	// instead of after one Interval
+	RunImmediately bool
+
+	// OnResult is called with the outcome of every proxy checked. Calls are
+	// serialized, so the callback needs no locking of its own.
+	OnResult func(HealthCheckResult)
+
+	// OnComplete is called with the summary of every finished run, for
+	// example to alert when too few proxies are left in rotation
+	OnComplete func(HealthCheckSummary)
 }
 
 // DefaultHealthCheckOptions returns sensible default options for health checking
@@ -36,94 +51,174 @@ func DefaultHealthCheckOptions() HealthCheckOptions {
 	}
 }
 
-// StartHealthCheck starts periodic health checking of all proxies
-func (r *rotator) StartHealthCheck(ctx context.Context, opts HealthCheckOptions) error {
-	go func() {
-		ticker := time.NewTicker(opts.Interval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				if err := r.performHealthCheck(ctx, opts); err != nil {
-					// Log error or handle it if needed
-					// But don't exit the goroutine
-				}
-			}
-		}
-	}()
+// withDefaults fills in unset fields from DefaultHealthCheckOptions
+func (o HealthCheckOptions) withDefaults() HealthCheckOptions {
+	defaults := DefaultHealthCheckOptions()
+	if o.Interval <= 0 {
+		o.Interval = defaults.Interval
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = defaults.Timeout
+	}
+	if o.HealthURL == "" {
+		o.HealthURL = defaults.HealthURL
+	}
+	if o.MaxFailures <= 0 {
+		o.MaxFailures = defaults.MaxFailures
+	}
+	if o.Parallel <= 0 {
+		o.Parallel = defaults.Parallel
+	}
+	return o
+}
 
-	return nil
+// HealthState is a proxy's place in the health lifecycle, merged from health
+// checks and live traffic
+type HealthState = domain.HealthState
+
+// Health lifecycle states: healthy -> suspect -> quarantined -> retired
+const (
+	HealthHealthy     = domain.HealthHealthy
+	HealthSuspect     = domain.HealthSuspect
+	HealthQuarantined = domain.HealthQuarantined
+	HealthRetired     = domain.HealthRetired
+)
+
+// HealthStatus is a point-in-time view of one proxy's health
+type HealthStatus struct {
+	// ProxyID is the proxy the status describes
+	ProxyID string
+
+	// State is the proxy's current health
+	State HealthState
+
+	// Failures is the number of health checks and re-probes failed in a row
+	Failures int
+
+	// ConsecutiveErrors is the number of connection errors in a row in live traffic
+	ConsecutiveErrors int
+
+	// RecentRequests and RecentFailures cover the passive failure-rate window
+	RecentRequests int
+	RecentFailures int
+
+	// LastError describes the most recent failure
+	LastError string
+
+	// Since is when the proxy entered its current state
+	Since time.Time
+
+	// NextProbe is when a quarantined proxy is probed next; zero otherwise
+	NextProbe time.Time
 }
 
-// performHealthCheck runs a health check on all proxies
-func (r *rotator) performHealthCheck(ctx context.Context, opts HealthCheckOptions) error {
-	proxies, err := r.List(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to list proxies: %w", err)
-	}
+// HealthManager is implemented by rotators that track proxy health from
+// health checks and, with Options.PassiveHealth, from live traffic
+type HealthManager interface {
+	// StartHealthCheck starts probing every proxy on opts.Interval until the
+	// returned checker is stopped or ctx is done
+	StartHealthCheck(ctx context.Context, opts HealthCheckOptions) (*HealthChecker, error)
 
-	if len(proxies) == 0 {
-		return nil // No proxies to check, not an error
-	}
+	// GetHealthStatus reports whether each proxy is enabled and in rotation
+	GetHealthStatus(ctx context.Context) (map[string]bool, error)
 
-	// Use a wait group to limit concurrency
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, opts.Parallel)
+	// ProxyHealth returns the health of one proxy
+	ProxyHealth(proxyID string) HealthStatus
 
-	// Track errors
-	var errMu sync.Mutex
-	var errors []error
+	// HealthSnapshot returns the health of every proxy with a recorded
+	// problem, sorted by proxy ID. Proxies missing from it are healthy.
+	HealthSnapshot() []HealthStatus
 
-	for _, proxy := range proxies {
-		wg.Add(1)
-		semaphore <- struct{}{}
+	// ReinstateProxy returns a quarantined or retired proxy to rotation
+	ReinstateProxy(ctx context.Context, proxyID string) error
 
-		go func(proxy *Proxy) {
-			defer wg.Done()
-			defer func() { <-semaphore }()
+	// Close stops re-probing quarantined proxies in the background. Call it
+	// when discarding the rotator; it keeps working, but quarantined proxies
+	// then only recover through health checks or ReinstateProxy.
+	Close() error
+}
 
-			// Create context with timeout
-			checkCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
-			defer cancel()
+var _ HealthManager = (*rotator)(nil)
 
-			// Check the proxy health
-			valid, _, err := r.ValidateProxy(checkCtx, proxy, opts.HealthURL)
-			if err != nil {
-				// If validation fails, consider the proxy invalid
-				valid = false
-			}
+// probeProxy fetches targetURL through a proxy. It returns how long the
+// request took and why it failed, or nil.
+func (r *rotator) probeProxy(ctx context.Context, proxy *Proxy, targetURL string) (time.Duration, error) {
+	valid, latency, err := r.ValidateProxy(ctx, proxy, targetURL)
+	if err != nil {
+		return latency, err
+	}
+	if !valid {
+		return latency, ErrValidationFailed
+	}
+	return latency, nil
+}
 
-			// Update proxy status if needed
-			if proxy.Enabled != valid {
-				proxy.SetEnabled(valid) // This updates both Enabled and IsActive
+// reprobe checks whether a quarantined proxy has recovered. It runs on the
+// health tracker's backoff timer.
+func (r *rotator) reprobe(proxyID string, generation uint64) {
+	ctx := r.health.ctx
 
-				// Update the proxy in the repository
-				updateCtx, updateCancel := context.WithTimeout(ctx, 5*time.Second)
-				defer updateCancel()
+	proxy, err := r.repo.GetByID(ctx, proxyID)
+	if errors.Is(err, repository.ErrProxyNotFound) {
+		r.health.forget(proxyID)
+		return
+	}
+	if err == nil {
+		_, err = r.probeProxy(ctx, proxy, r.health.config.ProbeURL)
+	}
 
-				if updateErr := r.repo.Update(updateCtx, proxy); updateErr != nil {
-					errMu.Lock()
-					errors = append(errors, fmt.Errorf("failed to update proxy %s: %w", proxy.ID, updateErr))
-					errMu.Unlock()
-				}
-			}
-		}(proxy)
+	if status, changed := r.health.reprobed(proxyID, generation, err); changed {
+		_ = r.saveHealth(ctx, status)
+	}
+}
+
+// saveHealth stores a proxy's health state with it. Proxies retired while
+// QuarantineConfig.DeleteRetired is set are removed instead. The state is
+// already in force in memory, so a failed save only loses it on restart.
+func (r *rotator) saveHealth(ctx context.Context, status HealthStatus) error {
+	proxy, err := r.repo.GetByID(ctx, status.ProxyID)
+	if err != nil {
+		return err
 	}
 
-	wg.Wait()
+	if status.State == HealthRetired && r.health.config.DeleteRetired {
+		return r.removeProxy(ctx, proxy)
+	}
 
-	// Handle any errors
-	if len(errors) > 0 {
-		return fmt.Errorf("health check completed with %d errors", len(errors))
+	// Update a copy so readers of the stored proxy never see a partial change
+	updated := *proxy
+	updated.Health = status.State
+	updated.HealthFailures = status.Failures
+	updated.HealthSince = nil
+	if status.State != HealthHealthy {
+		since := status.Since
+		updated.HealthSince = &since
 	}
+	return r.repo.Update(ctx, &updated)
+}
 
+// Close stops the health tracker's background re-probes
+func (r *rotator) Close() error {
+	r.health.close()
 	return nil
 }
 
-// GetHealthStatus returns the current health status of all proxies
+// reenable turns a proxy disabled by an earlier version's health checker,
+// which disabled proxies after one failed check, back on. The proxy is marked
+// healthy as well, so this only happens once per proxy.
+func (r *rotator) reenable(ctx context.Context, proxyID string) error {
+	proxy, err := r.repo.GetByID(ctx, proxyID)
+	if err != nil {
+		return err
+	}
+
+	updated := *proxy
+	updated.Enabled = true
+	updated.Health = HealthHealthy
+	return r.repo.Update(ctx, &updated)
+}
+
+// GetHealthStatus returns whether each proxy is enabled and in rotation
 func (r *rotator) GetHealthStatus(ctx context.Context) (map[string]bool, error) {
 	proxies, err := r.List(ctx)
 	if err != nil {
@@ -132,8 +227,28 @@ func (r *rotator) GetHealthStatus(ctx context.Context) (map[string]bool, error)
 
 	status := make(map[string]bool, len(proxies))
 	for _, proxy := range proxies {
-		status[proxy.ID] = proxy.Enabled
+		status[proxy.ID] = proxy.Enabled && r.health.inRotation(proxy.ID)
 	}
 
 	return status, nil
 }
+
+// ProxyHealth returns the health of one proxy
+func (r *rotator) ProxyHealth(proxyID string) HealthStatus {
+	return r.health.status(proxyID)
+}
+
+// HealthSnapshot returns the health of every proxy with a recorded problem
+func (r *rotator) HealthSnapshot() []HealthStatus {
+	return r.health.snapshot()
+}
+
+// ReinstateProxy clears a proxy's health record and returns it to rotation
+func (r *rotator) ReinstateProxy(ctx context.Context, proxyID string) error {
+	if _, err := r.repo.GetByID(ctx, proxyID); err != nil {
+		return err
+	}
+
+	r.health.forget(proxyID)
+	return r.saveHealth(ctx, HealthStatus{ProxyID: proxyID, State: HealthHealthy})
+}
@@ -0,0 +1,578 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/repository"
+)
+
+// newFlakyProxyServer starts a proxy that drops connections while down is set
+func newFlakyProxyServer(t *testing.T, name string, hits *int32, down *atomic.Bool) *httptest.Server {
+	t.Helper()
+
+	return newBlockingProxyServer(t, name, hits, func(w http.ResponseWriter, req *http.Request) {
+		if !down.Load() {
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %v", err)
+			return
+		}
+		if err := conn.Close(); err != nil {
+			t.Errorf("close failed: %v", err)
+		}
+	})
+}
+
+// waitForHealth polls a proxy's health until it reaches want
+func waitForHealth(t *testing.T, r *rotator, proxyID string, want HealthState) HealthStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := r.ProxyHealth(proxyID)
+		if status.State == want {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("proxy health = %s (%s), want %s", status.State, status.LastError, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// proxyIDByURL finds the ID of the proxy added for a test server
+func proxyIDByURL(t *testing.T, r *rotator, url string) string {
+	t.Helper()
+
+	for _, proxy := range mustList(t, r) {
+		if proxy.URL == url {
+			return proxy.ID
+		}
+	}
+	t.Fatalf("no proxy for %s", url)
+	return ""
+}
+
+func TestPassiveHealthConsecutiveErrors(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.RetryDelay = time.Millisecond
+	opts.PassiveHealth = &PassiveHealthConfig{ConsecutiveErrors: 2}
+	opts.Quarantine = &QuarantineConfig{
+		InitialBackoff: 20 * time.Millisecond,
+		MaxBackoff:     40 * time.Millisecond,
+		ProbeURL:       "http://probe.example.com/",
+	}
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var flakyHits, okHits int32
+	var down atomic.Bool
+	down.Store(true)
+	flaky := newFlakyProxyServer(t, "flaky", &flakyHits, &down)
+	ok := newTestProxyServer(t, "ok", &okHits)
+	for _, srv := range []*httptest.Server{flaky, ok} {
+		if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+	flakyID := proxyIDByURL(t, r, flaky.URL)
+
+	for i := 0; i < 4; i++ {
+		resp, err := r.RotatingClient().Get("http://example.com/")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	status := r.ProxyHealth(flakyID)
+	if status.State != HealthQuarantined {
+		t.Fatalf("State = %s after repeated connection errors, want %s", status.State, HealthQuarantined)
+	}
+	if status.NextProbe.IsZero() {
+		t.Error("quarantined proxy has no probe scheduled")
+	}
+
+	// Quarantined proxies are left out of rotation
+	for i := 0; i < 3; i++ {
+		proxy, err := r.GetProxy(ctx)
+		if err != nil {
+			t.Fatalf("GetProxy failed: %v", err)
+		}
+		if proxy.ID == flakyID {
+			t.Fatal("GetProxy returned a quarantined proxy")
+		}
+	}
+
+	healthy, err := r.GetHealthStatus(ctx)
+	if err != nil {
+		t.Fatalf("GetHealthStatus failed: %v", err)
+	}
+	if healthy[flakyID] {
+		t.Error("GetHealthStatus reports the quarantined proxy as healthy")
+	}
+
+	// Re-probes back off while the proxy stays down
+	probes := atomic.LoadInt32(&flakyHits)
+	time.Sleep(100 * time.Millisecond)
+	if got := atomic.LoadInt32(&flakyHits); got <= probes {
+		t.Error("quarantined proxy wasn't re-probed")
+	}
+	status = r.ProxyHealth(flakyID)
+	if status.State != HealthQuarantined || status.Failures == 0 {
+		t.Fatalf("health while the proxy is down = %+v, want quarantined with failed probes", status)
+	}
+
+	// A successful re-probe returns the proxy to rotation
+	down.Store(false)
+	waitForHealth(t, r, flakyID, HealthHealthy)
+
+	// Healthy proxies with a clean record are left out
+	if snapshot := r.HealthSnapshot(); len(snapshot) != 0 {
+		t.Errorf("HealthSnapshot() = %+v, want none once every proxy is healthy", snapshot)
+	}
+}
+
+func TestPassiveHealthFailureRate(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.MaxRetries = 0
+	opts.PassiveHealth = &PassiveHealthConfig{
+		ConsecutiveErrors:    10,
+		FailureRateThreshold: 0.5,
+		WindowSize:           4,
+		MinimumRequests:      4,
+	}
+	opts.Quarantine = &QuarantineConfig{InitialBackoff: time.Hour}
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var hits int32
+	var down atomic.Bool
+	srv := newFlakyProxyServer(t, "flaky", &hits, &down)
+	if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+		t.Fatalf("AddProxy failed: %v", err)
+	}
+	proxyID := proxyIDByURL(t, r, srv.URL)
+
+	get := func() {
+		t.Helper()
+		resp, err := r.RotatingClient().Get("http://example.com/")
+		if down.Load() {
+			if err == nil {
+				resp.Body.Close()
+				t.Fatal("Get through a dropped connection succeeded")
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// One failure in four stays under the threshold
+	down.Store(true)
+	get()
+	down.Store(false)
+	get()
+	get()
+	get()
+	if status := r.ProxyHealth(proxyID); status.State != HealthHealthy {
+		t.Fatalf("State = %s at a 25%% failure rate, want %s", status.State, HealthHealthy)
+	}
+	if snapshot := r.HealthSnapshot(); len(snapshot) != 1 || snapshot[0].RecentFailures != 1 {
+		t.Errorf("HealthSnapshot() = %+v, want the proxy with its recent failure", snapshot)
+	}
+
+	down.Store(true)
+	get()
+	get()
+	status := r.ProxyHealth(proxyID)
+	if status.State != HealthQuarantined {
+		t.Fatalf("State = %s at a 50%% failure rate, want %s", status.State, HealthQuarantined)
+	}
+	if status.RecentFailures != 2 || status.RecentRequests != 4 {
+		t.Errorf("window = %d/%d, want 2/4", status.RecentFailures, status.RecentRequests)
+	}
+
+	if _, err := r.GetProxy(ctx); !errors.Is(err, ErrNoProxiesAvailable) {
+		t.Errorf("GetProxy error = %v, want ErrNoProxiesAvailable", err)
+	}
+}
+
+func TestPassiveHealthIgnoresTargetErrors(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.RetryDelay = time.Millisecond
+	opts.PassiveHealth = &PassiveHealthConfig{
+		ConsecutiveErrors:    2,
+		FailureRateThreshold: 0.5,
+		WindowSize:           4,
+		MinimumRequests:      4,
+	}
+	opts.Quarantine = &QuarantineConfig{InitialBackoff: time.Hour}
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	// The target site is overloaded, whichever proxy asks
+	var hits int32
+	overloaded := func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	for _, name := range []string{"a", "b"} {
+		srv := newBlockingProxyServer(t, name, &hits, overloaded)
+		if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		resp, err := r.RotatingClient().Get("http://overloaded.example.com/")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	for _, proxy := range mustList(t, r) {
+		if status := r.ProxyHealth(proxy.ID); status.State != HealthHealthy {
+			t.Errorf("proxy %s: State = %s after target errors, want %s", proxy.ID, status.State, HealthHealthy)
+		}
+	}
+	if _, err := r.GetProxy(ctx); err != nil {
+		t.Errorf("GetProxy failed after target errors: %v", err)
+	}
+}
+
+func TestHealthCheckLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.MaxRetries = 0
+	opts.Quarantine = &QuarantineConfig{InitialBackoff: time.Hour}
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var hits int32
+	var down atomic.Bool
+	srv := newFlakyProxyServer(t, "flaky", &hits, &down)
+	if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+		t.Fatalf("AddProxy failed: %v", err)
+	}
+	proxyID := proxyIDByURL(t, r, srv.URL)
+
+	// Without PassiveHealth live traffic doesn't change health
+	down.Store(true)
+	for i := 0; i < 10; i++ {
+		if resp, err := r.RotatingClient().Get("http://example.com/"); err == nil {
+			resp.Body.Close()
+		}
+	}
+	if status := r.ProxyHealth(proxyID); status.State != HealthHealthy {
+		t.Fatalf("State = %s without passive health, want %s", status.State, HealthHealthy)
+	}
+
+	healthOpts := DefaultHealthCheckOptions()
+	healthOpts.HealthURL = "http://probe.example.com/"
+	healthOpts.MaxFailures = 2
+
+	check := func(want HealthState) {
+		t.Helper()
+		if summary := r.performHealthCheck(ctx, healthOpts); summary.Err != nil {
+			t.Fatalf("performHealthCheck failed: %v", summary.Err)
+		}
+		proxy, err := r.repo.GetByID(ctx, proxyID)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if status := r.ProxyHealth(proxyID); status.State != want || proxy.Health != want {
+			t.Fatalf("health = %s, stored %s, want %s", status.State, proxy.Health, want)
+		}
+		if !proxy.Enabled {
+			t.Fatal("a health check disabled the proxy")
+		}
+	}
+
+	// One failed check makes the proxy suspect but keeps it in rotation
+	check(HealthSuspect)
+	if _, err := r.GetProxy(ctx); err != nil {
+		t.Errorf("GetProxy failed for a suspect proxy: %v", err)
+	}
+
+	check(HealthQuarantined)
+	if _, err := r.GetProxy(ctx); !errors.Is(err, ErrNoProxiesAvailable) {
+		t.Errorf("GetProxy error = %v, want ErrNoProxiesAvailable", err)
+	}
+	if status := r.ProxyHealth(proxyID); status.Failures != 2 || status.LastError == "" {
+		t.Errorf("health = %+v, want 2 failures and an error", status)
+	}
+
+	down.Store(false)
+	check(HealthHealthy)
+	if _, err := r.GetProxy(ctx); err != nil {
+		t.Errorf("GetProxy failed after recovery: %v", err)
+	}
+}
+
+func TestHealthCheckReenablesLegacyDisabled(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var hits int32
+	srv := newTestProxyServer(t, "legacy", &hits)
+	if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+		t.Fatalf("AddProxy failed: %v", err)
+	}
+	proxyID := proxyIDByURL(t, r, srv.URL)
+
+	disable := func() {
+		t.Helper()
+		proxy, err := r.repo.GetByID(ctx, proxyID)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		disabled := *proxy
+		disabled.Enabled = false
+		if err := r.repo.Update(ctx, &disabled); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	healthOpts := DefaultHealthCheckOptions()
+	healthOpts.HealthURL = "http://probe.example.com/"
+
+	// Earlier versions disabled proxies after a failed check and stored no health state
+	disable()
+	summary := r.performHealthCheck(ctx, healthOpts)
+	if summary.Err != nil {
+		t.Fatalf("performHealthCheck failed: %v", summary.Err)
+	}
+	proxy, err := r.repo.GetByID(ctx, proxyID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if !proxy.Enabled || proxy.Health != HealthHealthy {
+		t.Errorf("proxy enabled = %v with health %q, want enabled and healthy", proxy.Enabled, proxy.Health)
+	}
+	if summary.InRotation != 1 {
+		t.Errorf("InRotation = %d, want 1", summary.InRotation)
+	}
+
+	// Proxies disabled since then stay disabled
+	disable()
+	if summary := r.performHealthCheck(ctx, healthOpts); summary.Err != nil {
+		t.Fatalf("performHealthCheck failed: %v", summary.Err)
+	}
+	if proxy, err := r.repo.GetByID(ctx, proxyID); err != nil || proxy.Enabled {
+		t.Errorf("proxy re-enabled twice (err %v)", err)
+	}
+}
+
+func TestQuarantineRetirement(t *testing.T) {
+	ctx := context.Background()
+
+	newQuarantineRotator := func(t *testing.T, deleteRetired bool) (*rotator, string) {
+		t.Helper()
+
+		opts := DefaultOptions()
+		opts.ValidateOnStart = false
+		opts.Quarantine = &QuarantineConfig{
+			ProbeURL:       "http://probe.example.com/",
+			InitialBackoff: 10 * time.Millisecond,
+			MaxBackoff:     10 * time.Millisecond,
+			RetireAfter:    30 * time.Millisecond,
+			DeleteRetired:  deleteRetired,
+		}
+
+		r, err := newRotator(opts)
+		if err != nil {
+			t.Fatalf("newRotator failed: %v", err)
+		}
+
+		var hits int32
+		var down atomic.Bool
+		down.Store(true)
+		srv := newFlakyProxyServer(t, "dead", &hits, &down)
+		if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+		proxyID := proxyIDByURL(t, r, srv.URL)
+
+		healthOpts := DefaultHealthCheckOptions()
+		healthOpts.HealthURL = "http://probe.example.com/"
+		healthOpts.MaxFailures = 1
+		if summary := r.performHealthCheck(ctx, healthOpts); summary.Err != nil {
+			t.Fatalf("performHealthCheck failed: %v", summary.Err)
+		}
+		return r, proxyID
+	}
+
+	t.Run("Flagged for review", func(t *testing.T) {
+		r, proxyID := newQuarantineRotator(t, false)
+
+		status := waitForHealth(t, r, proxyID, HealthRetired)
+		if !status.NextProbe.IsZero() {
+			t.Error("retired proxy still has a probe scheduled")
+		}
+		proxy, err := r.repo.GetByID(ctx, proxyID)
+		if err != nil {
+			t.Fatalf("retired proxy was removed: %v", err)
+		}
+		if proxy.Health != HealthRetired || proxy.HealthSince == nil {
+			t.Errorf("stored health = %s since %v, want retired", proxy.Health, proxy.HealthSince)
+		}
+
+		if err := r.ReinstateProxy(ctx, proxyID); err != nil {
+			t.Fatalf("ReinstateProxy failed: %v", err)
+		}
+		if status := r.ProxyHealth(proxyID); status.State != HealthHealthy {
+			t.Errorf("State = %s after ReinstateProxy, want %s", status.State, HealthHealthy)
+		}
+		if proxy, err := r.repo.GetByID(ctx, proxyID); err != nil || proxy.Health != HealthHealthy || proxy.HealthFailures != 0 {
+			t.Errorf("stored health after ReinstateProxy = %+v, %v", proxy, err)
+		}
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		r, proxyID := newQuarantineRotator(t, true)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if _, err := r.repo.GetByID(ctx, proxyID); errors.Is(err, repository.ErrProxyNotFound) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("retired proxy wasn't deleted")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if snapshot := r.HealthSnapshot(); len(snapshot) != 0 {
+			t.Errorf("HealthSnapshot() = %+v after deletion, want none", snapshot)
+		}
+	})
+}
+
+func TestHealthTrackerLoad(t *testing.T) {
+	since := time.Now().Add(-time.Hour)
+	tracker := newHealthTracker(QuarantineConfig{InitialBackoff: time.Hour}, nil)
+
+	tracker.load([]*Proxy{
+		{ID: "healthy"},
+		{ID: "suspect", Health: HealthSuspect, HealthFailures: 1, HealthSince: &since},
+		{ID: "quarantined", Health: HealthQuarantined, HealthFailures: 4, HealthSince: &since},
+		{ID: "retired", Health: HealthRetired, HealthFailures: 9, HealthSince: &since},
+	})
+
+	tests := []struct {
+		id         string
+		state      HealthState
+		inRotation bool
+	}{
+		{"healthy", HealthHealthy, true},
+		{"suspect", HealthSuspect, true},
+		{"quarantined", HealthQuarantined, false},
+		{"retired", HealthRetired, false},
+	}
+
+	for _, tt := range tests {
+		status := tracker.status(tt.id)
+		if status.State != tt.state || tracker.inRotation(tt.id) != tt.inRotation {
+			t.Errorf("%s: state %s, in rotation %v; want %s, %v", tt.id, status.State, tracker.inRotation(tt.id), tt.state, tt.inRotation)
+		}
+		if tt.state != HealthHealthy && !status.Since.Equal(since) {
+			t.Errorf("%s: Since = %v, want the stored %v", tt.id, status.Since, since)
+		}
+	}
+
+	if status := tracker.status("quarantined"); status.Failures != 4 || status.NextProbe.IsZero() {
+		t.Errorf("quarantined health = %+v, want 4 failures and a probe scheduled", status)
+	}
+}
+
+func TestHealthTrackerClose(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.Quarantine = &QuarantineConfig{
+		ProbeURL:       "http://probe.example.com/",
+		InitialBackoff: 5 * time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	}
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var hits int32
+	var down atomic.Bool
+	down.Store(true)
+	srv := newFlakyProxyServer(t, "dead", &hits, &down)
+	if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+		t.Fatalf("AddProxy failed: %v", err)
+	}
+
+	healthOpts := DefaultHealthCheckOptions()
+	healthOpts.HealthURL = "http://probe.example.com/"
+	healthOpts.MaxFailures = 1
+	if summary := r.performHealthCheck(ctx, healthOpts); summary.Err != nil {
+		t.Fatalf("performHealthCheck failed: %v", summary.Err)
+	}
+
+	// Wait for the re-probes to start
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&hits) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("quarantined proxy was never re-probed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	before := atomic.LoadInt32(&hits)
+	time.Sleep(100 * time.Millisecond)
+
+	if after := atomic.LoadInt32(&hits); after != before {
+		t.Errorf("proxy probed %d times after Close", after-before)
+	}
+}
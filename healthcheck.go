@@ -0,0 +1,292 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HealthCheckResult is the outcome of checking one proxy
+type HealthCheckResult struct {
+	// ProxyID and URL identify the proxy that was checked
+	ProxyID string
+	URL     string
+
+	// Healthy reports whether the check succeeded
+	Healthy bool
+
+	// Latency is how long the check took
+	Latency time.Duration
+
+	// Err is why the check failed; nil if it succeeded
+	Err error
+
+	// State is the proxy's health state after the check
+	State HealthState
+
+	// CheckedAt is when the check finished
+	CheckedAt time.Time
+}
+
+// HealthCheckSummary describes one run of the health checker
+type HealthCheckSummary struct {
+	// Started is when the run began
+	Started time.Time
+
+	// Duration is how long the run took
+	Duration time.Duration
+
+	// Total is the number of proxies in the rotator
+	Total int
+
+	// Checked is the number of proxies probed; retired proxies are skipped
+	Checked int
+
+	// Passed and Failed split the checked proxies by outcome
+	Passed int
+	Failed int
+
+	// InRotation is the number of enabled proxies left in rotation after the run
+	InRotation int
+
+	// Err holds the errors hit while listing proxies or storing their
+	// state; failed checks aren't errors
+	Err error
+}
+
+// HealthChecker runs periodic health checks until it is stopped.
+// It is safe for concurrent use.
+type HealthChecker struct {
+	r    *rotator
+	opts HealthCheckOptions
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// runMu keeps runs from overlapping
+	runMu sync.Mutex
+
+	mu      sync.RWMutex
+	last    HealthCheckSummary
+	hasLast bool
+}
+
+// StartHealthCheck starts periodic health checking of all proxies. Zero
+// fields in opts take their values from DefaultHealthCheckOptions.
+func (r *rotator) StartHealthCheck(ctx context.Context, opts HealthCheckOptions) (*HealthChecker, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &HealthChecker{
+		r:      r,
+		opts:   opts.withDefaults(),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go c.loop()
+	return c, nil
+}
+
+// loop runs checks on every tick until the checker is stopped
+func (c *HealthChecker) loop() {
+	defer close(c.done)
+
+	if c.opts.RunImmediately {
+		c.run(c.ctx)
+	}
+
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.run(c.ctx)
+		}
+	}
+}
+
+// run performs one check and records its summary. A run cut short by its
+// context isn't recorded.
+func (c *HealthChecker) run(ctx context.Context) HealthCheckSummary {
+	c.runMu.Lock()
+	defer c.runMu.Unlock()
+
+	summary := c.r.performHealthCheck(ctx, c.opts)
+	if ctx.Err() != nil || c.ctx.Err() != nil {
+		return summary
+	}
+
+	c.mu.Lock()
+	c.last, c.hasLast = summary, true
+	c.mu.Unlock()
+
+	if c.opts.OnComplete != nil {
+		c.opts.OnComplete(summary)
+	}
+	return summary
+}
+
+// RunNow runs a check right away and waits for it to finish. It waits for a
+// run already in progress first, and returns ErrHealthCheckStopped once the
+// checker has been stopped.
+func (c *HealthChecker) RunNow(ctx context.Context) (HealthCheckSummary, error) {
+	if c.ctx.Err() != nil {
+		return HealthCheckSummary{}, ErrHealthCheckStopped
+	}
+
+	// Stopping the checker cancels the run as well
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(c.ctx, cancel)
+	defer stop()
+
+	summary := c.run(ctx)
+	if c.ctx.Err() != nil {
+		return summary, ErrHealthCheckStopped
+	}
+	if err := ctx.Err(); err != nil {
+		return summary, err
+	}
+	return summary, summary.Err
+}
+
+// LastRun returns the summary of the most recent completed run, and false
+// if no run has completed yet
+func (c *HealthChecker) LastRun() (HealthCheckSummary, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.last, c.hasLast
+}
+
+// Stop stops the checker and waits for a run in progress to wind down.
+// It is safe to call more than once.
+func (c *HealthChecker) Stop() {
+	c.cancel()
+	<-c.done
+}
+
+// Done is closed once the checker has stopped, either through Stop or
+// because the context it was started with is done
+func (c *HealthChecker) Done() <-chan struct{} {
+	return c.done
+}
+
+// performHealthCheck runs a health check on all proxies except retired ones.
+// Failed checks make a proxy suspect and, after opts.MaxFailures in a row,
+// quarantine it; a successful check returns it to rotation.
+func (r *rotator) performHealthCheck(ctx context.Context, opts HealthCheckOptions) HealthCheckSummary {
+	summary := HealthCheckSummary{Started: time.Now()}
+
+	proxies, err := r.List(ctx)
+	if err != nil {
+		summary.Err = fmt.Errorf("failed to list proxies: %w", err)
+		summary.Duration = time.Since(summary.Started)
+		return summary
+	}
+	summary.Total = len(proxies)
+
+	// Use a wait group to limit concurrency
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, max(opts.Parallel, 1))
+
+	// Results are tallied and reported one at a time
+	var resultMu sync.Mutex
+	var updateErrors []error
+	reenabled := make(map[string]bool)
+
+dispatch:
+	for _, proxy := range proxies {
+		if r.health.status(proxy.ID).State == HealthRetired {
+			continue
+		}
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+		wg.Add(1)
+
+		go func(proxy *Proxy) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			// Create context with timeout
+			checkCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+
+			latency, checkErr := r.probeProxy(checkCtx, proxy, opts.HealthURL)
+			if ctx.Err() != nil {
+				// The run was stopped; the failure says nothing about the proxy
+				return
+			}
+
+			status, changed := r.health.probed(proxy.ID, checkErr, opts.MaxFailures)
+
+			updateCtx, updateCancel := context.WithTimeout(ctx, 5*time.Second)
+			defer updateCancel()
+
+			var updateErr error
+			if changed {
+				// Store the new state with the proxy
+				updateErr = r.saveHealth(updateCtx, status)
+			} else {
+				status = r.health.status(proxy.ID)
+			}
+
+			// Proxies disabled by an earlier version's checker have no health state
+			migrate := checkErr == nil && !proxy.Enabled && proxy.Health == ""
+			if migrate {
+				updateErr = errors.Join(updateErr, r.reenable(updateCtx, proxy.ID))
+			}
+
+			resultMu.Lock()
+			defer resultMu.Unlock()
+
+			summary.Checked++
+			if checkErr == nil {
+				summary.Passed++
+			} else {
+				summary.Failed++
+			}
+			if updateErr != nil {
+				updateErrors = append(updateErrors, fmt.Errorf("failed to update proxy %s: %w", proxy.ID, updateErr))
+			} else if migrate {
+				reenabled[proxy.ID] = true
+			}
+
+			if opts.OnResult != nil {
+				opts.OnResult(HealthCheckResult{
+					ProxyID:   proxy.ID,
+					URL:       proxy.URL,
+					Healthy:   checkErr == nil,
+					Latency:   latency,
+					Err:       checkErr,
+					State:     status.State,
+					CheckedAt: time.Now(),
+				})
+			}
+		}(proxy)
+	}
+
+	wg.Wait()
+
+	for _, proxy := range proxies {
+		if (proxy.Enabled || reenabled[proxy.ID]) && r.health.inRotation(proxy.ID) {
+			summary.InRotation++
+		}
+	}
+
+	// Handle any errors
+	if len(updateErrors) > 0 {
+		summary.Err = fmt.Errorf("health check completed with %d errors: %w", len(updateErrors), errors.Join(updateErrors...))
+	}
+	summary.Duration = time.Since(summary.Started)
+	return summary
+}
@@ -0,0 +1,185 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckerRunImmediately(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.Quarantine = &QuarantineConfig{InitialBackoff: time.Hour}
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var upHits, downHits int32
+	var up, down atomic.Bool
+	down.Store(true)
+	upSrv := newFlakyProxyServer(t, "up", &upHits, &up)
+	downSrv := newFlakyProxyServer(t, "down", &downHits, &down)
+	for _, srv := range []string{upSrv.URL, downSrv.URL} {
+		if err := r.AddProxy(ctx, srv, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+	downID := proxyIDByURL(t, r, downSrv.URL)
+
+	var mu sync.Mutex
+	results := make(map[string]HealthCheckResult)
+	summaries := make(chan HealthCheckSummary, 1)
+
+	checker, err := r.StartHealthCheck(ctx, HealthCheckOptions{
+		Interval:       time.Hour,
+		HealthURL:      "http://probe.example.com/",
+		MaxFailures:    1,
+		RunImmediately: true,
+		OnResult: func(result HealthCheckResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			results[result.URL] = result
+		},
+		OnComplete: func(summary HealthCheckSummary) {
+			summaries <- summary
+		},
+	})
+	if err != nil {
+		t.Fatalf("StartHealthCheck failed: %v", err)
+	}
+	defer checker.Stop()
+
+	var summary HealthCheckSummary
+	select {
+	case summary = <-summaries:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first run didn't start immediately")
+	}
+
+	if summary.Total != 2 || summary.Checked != 2 || summary.Passed != 1 || summary.Failed != 1 {
+		t.Errorf("summary = %+v, want 2 checked with 1 passed", summary)
+	}
+	if summary.InRotation != 1 {
+		t.Errorf("InRotation = %d, want 1", summary.InRotation)
+	}
+	if summary.Err != nil {
+		t.Errorf("summary error = %v", summary.Err)
+	}
+	if last, ok := checker.LastRun(); !ok || last.Started != summary.Started {
+		t.Errorf("LastRun = %+v, %v, want the first run", last, ok)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if result := results[upSrv.URL]; !result.Healthy || result.Err != nil || result.Latency <= 0 || result.State != HealthHealthy {
+		t.Errorf("healthy result = %+v", result)
+	}
+	result := results[downSrv.URL]
+	if result.Healthy || result.Err == nil || result.ProxyID != downID || result.State != HealthQuarantined {
+		t.Errorf("failed result = %+v", result)
+	}
+}
+
+func TestHealthCheckerRunNow(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var hits int32
+	var down atomic.Bool
+	srv := newFlakyProxyServer(t, "flaky", &hits, &down)
+	if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+		t.Fatalf("AddProxy failed: %v", err)
+	}
+
+	var runs atomic.Int32
+	healthOpts := DefaultHealthCheckOptions()
+	healthOpts.Interval = time.Hour
+	healthOpts.HealthURL = "http://probe.example.com/"
+	healthOpts.OnComplete = func(HealthCheckSummary) { runs.Add(1) }
+
+	checker, err := r.StartHealthCheck(ctx, healthOpts)
+	if err != nil {
+		t.Fatalf("StartHealthCheck failed: %v", err)
+	}
+
+	if _, ok := checker.LastRun(); ok {
+		t.Error("LastRun reported a run before any happened")
+	}
+
+	summary, err := checker.RunNow(ctx)
+	if err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	if summary.Checked != 1 || summary.Passed != 1 || summary.InRotation != 1 {
+		t.Errorf("summary = %+v, want 1 passed", summary)
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("proxy hits = %d, want 1", atomic.LoadInt32(&hits))
+	}
+
+	down.Store(true)
+	summary, err = checker.RunNow(ctx)
+	if err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	if summary.Failed != 1 || summary.InRotation != 1 {
+		t.Errorf("summary = %+v, want 1 failed and the suspect proxy in rotation", summary)
+	}
+	if last, ok := checker.LastRun(); !ok || last.Failed != 1 {
+		t.Errorf("LastRun = %+v, %v, want the second run", last, ok)
+	}
+	if runs.Load() != 2 {
+		t.Errorf("OnComplete called %d times, want 2", runs.Load())
+	}
+
+	checker.Stop()
+	checker.Stop()
+
+	select {
+	case <-checker.Done():
+	default:
+		t.Error("Done not closed after Stop")
+	}
+	if _, err := checker.RunNow(ctx); !errors.Is(err, ErrHealthCheckStopped) {
+		t.Errorf("RunNow error = %v after Stop, want ErrHealthCheckStopped", err)
+	}
+}
+
+func TestHealthCheckerContextDone(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	checker, err := r.StartHealthCheck(ctx, HealthCheckOptions{})
+	if err != nil {
+		t.Fatalf("StartHealthCheck failed: %v", err)
+	}
+	cancel()
+
+	select {
+	case <-checker.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("checker didn't stop when its context was canceled")
+	}
+	checker.Stop()
+}
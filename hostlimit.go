@@ -0,0 +1,226 @@
+package lashes
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Defaults for the per-(proxy, host) limiter store
+const (
+	DefaultHostLimiterIdleTimeout = 10 * time.Minute
+	DefaultMaxHostLimiters        = 10000
+)
+
+// HostRateRule sets the budget for requests from one proxy to matching target hosts
+type HostRateRule struct {
+	// Host is an exact host name such as "api.example.com", or a wildcard
+	// such as "*.example.com" that matches every subdomain of example.com
+	Host string
+
+	// RequestsPerSecond is the sustained rate allowed per proxy and host.
+	// Use fractions for per-minute budgets, e.g. 0.5 for 30 per minute.
+	RequestsPerSecond float64
+
+	// Burst is the number of requests a proxy may send to the host at once.
+	// Values below one are treated as one.
+	Burst int
+}
+
+// normalizeHost lowercases a host name and drops any trailing dot
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// hostMatches reports whether host matches an exact or "*.suffix" pattern
+func hostMatches(pattern, host string) bool {
+	pattern, host = normalizeHost(pattern), normalizeHost(host)
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return pattern == host
+}
+
+// hostRules resolves the rule that applies to a target host
+type hostRules struct {
+	exact     map[string]HostRateRule
+	wildcards []HostRateRule // longest pattern first
+	fallback  *HostRateRule
+}
+
+// newHostRules indexes the rules; fallback, if set, applies to unmatched hosts
+func newHostRules(rules []HostRateRule, fallback *HostRateRule) hostRules {
+	hr := hostRules{exact: make(map[string]HostRateRule), fallback: fallback}
+	for _, rule := range rules {
+		rule.Host = normalizeHost(rule.Host)
+		if strings.HasPrefix(rule.Host, "*.") {
+			hr.wildcards = append(hr.wildcards, rule)
+		} else {
+			hr.exact[rule.Host] = rule
+		}
+	}
+
+	// The most specific wildcard wins
+	sort.SliceStable(hr.wildcards, func(i, j int) bool {
+		return len(hr.wildcards[i].Host) > len(hr.wildcards[j].Host)
+	})
+	return hr
+}
+
+// match returns the rule for a host, if any applies
+func (hr hostRules) match(host string) (HostRateRule, bool) {
+	host = normalizeHost(host)
+	if rule, ok := hr.exact[host]; ok {
+		return rule, true
+	}
+	for _, rule := range hr.wildcards {
+		if hostMatches(rule.Host, host) {
+			return rule, true
+		}
+	}
+	if hr.fallback != nil {
+		return *hr.fallback, true
+	}
+	return HostRateRule{}, false
+}
+
+// hostLimiterEntry is a keyed limiter and when it was last used
+type hostLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// hostLimiters stores per-(proxy, host) limiters, evicting idle ones so
+// memory stays bounded however many hosts are visited
+type hostLimiters struct {
+	rules       hostRules
+	idleTimeout time.Duration
+	maxKeys     int
+
+	// onEvict, if set, is called with the key of every limiter dropped from
+	// the store. It runs with the store's lock held.
+	onEvict func(key string)
+
+	mu        sync.Mutex
+	entries   map[string]*hostLimiterEntry
+	lastSweep time.Time
+}
+
+// newHostLimiters creates an empty store; zero limits use the defaults
+func newHostLimiters(rules hostRules, idleTimeout time.Duration, maxKeys int) *hostLimiters {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultHostLimiterIdleTimeout
+	}
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxHostLimiters
+	}
+	return &hostLimiters{
+		rules:       rules,
+		idleTimeout: idleTimeout,
+		maxKeys:     maxKeys,
+		entries:     make(map[string]*hostLimiterEntry),
+		lastSweep:   time.Now(),
+	}
+}
+
+// hostLimiterKey joins a proxy ID and host into a store key
+func hostLimiterKey(proxyID, host string) string {
+	return proxyID + "|" + normalizeHost(host)
+}
+
+// lookup returns the existing limiter for a proxy and host without creating one
+func (hl *hostLimiters) lookup(proxyID, host string) *rate.Limiter {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	if entry, ok := hl.entries[hostLimiterKey(proxyID, host)]; ok {
+		return entry.limiter
+	}
+	return nil
+}
+
+// get returns the limiter for a proxy and host, creating it when a rule
+// applies. It returns nil for hosts no rule covers.
+func (hl *hostLimiters) get(proxyID, host string) *rate.Limiter {
+	if host == "" {
+		return nil
+	}
+
+	now := time.Now()
+	key := hostLimiterKey(proxyID, host)
+
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	if entry, ok := hl.entries[key]; ok {
+		entry.lastUsed = now
+		return entry.limiter
+	}
+
+	rule, ok := hl.rules.match(host)
+	if !ok {
+		return nil
+	}
+
+	if now.Sub(hl.lastSweep) > hl.idleTimeout || len(hl.entries) >= hl.maxKeys {
+		hl.evict(now)
+	}
+
+	limiter := rate.NewLimiter(rate.Limit(rule.RequestsPerSecond), max(rule.Burst, 1))
+	hl.entries[key] = &hostLimiterEntry{limiter: limiter, lastUsed: now}
+	return limiter
+}
+
+// evict drops idle limiters, then the least recently used ones if the store
+// is still full. The caller must hold the lock.
+func (hl *hostLimiters) evict(now time.Time) {
+	hl.lastSweep = now
+	for key, entry := range hl.entries {
+		if now.Sub(entry.lastUsed) > hl.idleTimeout {
+			hl.remove(key)
+		}
+	}
+
+	for len(hl.entries) >= hl.maxKeys {
+		var oldestKey string
+		var oldest time.Time
+		for key, entry := range hl.entries {
+			if oldestKey == "" || entry.lastUsed.Before(oldest) {
+				oldestKey, oldest = key, entry.lastUsed
+			}
+		}
+		hl.remove(oldestKey)
+	}
+}
+
+// remove drops a limiter from the store. The caller must hold the lock.
+func (hl *hostLimiters) remove(key string) {
+	delete(hl.entries, key)
+	if hl.onEvict != nil {
+		hl.onEvict(key)
+	}
+}
+
+// forget drops every host limiter belonging to a proxy
+func (hl *hostLimiters) forget(proxyID string) {
+	prefix := proxyID + "|"
+
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	for key := range hl.entries {
+		if strings.HasPrefix(key, prefix) {
+			hl.remove(key)
+		}
+	}
+}
+
+// len returns the number of stored limiters
+func (hl *hostLimiters) len() int {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+	return len(hl.entries)
+}
@@ -18,6 +18,42 @@ const (
 	StateHalfOpen
 )
 
+// String returns the state's name
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Mode selects how a closed circuit breaker decides to open
+type Mode int
+
+const (
+	// ModeConsecutive opens after MaxFailures failures in a row
+	ModeConsecutive Mode = iota
+	// ModeCountWindow opens when the failure rate over the last WindowSize calls
+	// reaches FailureRateThreshold
+	ModeCountWindow
+	// ModeTimeWindow opens when the failure rate over the last WindowDuration
+	// reaches FailureRateThreshold
+	ModeTimeWindow
+)
+
+// Defaults applied to unset window settings
+const (
+	DefaultWindowSize           = 20
+	DefaultWindowDuration       = time.Minute
+	DefaultFailureRateThreshold = 0.5
+	DefaultMinimumRequests      = 10
+)
+
 // Config defines circuit breaker behavior
 type Config struct {
 	// MaxFailures is the threshold of failures before opening the circuit
@@ -26,6 +62,47 @@ type Config struct {
 	ResetTimeout time.Duration
 	// MaxHalfOpenRequests is the number of requests allowed in the HalfOpen state
 	MaxHalfOpenRequests int
+
+	// Mode selects consecutive-failure counting or a sliding failure-rate window
+	Mode Mode
+	// WindowSize is the number of recent calls considered by ModeCountWindow
+	WindowSize int
+	// WindowDuration is the period considered by ModeTimeWindow
+	WindowDuration time.Duration
+	// FailureRateThreshold is the failure ratio (0-1] that opens a windowed breaker
+	FailureRateThreshold float64
+	// MinimumRequests is the number of calls a window needs before its rate
+	// is trusted. ModeCountWindow caps it at WindowSize.
+	MinimumRequests int
+	// SlowCallThreshold counts calls slower than this as failures; zero disables it
+	SlowCallThreshold time.Duration
+
+	// OnStateChange is called after every state transition, outside the breaker's lock
+	OnStateChange func(from, to State)
+}
+
+// withDefaults fills in unset window settings
+func (c Config) withDefaults() Config {
+	if c.Mode == ModeConsecutive {
+		return c
+	}
+	if c.WindowSize <= 0 {
+		c.WindowSize = DefaultWindowSize
+	}
+	if c.WindowDuration/timeWindowBuckets <= 0 {
+		c.WindowDuration = DefaultWindowDuration
+	}
+	if c.FailureRateThreshold <= 0 || c.FailureRateThreshold > 1 {
+		c.FailureRateThreshold = DefaultFailureRateThreshold
+	}
+	if c.MinimumRequests <= 0 {
+		c.MinimumRequests = DefaultMinimumRequests
+	}
+	if c.Mode == ModeCountWindow {
+		// A full window must be enough for the rate to count
+		c.MinimumRequests = min(c.MinimumRequests, c.WindowSize)
+	}
+	return c
 }
 
 // DefaultConfig returns a sensible default configuration
@@ -46,23 +123,68 @@ type CircuitBreaker struct {
 	lastStateChange time.Time
 	halfOpenCount   int
 
+	// window holds recent outcomes in the failure-rate modes
+	window window
+
 	mu sync.RWMutex
 }
 
 // NewCircuitBreaker creates a new circuit breaker with the given config
 func NewCircuitBreaker(cfg Config) *CircuitBreaker {
+	cfg = cfg.withDefaults()
 	return &CircuitBreaker{
 		config:          cfg,
 		state:           StateClosed,
 		lastStateChange: time.Now(),
+		window:          newWindow(cfg),
 	}
 }
 
+// Snapshot is a point-in-time view of a circuit breaker
+type Snapshot struct {
+	// State is the breaker's current state
+	State State
+	// Failures is the consecutive failure count, or the failures in the
+	// current window for the failure-rate modes
+	Failures int
+	// Since is when the breaker entered its current state
+	Since time.Time
+	// RetryAt is when an open breaker admits its next trial request; zero otherwise
+	RetryAt time.Time
+}
+
 // Allow returns whether a request should be permitted
 func (cb *CircuitBreaker) Allow() bool {
+	allowed, _ := cb.Acquire()
+	return allowed
+}
+
+// Acquire is like Allow, and also reports whether the request took a
+// half-open trial slot. Release gives the slot back if the request is never sent.
+func (cb *CircuitBreaker) Acquire() (allowed, trial bool) {
+	cb.mu.Lock()
+	from := cb.state
+	allowed = cb.allow()
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+	return allowed, allowed && to == StateHalfOpen
+}
+
+// Release gives back a half-open trial slot taken by Acquire for a request
+// that was never sent
+func (cb *CircuitBreaker) Release() {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
+	if cb.state == StateHalfOpen && cb.halfOpenCount > 0 {
+		cb.halfOpenCount--
+	}
+}
+
+// allow implements Allow; the caller must hold the lock
+func (cb *CircuitBreaker) allow() bool {
 	switch cb.state {
 	case StateClosed:
 		return true
@@ -77,6 +199,11 @@ func (cb *CircuitBreaker) Allow() bool {
 		}
 		return false
 	case StateHalfOpen:
+		// Trial requests that never reported back must not hold the breaker forever
+		if time.Since(cb.lastStateChange) > cb.config.ResetTimeout {
+			cb.lastStateChange = time.Now()
+			cb.halfOpenCount = 0
+		}
 		// Only allow limited requests in half-open state
 		if cb.halfOpenCount < cb.config.MaxHalfOpenRequests {
 			cb.halfOpenCount++
@@ -88,13 +215,114 @@ func (cb *CircuitBreaker) Allow() bool {
 	}
 }
 
+// Ready reports whether Allow would currently permit a request,
+// without consuming a half-open slot or changing state
+func (cb *CircuitBreaker) Ready() bool {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	switch cb.state {
+	case StateClosed:
+		return true
+	case StateOpen:
+		return time.Since(cb.lastStateChange) > cb.config.ResetTimeout
+	case StateHalfOpen:
+		return cb.halfOpenCount < cb.config.MaxHalfOpenRequests ||
+			time.Since(cb.lastStateChange) > cb.config.ResetTimeout
+	default:
+		return false
+	}
+}
+
+// RetryAfter returns how long an open breaker keeps rejecting requests.
+// It returns zero in any other state.
+func (cb *CircuitBreaker) RetryAfter() time.Duration {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	if cb.state != StateOpen {
+		return 0
+	}
+	if remaining := cb.config.ResetTimeout - time.Since(cb.lastStateChange); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
 // RecordSuccess records a successful request
 func (cb *CircuitBreaker) RecordSuccess() {
+	cb.Record(true, 0)
+}
+
+// RecordFailure records a failed request
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.Record(false, 0)
+}
+
+// Record records the outcome of a request and how long it took.
+// Successful calls slower than SlowCallThreshold count as failures.
+func (cb *CircuitBreaker) Record(success bool, latency time.Duration) {
+	if cb.config.SlowCallThreshold > 0 && latency > cb.config.SlowCallThreshold {
+		success = false
+	}
+
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	from := cb.state
+	if success {
+		cb.onSuccess()
+	} else {
+		cb.onFailure()
+	}
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+}
+
+// ForceOpen opens the circuit immediately. It half-opens again after
+// ResetTimeout like any other open breaker.
+func (cb *CircuitBreaker) ForceOpen() {
+	cb.mu.Lock()
+	from := cb.state
+	cb.state = StateOpen
+	cb.lastStateChange = time.Now()
+	cb.halfOpenCount = 0
+	cb.mu.Unlock()
+
+	cb.notify(from, StateOpen)
+}
+
+// Reset closes the circuit and forgets all recorded failures
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	from := cb.state
+	cb.state = StateClosed
+	cb.failures = 0
+	cb.lastStateChange = time.Now()
+	cb.halfOpenCount = 0
+	if cb.window != nil {
+		cb.window.reset()
+	}
+	cb.mu.Unlock()
+
+	cb.notify(from, StateClosed)
+}
 
+// notify reports a state transition to the OnStateChange hook
+func (cb *CircuitBreaker) notify(from, to State) {
+	if from != to && cb.config.OnStateChange != nil {
+		cb.config.OnStateChange(from, to)
+	}
+}
+
+// onSuccess applies a successful call; the caller must hold the lock
+func (cb *CircuitBreaker) onSuccess() {
 	switch cb.state {
 	case StateClosed:
+		if cb.window != nil {
+			cb.recordWindow(false)
+			return
+		}
 		// Reset failures counter
 		cb.failures = 0
 	case StateHalfOpen:
@@ -103,18 +331,22 @@ func (cb *CircuitBreaker) RecordSuccess() {
 		cb.failures = 0
 		cb.lastStateChange = time.Now()
 		cb.halfOpenCount = 0
+		if cb.window != nil {
+			cb.window.reset()
+		}
 	case StateOpen:
 		// No action needed for StateOpen
 	}
 }
 
-// RecordFailure records a failed request
-func (cb *CircuitBreaker) RecordFailure() {
-	cb.mu.Lock()
-	defer cb.mu.Unlock()
-
+// onFailure applies a failed call; the caller must hold the lock
+func (cb *CircuitBreaker) onFailure() {
 	switch cb.state {
 	case StateClosed:
+		if cb.window != nil {
+			cb.recordWindow(true)
+			return
+		}
 		// Increment failure counter
 		cb.failures++
 		if cb.failures >= cb.config.MaxFailures {
@@ -124,7 +356,9 @@ func (cb *CircuitBreaker) RecordFailure() {
 	case StateHalfOpen:
 		// If failed in half-open, go back to open immediately
 		cb.state = StateOpen
-		cb.failures = cb.config.MaxFailures // Reset to max failures
+		if cb.window == nil {
+			cb.failures = cb.config.MaxFailures // Reset to max failures
+		}
 		cb.lastStateChange = time.Now()
 		cb.halfOpenCount = 0
 	case StateOpen:
@@ -132,9 +366,44 @@ func (cb *CircuitBreaker) RecordFailure() {
 	}
 }
 
+// recordWindow adds an outcome to the sliding window and opens the circuit
+// once enough calls have been seen and the failure rate reaches the threshold
+func (cb *CircuitBreaker) recordWindow(failed bool) {
+	now := time.Now()
+	cb.window.add(failed, now)
+
+	total, failures := cb.window.counts(now)
+	cb.failures = failures
+	if total < cb.config.MinimumRequests {
+		return
+	}
+
+	if float64(failures)/float64(total) >= cb.config.FailureRateThreshold {
+		cb.state = StateOpen
+		cb.lastStateChange = now
+		cb.window.reset()
+	}
+}
+
 // GetState returns the current state of the circuit breaker
 func (cb *CircuitBreaker) GetState() State {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
 	return cb.state
 }
+
+// Snapshot returns the breaker's current state and counters
+func (cb *CircuitBreaker) Snapshot() Snapshot {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	snapshot := Snapshot{
+		State:    cb.state,
+		Failures: cb.failures,
+		Since:    cb.lastStateChange,
+	}
+	if cb.state == StateOpen {
+		snapshot.RetryAt = cb.lastStateChange.Add(cb.config.ResetTimeout)
+	}
+	return snapshot
+}
@@ -186,4 +186,247 @@ func TestCircuitBreaker(t *testing.T) {
 			t.Error("Allow() = true, want false after failure in half-open state")
 		}
 	})
+	t.Run("Ready does not consume half-open slots", func(t *testing.T) {
+		cb := breaker.NewCircuitBreaker(breaker.Config{
+			MaxFailures:         1,
+			ResetTimeout:        time.Millisecond * 50,
+			MaxHalfOpenRequests: 1,
+		})
+
+		cb.RecordFailure()
+		if cb.Ready() {
+			t.Error("Ready() = true, want false while open")
+		}
+		if retryAfter := cb.RetryAfter(); retryAfter <= 0 || retryAfter > time.Millisecond*50 {
+			t.Errorf("RetryAfter() = %v, want within the reset timeout", retryAfter)
+		}
+
+		time.Sleep(time.Millisecond * 60)
+
+		for i := 0; i < 3; i++ {
+			if !cb.Ready() {
+				t.Fatalf("Ready() call %d = false, want true after timeout", i)
+			}
+		}
+		if state := cb.GetState(); state != breaker.StateOpen {
+			t.Errorf("State = %v, want Ready() to leave it %v", state, breaker.StateOpen)
+		}
+
+		if !cb.Allow() {
+			t.Fatal("Allow() = false, want true for the trial request")
+		}
+		if cb.Ready() {
+			t.Error("Ready() = true, want false once the half-open slot is taken")
+		}
+	})
+}
+
+func TestCircuitBreakerWindowModes(t *testing.T) {
+	t.Run("Count window trips on failure rate", func(t *testing.T) {
+		cb := breaker.NewCircuitBreaker(breaker.Config{
+			ResetTimeout:         time.Second * 30,
+			MaxHalfOpenRequests:  1,
+			Mode:                 breaker.ModeCountWindow,
+			WindowSize:           10,
+			FailureRateThreshold: 0.5,
+			MinimumRequests:      10,
+		})
+
+		// 60% failures interleaved with successes never trip a consecutive breaker
+		for i := 0; i < 9; i++ {
+			if i%5 < 3 {
+				cb.RecordFailure()
+			} else {
+				cb.RecordSuccess()
+			}
+			if state := cb.GetState(); state != breaker.StateClosed {
+				t.Fatalf("State after %d calls = %v, want %v below the minimum volume", i+1, state, breaker.StateClosed)
+			}
+		}
+
+		cb.RecordSuccess()
+		if state := cb.GetState(); state != breaker.StateOpen {
+			t.Errorf("State = %v, want %v at 50%% failures over 10 calls", state, breaker.StateOpen)
+		}
+	})
+
+	t.Run("Count window forgets old outcomes", func(t *testing.T) {
+		cb := breaker.NewCircuitBreaker(breaker.Config{
+			ResetTimeout:         time.Second * 30,
+			Mode:                 breaker.ModeCountWindow,
+			WindowSize:           4,
+			FailureRateThreshold: 0.75,
+			MinimumRequests:      4,
+		})
+
+		cb.RecordFailure()
+		cb.RecordFailure()
+		for i := 0; i < 6; i++ {
+			cb.RecordSuccess()
+		}
+		cb.RecordFailure()
+		cb.RecordFailure()
+
+		// Only the last four calls count: two failures out of four
+		if state := cb.GetState(); state != breaker.StateClosed {
+			t.Errorf("State = %v, want %v", state, breaker.StateClosed)
+		}
+	})
+
+	t.Run("Count window caps the minimum volume", func(t *testing.T) {
+		cb := breaker.NewCircuitBreaker(breaker.Config{
+			ResetTimeout:         time.Second * 30,
+			Mode:                 breaker.ModeCountWindow,
+			WindowSize:           5,
+			FailureRateThreshold: 0.5,
+			MinimumRequests:      50,
+		})
+
+		for i := 0; i < 5; i++ {
+			cb.RecordFailure()
+		}
+		if state := cb.GetState(); state != breaker.StateOpen {
+			t.Errorf("State = %v, want %v once the window is full of failures", state, breaker.StateOpen)
+		}
+	})
+
+	t.Run("Time window trips on failure rate", func(t *testing.T) {
+		cb := breaker.NewCircuitBreaker(breaker.Config{
+			ResetTimeout:         time.Second * 30,
+			Mode:                 breaker.ModeTimeWindow,
+			WindowDuration:       time.Minute,
+			FailureRateThreshold: 0.5,
+			MinimumRequests:      4,
+		})
+
+		cb.RecordSuccess()
+		cb.RecordFailure()
+		cb.RecordSuccess()
+		if state := cb.GetState(); state != breaker.StateClosed {
+			t.Fatalf("State = %v, want %v below the minimum volume", state, breaker.StateClosed)
+		}
+
+		cb.RecordFailure()
+		if state := cb.GetState(); state != breaker.StateOpen {
+			t.Errorf("State = %v, want %v", state, breaker.StateOpen)
+		}
+	})
+
+	t.Run("Time window expires old outcomes", func(t *testing.T) {
+		cb := breaker.NewCircuitBreaker(breaker.Config{
+			ResetTimeout:         time.Second * 30,
+			Mode:                 breaker.ModeTimeWindow,
+			WindowDuration:       time.Millisecond * 100,
+			FailureRateThreshold: 0.5,
+			MinimumRequests:      2,
+		})
+
+		for i := 0; i < 3; i++ {
+			cb.RecordSuccess()
+		}
+		time.Sleep(time.Millisecond * 150)
+
+		// Counting the expired successes would keep the rate at 40%
+		cb.RecordFailure()
+		cb.RecordFailure()
+		if state := cb.GetState(); state != breaker.StateOpen {
+			t.Errorf("State = %v, want %v once earlier successes left the window", state, breaker.StateOpen)
+		}
+	})
+
+	t.Run("Slow calls count as failures", func(t *testing.T) {
+		cb := breaker.NewCircuitBreaker(breaker.Config{
+			MaxFailures:       2,
+			ResetTimeout:      time.Second * 30,
+			SlowCallThreshold: time.Second,
+		})
+
+		cb.Record(true, time.Millisecond*100)
+		cb.Record(true, time.Second*2)
+		cb.Record(true, time.Second*3)
+
+		if state := cb.GetState(); state != breaker.StateOpen {
+			t.Errorf("State = %v, want %v after two slow calls", state, breaker.StateOpen)
+		}
+	})
+}
+
+func TestCircuitBreakerIntrospection(t *testing.T) {
+	t.Run("State changes are reported", func(t *testing.T) {
+		var transitions []string
+		cb := breaker.NewCircuitBreaker(breaker.Config{
+			MaxFailures:         1,
+			ResetTimeout:        time.Millisecond * 20,
+			MaxHalfOpenRequests: 1,
+			OnStateChange: func(from, to breaker.State) {
+				transitions = append(transitions, from.String()+"->"+to.String())
+			},
+		})
+
+		cb.RecordFailure()
+		time.Sleep(time.Millisecond * 30)
+		cb.Allow()
+		cb.RecordSuccess()
+		cb.RecordSuccess()
+
+		want := []string{"closed->open", "open->half-open", "half-open->closed"}
+		if len(transitions) != len(want) {
+			t.Fatalf("transitions = %v, want %v", transitions, want)
+		}
+		for i := range want {
+			if transitions[i] != want[i] {
+				t.Errorf("transition %d = %q, want %q", i, transitions[i], want[i])
+			}
+		}
+	})
+
+	t.Run("Release returns an unused trial slot", func(t *testing.T) {
+		cb := breaker.NewCircuitBreaker(breaker.Config{
+			MaxFailures:         1,
+			ResetTimeout:        time.Millisecond * 20,
+			MaxHalfOpenRequests: 1,
+		})
+
+		if allowed, trial := cb.Acquire(); !allowed || trial {
+			t.Fatalf("Acquire() on a closed breaker = %v, %v, want true, false", allowed, trial)
+		}
+
+		cb.RecordFailure()
+		time.Sleep(time.Millisecond * 30)
+		if allowed, trial := cb.Acquire(); !allowed || !trial {
+			t.Fatalf("Acquire() after the reset timeout = %v, %v, want a trial", allowed, trial)
+		}
+		if cb.Allow() {
+			t.Fatal("Allow() = true with the only trial slot taken")
+		}
+
+		cb.Release()
+		if !cb.Allow() {
+			t.Error("Allow() = false after the trial slot was released")
+		}
+	})
+
+	t.Run("ForceOpen and Reset", func(t *testing.T) {
+		cb := breaker.NewCircuitBreaker(breaker.Config{
+			MaxFailures:         3,
+			ResetTimeout:        time.Minute,
+			MaxHalfOpenRequests: 1,
+		})
+		cb.RecordFailure()
+
+		cb.ForceOpen()
+		snapshot := cb.Snapshot()
+		if snapshot.State != breaker.StateOpen || cb.Allow() {
+			t.Fatalf("State after ForceOpen = %v, want %v", snapshot.State, breaker.StateOpen)
+		}
+		if want := snapshot.Since.Add(time.Minute); !snapshot.RetryAt.Equal(want) {
+			t.Errorf("RetryAt = %v, want %v", snapshot.RetryAt, want)
+		}
+
+		cb.Reset()
+		snapshot = cb.Snapshot()
+		if snapshot.State != breaker.StateClosed || snapshot.Failures != 0 || !snapshot.RetryAt.IsZero() {
+			t.Errorf("Snapshot after Reset = %+v, want closed with no failures", snapshot)
+		}
+	})
 }
@@ -0,0 +1,105 @@
+package breaker
+
+import "time"
+
+// timeWindowBuckets is the number of buckets a time-based window is split into
+const timeWindowBuckets = 10
+
+// window tracks recent call outcomes for the failure-rate modes
+type window interface {
+	// add records the outcome of a call made at now
+	add(failed bool, now time.Time)
+	// counts returns the number of calls and failures still inside the window
+	counts(now time.Time) (total, failures int)
+	// reset forgets every recorded outcome
+	reset()
+}
+
+// newWindow returns the window implementation for the config's mode,
+// or nil for the consecutive-failure mode
+func newWindow(cfg Config) window {
+	switch cfg.Mode {
+	case ModeCountWindow:
+		return &countWindow{outcomes: make([]bool, cfg.WindowSize)}
+	case ModeTimeWindow:
+		return &timeWindow{width: cfg.WindowDuration / timeWindowBuckets}
+	default:
+		return nil
+	}
+}
+
+// countWindow keeps the outcomes of the last len(outcomes) calls in a ring buffer
+type countWindow struct {
+	outcomes []bool
+	next     int
+	total    int
+	failures int
+}
+
+func (w *countWindow) add(failed bool, _ time.Time) {
+	if w.total == len(w.outcomes) {
+		// Overwrite the oldest outcome
+		if w.outcomes[w.next] {
+			w.failures--
+		}
+	} else {
+		w.total++
+	}
+
+	w.outcomes[w.next] = failed
+	if failed {
+		w.failures++
+	}
+	w.next = (w.next + 1) % len(w.outcomes)
+}
+
+func (w *countWindow) counts(time.Time) (int, int) {
+	return w.total, w.failures
+}
+
+func (w *countWindow) reset() {
+	w.next, w.total, w.failures = 0, 0, 0
+}
+
+// timeBucket aggregates the calls made during one slice of a time window
+type timeBucket struct {
+	start    time.Time
+	total    int
+	failures int
+}
+
+// timeWindow aggregates outcomes into fixed-width buckets so memory use
+// doesn't grow with request volume
+type timeWindow struct {
+	width   time.Duration
+	buckets [timeWindowBuckets]timeBucket
+}
+
+func (w *timeWindow) add(failed bool, now time.Time) {
+	start := now.Truncate(w.width)
+	b := &w.buckets[(start.UnixNano()/int64(w.width))%timeWindowBuckets]
+	if !b.start.Equal(start) {
+		// The bucket still holds a previous lap of the window
+		*b = timeBucket{start: start}
+	}
+
+	b.total++
+	if failed {
+		b.failures++
+	}
+}
+
+func (w *timeWindow) counts(now time.Time) (total, failures int) {
+	cutoff := now.Truncate(w.width).Add(-w.width * (timeWindowBuckets - 1))
+	for _, b := range w.buckets {
+		if !b.start.Before(cutoff) {
+			total += b.total
+			failures += b.failures
+		}
+	}
+	return total, failures
+}
+
+func (w *timeWindow) reset() {
+	w.buckets = [timeWindowBuckets]timeBucket{}
+}
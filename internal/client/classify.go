@@ -0,0 +1,150 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Classification is the verdict on a response
+type Classification int
+
+// Response classifications
+const (
+	// Success is a response that got what the request asked for
+	Success Classification = iota
+
+	// SoftFail is a failed response that says nothing bad about the proxy,
+	// such as a 404
+	SoftFail
+
+	// Ban is a response showing that the site has blocked the proxy
+	Ban
+
+	// Captcha is a response that asks for a captcha or challenge to be solved
+	// instead of serving the page
+	Captcha
+
+	// TransientError is a failure worth retrying that counts against the
+	// proxy, such as a connection error, a server error or throttling
+	TransientError
+)
+
+// String returns the classification's name
+func (c Classification) String() string {
+	switch c {
+	case Success:
+		return "success"
+	case SoftFail:
+		return "soft-fail"
+	case Ban:
+		return "ban"
+	case Captcha:
+		return "captcha"
+	case TransientError:
+		return "transient-error"
+	default:
+		return "unknown"
+	}
+}
+
+// ResponseClassifier decides what a response means for the proxy that fetched it
+type ResponseClassifier interface {
+	// Classify returns the verdict on a response. body holds the start of the
+	// response body if the classifier implements BodyPeeker, and is nil otherwise.
+	Classify(resp *http.Response, body []byte) Classification
+}
+
+// BodyPeeker is implemented by classifiers that need to see the start of the body
+type BodyPeeker interface {
+	// PeekBytes returns how much of the body to read before classifying
+	PeekBytes() int
+}
+
+// StatusClassifier classifies responses by status code alone. Statuses below
+// 400 are successes. Server errors, proxy authentication failures and
+// throttling point at the proxy and are transient errors; other client
+// errors come from the target and are soft failures.
+type StatusClassifier struct{}
+
+// Classify implements ResponseClassifier
+func (StatusClassifier) Classify(resp *http.Response, body []byte) Classification {
+	code := resp.StatusCode
+	switch {
+	case code < http.StatusBadRequest:
+		return Success
+	case code >= http.StatusInternalServerError,
+		code == http.StatusProxyAuthRequired,
+		code == http.StatusTooManyRequests:
+		return TransientError
+	default:
+		return SoftFail
+	}
+}
+
+// Classify runs a classifier on a response, peeking at the body first when
+// the classifier asks for it. A nil classifier uses StatusClassifier.
+func Classify(classifier ResponseClassifier, resp *http.Response) Classification {
+	if classifier == nil {
+		classifier = StatusClassifier{}
+	}
+
+	var body []byte
+	if peeker, ok := classifier.(BodyPeeker); ok {
+		body = PeekBody(resp, peeker.PeekBytes())
+	}
+	return classifier.Classify(resp, body)
+}
+
+// PeekBody reads up to n bytes from the start of a response body and puts
+// them back, so the caller still reads the whole body. It returns nil if the
+// body is empty, can't be read or isn't a text, HTML, XML or JSON document;
+// streams and binary downloads are never read ahead of the caller.
+func PeekBody(resp *http.Response, n int) []byte {
+	if n <= 0 || resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	if resp.Request != nil && resp.Request.Method == http.MethodHead {
+		return nil
+	}
+	if !peekable(resp.Header.Get("Content-Type")) {
+		return nil
+	}
+
+	prefix, err := io.ReadAll(io.LimitReader(resp.Body, int64(n)))
+	resp.Body = peekedBody{Reader: io.MultiReader(bytes.NewReader(prefix), resp.Body), Closer: resp.Body}
+	if err != nil {
+		// The caller sees the same error when it reads the body
+		return nil
+	}
+	return prefix
+}
+
+// peekable reports whether a content type is a document worth searching
+// for block pages. Event streams are left alone, since reading ahead would
+// block until enough events arrive.
+func peekable(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/json", mediaType == "application/xml", mediaType == "application/xhtml+xml":
+		return true
+	default:
+		return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+	}
+}
+
+// peekedBody replays a body's prefix before the rest of it
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -2,12 +2,14 @@ package client
 
 import (
 	"crypto/tls"
+	"fmt"
 	"net/http"
 	"net/url"
 	"sync"
 	"time"
 
 	"github.com/greysquirr3l/lashes/internal/agent"
+	"github.com/greysquirr3l/lashes/internal/client/dialer"
 	"github.com/greysquirr3l/lashes/internal/domain"
 )
 
@@ -15,6 +17,7 @@ type Client struct {
 	http.Client // Embed http.Client
 	maxRetries  int
 	metrics     *domain.Metrics
+	classifier  ResponseClassifier // nil classifies by status code
 }
 
 type Options struct {
@@ -43,15 +46,14 @@ func NewClient(proxy *domain.Proxy, options Options) (*http.Client, error) {
 
 // createDefaultClient is the default implementation for creating HTTP clients
 func createDefaultClient(proxy *domain.Proxy, options Options) (*http.Client, error) {
-	// Parse the proxy URL
-	proxyURL, err := url.Parse(proxy.URL)
+	// Parse the proxy URL; Username/Password fields take precedence over its userinfo
+	proxyURL, err := proxy.AuthURL()
 	if err != nil {
 		return nil, err
 	}
 
-	// Create the transport with proxy
+	// Create the transport; the proxy itself is configured below
 	transport := &http.Transport{
-		Proxy: http.ProxyURL(proxyURL),
 		// Set modern defaults for TLS
 		TLSClientConfig: &tls.Config{
 			MinVersion: tls.VersionTLS12,
@@ -91,6 +93,11 @@ func createDefaultClient(proxy *domain.Proxy, options Options) (*http.Client, er
 		transport.TLSClientConfig = tls
 	}
 
+	// Route connections through the proxy
+	if err := configureProxy(transport, proxy, proxyURL); err != nil {
+		return nil, err
+	}
+
 	// Configure headers for future requests
 	headers := configureHeaders(options.Headers)
 
@@ -110,6 +117,61 @@ func createDefaultClient(proxy *domain.Proxy, options Options) (*http.Client, er
 	return httpClient, nil
 }
 
+// configureProxy routes the transport through the proxy based on its type.
+// HTTP proxies use the standard CONNECT-capable proxy support, which sends
+// the URL credentials as Proxy-Authorization, while SOCKS proxies are dialed
+// natively so SOCKS4/4a and SOCKS5 authentication work.
+func configureProxy(transport *http.Transport, proxy *domain.Proxy, proxyURL *url.URL) error {
+	proxyType := proxy.Type
+	if proxyType == "" {
+		proxyType = proxyTypeFromScheme(proxyURL.Scheme)
+	}
+
+	switch proxyType {
+	case domain.SOCKS4Proxy, domain.SOCKS5Proxy:
+		socksURL := *proxyURL
+		socksURL.Scheme = socksScheme(proxyType, proxyURL.Scheme)
+
+		d, err := dialer.FromURL(&socksURL)
+		if err != nil {
+			return err
+		}
+		transport.Proxy = nil
+		transport.DialContext = d.DialContext
+	case domain.HTTPProxy:
+		transport.Proxy = http.ProxyURL(proxyURL)
+	default:
+		return fmt.Errorf("unsupported proxy type: %s", proxy.Type)
+	}
+
+	return nil
+}
+
+// proxyTypeFromScheme infers the proxy type from a URL scheme, defaulting to HTTP
+func proxyTypeFromScheme(scheme string) domain.ProxyType {
+	if proxyType, ok := domain.ProxyTypeFromScheme(scheme); ok {
+		return proxyType
+	}
+	return domain.HTTPProxy
+}
+
+// socksScheme returns the SOCKS scheme to dial with. The URL scheme selects
+// the remote-DNS variants (socks4a, socks5h); anything else falls back to
+// the plain scheme for the proxy type.
+func socksScheme(proxyType domain.ProxyType, scheme string) string {
+	if proxyType == domain.SOCKS4Proxy {
+		if scheme == "socks4a" {
+			return scheme
+		}
+		return "socks4"
+	}
+
+	if scheme == "socks5h" {
+		return scheme
+	}
+	return "socks5"
+}
+
 // configureHeaders sets up the request headers
 func configureHeaders(headers http.Header) http.Header {
 	if headers == nil {
@@ -207,8 +269,8 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 			latency := time.Since(startTime)
 			c.metrics.IncrementLatency(latency)
 
-			// Update metrics based on status code
-			if resp.StatusCode < 400 {
+			// Update metrics based on what the response means
+			if Classify(c.classifier, resp) == Success {
 				c.metrics.RecordSuccess(resp.StatusCode)
 			} else {
 				c.metrics.RecordFailure(resp.StatusCode)
@@ -250,6 +312,11 @@ func (c *Client) ConfigureTLS(verifyTLS bool) {
 	c.Transport = transport
 }
 
+// SetClassifier sets the classifier that decides which responses count as successes
+func (c *Client) SetClassifier(classifier ResponseClassifier) {
+	c.classifier = classifier
+}
+
 // GetMetrics returns the client's metrics
 func (c *Client) GetMetrics() *domain.Metrics {
 	return c.metrics
@@ -0,0 +1,429 @@
+// Package dialer implements SOCKS4, SOCKS4a and SOCKS5 proxy dialing.
+//
+// SOCKS5 supports the no-authentication and username/password (RFC 1929)
+// methods. Destination host names can be resolved locally or, for SOCKS4a
+// and SOCKS5 with RemoteDNS set ("socks5h"), by the proxy itself.
+package dialer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Protocol identifies the SOCKS protocol version
+type Protocol int
+
+const (
+	// SOCKS4 is SOCKS version 4 (SOCKS4a when RemoteDNS is set)
+	SOCKS4 Protocol = 4
+	// SOCKS5 is SOCKS version 5
+	SOCKS5 Protocol = 5
+)
+
+// Common errors
+var (
+	ErrUnsupportedScheme  = errors.New("unsupported SOCKS scheme")
+	ErrUnsupportedNetwork = errors.New("SOCKS proxies only support TCP")
+	ErrAuthFailed         = errors.New("SOCKS authentication failed")
+	ErrNoAcceptableAuth   = errors.New("SOCKS proxy accepted none of the offered authentication methods")
+	ErrNoIPv4Address      = errors.New("SOCKS4 requires an IPv4 destination address")
+)
+
+// ContextDialer dials network connections with a context
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Dialer establishes connections through a SOCKS proxy
+type Dialer struct {
+	// ProxyAddr is the host:port of the SOCKS proxy
+	ProxyAddr string
+
+	// Protocol is the SOCKS protocol version
+	Protocol Protocol
+
+	// RemoteDNS lets the proxy resolve destination host names (SOCKS4a, socks5h)
+	RemoteDNS bool
+
+	// Username and Password authenticate with the proxy.
+	// SOCKS4 sends the username as the user ID and ignores the password.
+	Username string
+	Password string
+
+	// Forward dials the connection to the proxy (defaults to a net.Dialer)
+	Forward ContextDialer
+
+	// Resolver resolves destination host names when RemoteDNS is false
+	Resolver *net.Resolver
+}
+
+// FromURL creates a Dialer from a proxy URL.
+// Supported schemes are socks4, socks4a, socks5 and socks5h.
+func FromURL(u *url.URL) (*Dialer, error) {
+	d := &Dialer{ProxyAddr: u.Host}
+
+	switch u.Scheme {
+	case "socks4":
+		d.Protocol = SOCKS4
+	case "socks4a":
+		d.Protocol = SOCKS4
+		d.RemoteDNS = true
+	case "socks5":
+		d.Protocol = SOCKS5
+	case "socks5h":
+		d.Protocol = SOCKS5
+		d.RemoteDNS = true
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+	}
+
+	if u.User != nil {
+		d.Username = u.User.Username()
+		d.Password, _ = u.User.Password()
+	}
+
+	return d, nil
+}
+
+// ReplyError is returned when the proxy rejects a connection request
+type ReplyError struct {
+	Protocol Protocol
+	Code     byte
+}
+
+// Error implements the error interface
+func (e *ReplyError) Error() string {
+	return fmt.Sprintf("SOCKS%d proxy rejected connection: %s", e.Protocol, e.reason())
+}
+
+func (e *ReplyError) reason() string {
+	if e.Protocol == SOCKS4 {
+		switch e.Code {
+		case socks4Rejected:
+			return "request rejected or failed"
+		case socks4NoIdentd:
+			return "identd unreachable"
+		case socks4IdentMismatch:
+			return "identd user ID mismatch"
+		}
+		return fmt.Sprintf("unknown reply code %d", e.Code)
+	}
+
+	switch e.Code {
+	case 0x01:
+		return "general failure"
+	case 0x02:
+		return "connection not allowed by ruleset"
+	case 0x03:
+		return "network unreachable"
+	case 0x04:
+		return "host unreachable"
+	case 0x05:
+		return "connection refused"
+	case 0x06:
+		return "TTL expired"
+	case 0x07:
+		return "command not supported"
+	case 0x08:
+		return "address type not supported"
+	}
+	return fmt.Sprintf("unknown reply code %d", e.Code)
+}
+
+// DialContext connects to address through the SOCKS proxy
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedNetwork, network)
+	}
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination address %q: %w", address, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination port %q: %w", portStr, err)
+	}
+
+	forward := d.Forward
+	if forward == nil {
+		forward = &net.Dialer{}
+	}
+
+	conn, err := forward.DialContext(ctx, "tcp", d.ProxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SOCKS proxy: %w", err)
+	}
+
+	if err := d.handshake(ctx, conn, host, uint16(port)); err != nil {
+		if closeErr := conn.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// handshake negotiates the connection, aborting if the context is done
+func (d *Dialer) handshake(ctx context.Context, conn net.Conn, host string, port uint16) (err error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+		defer func() {
+			if resetErr := conn.SetDeadline(time.Time{}); resetErr != nil && err == nil {
+				err = resetErr
+			}
+		}()
+	}
+
+	// Unblock reads and writes if the context is cancelled mid-handshake
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// A failed deadline means the connection is already unusable
+			_ = conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+
+	if d.Protocol == SOCKS4 {
+		err = d.handshakeSOCKS4(ctx, conn, host, port)
+	} else {
+		err = d.handshakeSOCKS5(ctx, conn, host, port)
+	}
+
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// resolve looks up a host name locally, preferring IPv4 when requested
+func (d *Dialer) resolve(ctx context.Context, host string, ipv4Only bool) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if ipv4Only && ip.To4() == nil {
+			return nil, ErrNoIPv4Address
+		}
+		return ip, nil
+	}
+
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+
+	for _, addr := range addrs {
+		if ip4 := addr.IP.To4(); ip4 != nil {
+			return ip4, nil
+		}
+	}
+	if ipv4Only || len(addrs) == 0 {
+		return nil, ErrNoIPv4Address
+	}
+	return addrs[0].IP, nil
+}
+
+// SOCKS4 reply codes
+const (
+	socks4Granted       = 90
+	socks4Rejected      = 91
+	socks4NoIdentd      = 92
+	socks4IdentMismatch = 93
+)
+
+// handshakeSOCKS4 performs a SOCKS4 or SOCKS4a CONNECT request
+func (d *Dialer) handshakeSOCKS4(ctx context.Context, conn net.Conn, host string, port uint16) error {
+	req := []byte{4, 1, byte(port >> 8), byte(port)}
+
+	var remoteHost string
+	if ip := net.ParseIP(host); ip != nil || !d.RemoteDNS {
+		ip, err := d.resolve(ctx, host, true)
+		if err != nil {
+			return err
+		}
+		req = append(req, ip.To4()...)
+	} else {
+		// SOCKS4a: an invalid IP of 0.0.0.x tells the proxy a host name follows
+		req = append(req, 0, 0, 0, 1)
+		remoteHost = host
+	}
+
+	req = append(req, d.Username...)
+	req = append(req, 0)
+	if remoteHost != "" {
+		req = append(req, remoteHost...)
+		req = append(req, 0)
+	}
+
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("failed to send SOCKS4 request: %w", err)
+	}
+
+	var reply [8]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return fmt.Errorf("failed to read SOCKS4 reply: %w", err)
+	}
+	if reply[1] != socks4Granted {
+		return &ReplyError{Protocol: SOCKS4, Code: reply[1]}
+	}
+
+	return nil
+}
+
+// SOCKS5 constants
+const (
+	socks5Version      = 5
+	socks5NoAuth       = 0x00
+	socks5UserPass     = 0x02
+	socks5NoAcceptable = 0xff
+	socks5Connect      = 0x01
+	socks5IPv4         = 0x01
+	socks5Domain       = 0x03
+	socks5IPv6         = 0x04
+)
+
+// handshakeSOCKS5 negotiates authentication and performs a SOCKS5 CONNECT request
+func (d *Dialer) handshakeSOCKS5(ctx context.Context, conn net.Conn, host string, port uint16) error {
+	if err := d.authenticateSOCKS5(conn); err != nil {
+		return err
+	}
+
+	req := []byte{socks5Version, socks5Connect, 0}
+
+	if ip := net.ParseIP(host); ip == nil && d.RemoteDNS {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long: %s", host)
+		}
+		req = append(req, socks5Domain, byte(len(host)))
+		req = append(req, host...)
+	} else {
+		ip, err := d.resolve(ctx, host, false)
+		if err != nil {
+			return err
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, socks5IPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, socks5IPv6)
+			req = append(req, ip.To16()...)
+		}
+	}
+	req = append(req, byte(port>>8), byte(port))
+
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("failed to send SOCKS5 request: %w", err)
+	}
+
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return fmt.Errorf("failed to read SOCKS5 reply: %w", err)
+	}
+	if header[0] != socks5Version {
+		return fmt.Errorf("unexpected SOCKS5 reply version %d", header[0])
+	}
+	if header[1] != 0 {
+		return &ReplyError{Protocol: SOCKS5, Code: header[1]}
+	}
+
+	// Discard the bound address; callers only need the established stream
+	var addrLen int
+	switch header[3] {
+	case socks5IPv4:
+		addrLen = net.IPv4len
+	case socks5IPv6:
+		addrLen = net.IPv6len
+	case socks5Domain:
+		var l [1]byte
+		if _, err := io.ReadFull(conn, l[:]); err != nil {
+			return fmt.Errorf("failed to read SOCKS5 bound address: %w", err)
+		}
+		addrLen = int(l[0])
+	default:
+		return fmt.Errorf("unknown SOCKS5 address type %d", header[3])
+	}
+
+	if _, err := io.ReadFull(conn, make([]byte, addrLen+2)); err != nil {
+		return fmt.Errorf("failed to read SOCKS5 bound address: %w", err)
+	}
+
+	return nil
+}
+
+// authenticateSOCKS5 negotiates an authentication method with the proxy
+func (d *Dialer) authenticateSOCKS5(conn net.Conn) error {
+	methods := []byte{socks5NoAuth}
+	if d.Username != "" || d.Password != "" {
+		methods = []byte{socks5UserPass, socks5NoAuth}
+	}
+
+	greeting := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err := conn.Write(greeting); err != nil {
+		return fmt.Errorf("failed to send SOCKS5 greeting: %w", err)
+	}
+
+	var choice [2]byte
+	if _, err := io.ReadFull(conn, choice[:]); err != nil {
+		return fmt.Errorf("failed to read SOCKS5 greeting reply: %w", err)
+	}
+	if choice[0] != socks5Version {
+		return fmt.Errorf("unexpected SOCKS5 greeting version %d", choice[0])
+	}
+
+	switch choice[1] {
+	case socks5NoAuth:
+		return nil
+	case socks5UserPass:
+		if d.Username == "" && d.Password == "" {
+			return ErrNoAcceptableAuth
+		}
+		return d.authenticateUserPass(conn)
+	case socks5NoAcceptable:
+		return ErrNoAcceptableAuth
+	default:
+		return fmt.Errorf("SOCKS5 proxy chose unsupported authentication method %d", choice[1])
+	}
+}
+
+// authenticateUserPass performs username/password authentication (RFC 1929)
+func (d *Dialer) authenticateUserPass(conn net.Conn) error {
+	if len(d.Username) > 255 || len(d.Password) > 255 {
+		return errors.New("SOCKS5 username and password must be at most 255 bytes")
+	}
+
+	req := []byte{1, byte(len(d.Username))}
+	req = append(req, d.Username...)
+	req = append(req, byte(len(d.Password)))
+	req = append(req, d.Password...)
+
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("failed to send SOCKS5 credentials: %w", err)
+	}
+
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return fmt.Errorf("failed to read SOCKS5 authentication reply: %w", err)
+	}
+	if reply[1] != 0 {
+		return ErrAuthFailed
+	}
+
+	return nil
+}
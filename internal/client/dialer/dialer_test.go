@@ -0,0 +1,419 @@
+package dialer_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/client/dialer"
+)
+
+// socksServer is a minimal in-process SOCKS4/4a/5 server for tests
+type socksServer struct {
+	ln       net.Listener
+	username string
+	password string
+
+	// hosts maps host names to real addresses for remote DNS resolution
+	hosts map[string]string
+
+	mu       sync.Mutex
+	lastHost string
+}
+
+func newSOCKSServer(t *testing.T, username, password string, hosts map[string]string) *socksServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	s := &socksServer{ln: ln, username: username, password: password, hosts: hosts}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+
+	return s
+}
+
+func (s *socksServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *socksServer) requestedHost() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastHost
+}
+
+func (s *socksServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *socksServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	version, err := r.ReadByte()
+	if err != nil {
+		return
+	}
+
+	var target string
+	if version == 4 {
+		target, err = s.handleSOCKS4(r, conn)
+	} else {
+		target, err = s.handleSOCKS5(r, conn)
+	}
+	if err != nil || target == "" {
+		return
+	}
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	if version == 4 {
+		conn.Write([]byte{0, 90, 0, 0, 0, 0, 0, 0})
+	} else {
+		conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	}
+
+	go io.Copy(upstream, r)
+	io.Copy(conn, upstream)
+}
+
+func (s *socksServer) resolveTarget(host string, port uint16) string {
+	s.mu.Lock()
+	s.lastHost = host
+	s.mu.Unlock()
+
+	if addr, ok := s.hosts[host]; ok {
+		return addr
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+func readCString(r *bufio.Reader) (string, error) {
+	b, err := r.ReadBytes(0)
+	if err != nil {
+		return "", err
+	}
+	return string(b[:len(b)-1]), nil
+}
+
+func (s *socksServer) handleSOCKS4(r *bufio.Reader, conn net.Conn) (string, error) {
+	var req [7]byte
+	if _, err := io.ReadFull(r, req[:]); err != nil {
+		return "", err
+	}
+	port := binary.BigEndian.Uint16(req[1:3])
+	ip := net.IP(req[3:7])
+
+	userID, err := readCString(r)
+	if err != nil {
+		return "", err
+	}
+
+	host := ip.String()
+	if ip[0] == 0 && ip[1] == 0 && ip[2] == 0 && ip[3] != 0 {
+		if host, err = readCString(r); err != nil {
+			return "", err
+		}
+	}
+
+	if s.username != "" && userID != s.username {
+		conn.Write([]byte{0, 91, 0, 0, 0, 0, 0, 0})
+		return "", nil
+	}
+
+	return s.resolveTarget(host, port), nil
+}
+
+func (s *socksServer) handleSOCKS5(r *bufio.Reader, conn net.Conn) (string, error) {
+	n, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	methods := make([]byte, n)
+	if _, err := io.ReadFull(r, methods); err != nil {
+		return "", err
+	}
+
+	wanted := byte(0x00)
+	if s.username != "" {
+		wanted = 0x02
+	}
+	offered := false
+	for _, m := range methods {
+		if m == wanted {
+			offered = true
+		}
+	}
+	if !offered {
+		conn.Write([]byte{5, 0xff})
+		return "", nil
+	}
+	conn.Write([]byte{5, wanted})
+
+	if wanted == 0x02 {
+		var hdr [2]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return "", err
+		}
+		user := make([]byte, hdr[1])
+		if _, err := io.ReadFull(r, user); err != nil {
+			return "", err
+		}
+		plen, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		pass := make([]byte, plen)
+		if _, err := io.ReadFull(r, pass); err != nil {
+			return "", err
+		}
+		if string(user) != s.username || string(pass) != s.password {
+			conn.Write([]byte{1, 1})
+			return "", nil
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(r, req[:]); err != nil {
+		return "", err
+	}
+
+	var host string
+	switch req[3] {
+	case 0x01:
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case 0x04:
+		ip := make([]byte, 16)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case 0x03:
+		l, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		name := make([]byte, l)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		return "", fmt.Errorf("bad address type %d", req[3])
+	}
+
+	var portBuf [2]byte
+	if _, err := io.ReadFull(r, portBuf[:]); err != nil {
+		return "", err
+	}
+
+	return s.resolveTarget(host, binary.BigEndian.Uint16(portBuf[:])), nil
+}
+
+// get performs an HTTP GET through the dialer and returns the response body
+func get(t *testing.T, d *dialer.Dialer, target string) (string, error) {
+	t.Helper()
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{DialContext: d.DialContext},
+		Timeout:   5 * time.Second,
+	}
+
+	resp, err := httpClient.Get(target)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestDialer(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer target.Close()
+
+	targetAddr := target.Listener.Addr().String()
+	_, targetPort, _ := net.SplitHostPort(targetAddr)
+	remoteHosts := map[string]string{"target.test": targetAddr}
+
+	testCases := []struct {
+		name       string
+		proxyURL   string
+		username   string
+		password   string
+		targetURL  string
+		remoteHost string // host name the proxy should see, if resolved remotely
+		wantErr    error
+	}{
+		{
+			name:      "SOCKS4 with IP address",
+			proxyURL:  "socks4://%s",
+			targetURL: target.URL,
+		},
+		{
+			name:      "SOCKS4 with user ID",
+			proxyURL:  "socks4://alice@%s",
+			username:  "alice",
+			targetURL: target.URL,
+		},
+		{
+			name:      "SOCKS4 with wrong user ID",
+			proxyURL:  "socks4://bob@%s",
+			username:  "alice",
+			targetURL: target.URL,
+			wantErr:   &dialer.ReplyError{},
+		},
+		{
+			name:       "SOCKS4a resolves host names remotely",
+			proxyURL:   "socks4a://%s",
+			targetURL:  "http://target.test:" + targetPort,
+			remoteHost: "target.test",
+		},
+		{
+			name:      "SOCKS5 without authentication",
+			proxyURL:  "socks5://%s",
+			targetURL: target.URL,
+		},
+		{
+			name:      "SOCKS5 with username and password",
+			proxyURL:  "socks5://alice:secret@%s",
+			username:  "alice",
+			password:  "secret",
+			targetURL: target.URL,
+		},
+		{
+			name:      "SOCKS5 with wrong password",
+			proxyURL:  "socks5://alice:wrong@%s",
+			username:  "alice",
+			password:  "secret",
+			targetURL: target.URL,
+			wantErr:   dialer.ErrAuthFailed,
+		},
+		{
+			name:      "SOCKS5 missing required credentials",
+			proxyURL:  "socks5://%s",
+			username:  "alice",
+			password:  "secret",
+			targetURL: target.URL,
+			wantErr:   dialer.ErrNoAcceptableAuth,
+		},
+		{
+			name:       "SOCKS5h resolves host names remotely",
+			proxyURL:   "socks5h://%s",
+			targetURL:  "http://target.test:" + targetPort,
+			remoteHost: "target.test",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newSOCKSServer(t, tc.username, tc.password, remoteHosts)
+
+			u, err := url.Parse(fmt.Sprintf(tc.proxyURL, srv.addr()))
+			if err != nil {
+				t.Fatalf("url.Parse failed: %v", err)
+			}
+			d, err := dialer.FromURL(u)
+			if err != nil {
+				t.Fatalf("FromURL failed: %v", err)
+			}
+
+			body, err := get(t, d, tc.targetURL)
+			if tc.wantErr != nil {
+				var replyErr *dialer.ReplyError
+				if _, isReply := tc.wantErr.(*dialer.ReplyError); isReply {
+					if !errors.As(err, &replyErr) {
+						t.Fatalf("error = %v, want a ReplyError", err)
+					}
+					return
+				}
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if body != "hello" {
+				t.Errorf("body = %q, want %q", body, "hello")
+			}
+			if tc.remoteHost != "" && srv.requestedHost() != tc.remoteHost {
+				t.Errorf("proxy saw host %q, want %q", srv.requestedHost(), tc.remoteHost)
+			}
+		})
+	}
+}
+
+func TestFromURLUnsupportedScheme(t *testing.T) {
+	u, _ := url.Parse("http://127.0.0.1:1080")
+	if _, err := dialer.FromURL(u); !errors.Is(err, dialer.ErrUnsupportedScheme) {
+		t.Errorf("FromURL error = %v, want %v", err, dialer.ErrUnsupportedScheme)
+	}
+}
+
+func TestDialContextCancellation(t *testing.T) {
+	// A proxy that accepts connections but never answers the handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	d := &dialer.Dialer{ProxyAddr: ln.Addr().String(), Protocol: dialer.SOCKS5}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = d.DialContext(ctx, "tcp", "127.0.0.1:80")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DialContext error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("DialContext took %v after the context expired", elapsed)
+	}
+}
@@ -0,0 +1,393 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+)
+
+// Outcome describes the result of a single round trip made through a proxy
+type Outcome struct {
+	Proxy    *domain.Proxy
+	Request  *http.Request
+	Response *http.Response // nil when Err is set
+	Err      error
+	Latency  time.Duration
+}
+
+// Source supplies proxies to a RotatingTransport and receives the outcome
+// of every request made through them.
+type Source interface {
+	// Select returns the proxy to use for the given request.
+	// Proxies listed in exclude have already been tried for this request
+	// and should be avoided.
+	Select(req *http.Request, exclude []string) (*domain.Proxy, error)
+
+	// Report is called once for every completed round trip. Returning true
+	// retries a response the RetryPolicy would accept, such as a block page,
+	// through another proxy.
+	Report(outcome Outcome) (retry bool)
+}
+
+// Backoff calculates the delay before a retry attempt
+type Backoff interface {
+	NextDelay(attempt int) time.Duration
+}
+
+// SequenceBackoff is implemented by backoffs whose delay depends on the
+// previous delay of the same request's retries
+type SequenceBackoff interface {
+	NextDelayAfter(attempt int, previous time.Duration) time.Duration
+}
+
+// RetryPolicy controls how a RotatingTransport retries failed requests
+type RetryPolicy struct {
+	// MaxRetries is the number of additional attempts after the first one
+	MaxRetries int
+
+	// Backoff calculates the delay before each retry (no delay if nil)
+	Backoff Backoff
+
+	// RetryOnStatus lists response status codes that trigger a retry
+	RetryOnStatus []int
+
+	// RetryNonIdempotent allows retrying methods such as POST and PATCH
+	RetryNonIdempotent bool
+
+	// MaxRetryAfter is the longest Retry-After the transport waits out.
+	// A response asking for longer is retried through another proxy after
+	// the usual backoff delay. Defaults to DefaultMaxRetryAfter.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultMaxRetryAfter is the longest Retry-After waited out when a policy doesn't set one
+const DefaultMaxRetryAfter = time.Minute
+
+// DefaultRetryStatusCodes are the status codes retried when a policy doesn't set any
+var DefaultRetryStatusCodes = []int{
+	http.StatusProxyAuthRequired,
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RotatingTransport is an http.RoundTripper that asks its Source for a proxy
+// on every request. Transports are cached per proxy ID so connections to the
+// same proxy are reused across requests. Failed requests are retried through
+// a different proxy according to the RetryPolicy.
+type RotatingTransport struct {
+	source  Source
+	options Options
+	retry   RetryPolicy
+
+	transports map[string]*cachedTransport
+	mu         sync.Mutex
+}
+
+// cachedTransport remembers which proxy configuration a transport was built for
+type cachedTransport struct {
+	key string
+	rt  http.RoundTripper
+}
+
+// NewRotatingTransport creates a RotatingTransport that selects proxies from source.
+// The options are applied to every per-proxy transport it creates.
+func NewRotatingTransport(source Source, options Options, retry RetryPolicy) *RotatingTransport {
+	if retry.RetryOnStatus == nil {
+		retry.RetryOnStatus = DefaultRetryStatusCodes
+	}
+	if retry.MaxRetryAfter <= 0 {
+		retry.MaxRetryAfter = DefaultMaxRetryAfter
+	}
+
+	return &RotatingTransport{
+		source:     source,
+		options:    options,
+		retry:      retry,
+		transports: make(map[string]*cachedTransport),
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *RotatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	canRetry := t.retry.MaxRetries > 0 && isReplayable(req) &&
+		(t.retry.RetryNonIdempotent || isIdempotent(req))
+
+	var tried []string
+	var backoff time.Duration
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 {
+			var err error
+			if attemptReq, err = rewindRequest(req); err != nil {
+				return nil, err
+			}
+		}
+
+		proxy, resp, retry, err := t.roundTripOnce(attemptReq, tried)
+		if proxy == nil {
+			// No proxy could be selected, so there is nothing to retry through
+			return nil, err
+		}
+		tried = append(tried, proxy.ID)
+
+		if !canRetry || attempt >= t.retry.MaxRetries || !(retry || t.shouldRetry(req, resp, err)) {
+			return resp, err
+		}
+
+		var delay time.Duration
+		delay, backoff = t.retryDelay(attempt+1, backoff, resp)
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < delay {
+			// Waiting would outlive the request, so hand back what we have
+			return resp, err
+		}
+
+		if resp != nil {
+			discardBody(resp)
+		}
+
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// roundTripOnce sends a single attempt through a proxy that hasn't been tried yet.
+// retry reports whether the source asked for the response to be retried.
+func (t *RotatingTransport) roundTripOnce(req *http.Request, tried []string) (proxy *domain.Proxy, resp *http.Response, retry bool, err error) {
+	proxy, err = t.source.Select(req, tried)
+	if err != nil && len(tried) > 0 {
+		// Every proxy has been tried; reusing one beats failing outright
+		proxy, err = t.source.Select(req, nil)
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	rt, err := t.transportFor(proxy)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	startTime := time.Now()
+	resp, err = rt.RoundTrip(req)
+
+	retry = t.source.Report(Outcome{
+		Proxy:    proxy,
+		Request:  req,
+		Response: resp,
+		Err:      err,
+		Latency:  time.Since(startTime),
+	})
+
+	return proxy, resp, retry, err
+}
+
+// shouldRetry reports whether an attempt failed in a way worth retrying
+func (t *RotatingTransport) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		// Don't retry once the caller has given up
+		return req.Context().Err() == nil
+	}
+
+	for _, code := range t.retry.RetryOnStatus {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before the given retry attempt,
+// honouring a Retry-After header when it asks for longer than the backoff.
+// previous is the backoff's delay for the last retry; the backoff's delay for
+// this one is returned alongside, so Retry-After doesn't feed into the next.
+// A Retry-After longer than MaxRetryAfter is ignored in favour of the backoff.
+func (t *RotatingTransport) retryDelay(attempt int, previous time.Duration, resp *http.Response) (delay, backoff time.Duration) {
+	switch b := t.retry.Backoff.(type) {
+	case nil:
+	case SequenceBackoff:
+		backoff = b.NextDelayAfter(attempt, previous)
+	default:
+		backoff = b.NextDelay(attempt)
+	}
+
+	delay = backoff
+	if retryAfter, ok := RetryAfter(resp); ok && retryAfter > delay && retryAfter <= t.retry.MaxRetryAfter {
+		delay = retryAfter
+	}
+
+	return delay, backoff
+}
+
+// transportFor returns the cached transport for a proxy, creating it if the
+// proxy is new or its configuration has changed since the transport was built.
+func (t *RotatingTransport) transportFor(proxy *domain.Proxy) (http.RoundTripper, error) {
+	key := transportKey(proxy)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cached, ok := t.transports[proxy.ID]; ok {
+		if cached.key == key {
+			return cached.rt, nil
+		}
+		closeIdle(cached.rt)
+	}
+
+	httpClient, err := NewClient(proxy, t.options)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := httpClient.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	t.transports[proxy.ID] = &cachedTransport{key: key, rt: rt}
+	return rt, nil
+}
+
+// Forget drops the cached transport for a proxy and closes its idle connections
+func (t *RotatingTransport) Forget(proxyID string) {
+	t.mu.Lock()
+	cached, ok := t.transports[proxyID]
+	delete(t.transports, proxyID)
+	t.mu.Unlock()
+
+	if ok {
+		closeIdle(cached.rt)
+	}
+}
+
+// CloseIdleConnections closes idle connections on every cached transport
+func (t *RotatingTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, cached := range t.transports {
+		closeIdle(cached.rt)
+	}
+}
+
+// transportKey identifies the proxy settings a transport depends on
+func transportKey(proxy *domain.Proxy) string {
+	return string(proxy.Type) + "|" + proxy.URL + "|" + proxy.Username + "|" + proxy.Password
+}
+
+// closeIdle closes idle connections if the transport supports it
+func closeIdle(rt http.RoundTripper) {
+	type idleCloser interface {
+		CloseIdleConnections()
+	}
+	if c, ok := rt.(idleCloser); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// CloseIdleConnections forwards to the underlying transport
+func (t *headerTransport) CloseIdleConnections() {
+	closeIdle(t.rt)
+}
+
+// RetryAfter parses the Retry-After header of a response.
+// Both delay-seconds and HTTP-date forms are supported.
+func RetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// isReplayable reports whether the request body can be sent again
+func isReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// isIdempotent reports whether the request may safely be sent more than once
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	// Same convention as net/http: an idempotency key makes any method safe to retry
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// rewindRequest returns a copy of the request with a fresh body for another attempt
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return clone, nil
+	}
+
+	if req.GetBody == nil {
+		return nil, errors.New("request body cannot be replayed")
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone.Body = body
+
+	return clone, nil
+}
+
+// discardBody drains a bounded amount of the body so the connection can be reused
+func discardBody(resp *http.Response) {
+	const maxDrain = 4 << 10
+
+	// The response is being thrown away; a failed read or close only means
+	// the connection won't be reused
+	_, _ = io.CopyN(io.Discard, resp.Body, maxDrain)
+	_ = resp.Body.Close()
+}
+
+// sleepContext waits for the given duration or until the context is done
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
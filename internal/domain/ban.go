@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Ban keeps a proxy away from a target domain until it expires
+type Ban struct {
+	ProxyID   string    `json:"proxy_id"`
+	Domain    string    `json:"domain"`
+	Reason    string    `json:"reason"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the ban has lapsed at the given time
+func (b *Ban) Expired(now time.Time) bool {
+	return !now.Before(b.ExpiresAt)
+}
+
+// Covers reports whether the ban applies to host, which is the case for the
+// banned domain itself and all of its subdomains
+func (b *Ban) Covers(host string) bool {
+	host = strings.ToLower(host)
+	return host == b.Domain || strings.HasSuffix(host, "."+b.Domain)
+}
@@ -0,0 +1,27 @@
+package domain
+
+// HealthState is a proxy's place in the health lifecycle
+type HealthState string
+
+// Health lifecycle states. A proxy with no recorded state is healthy.
+const (
+	// HealthHealthy proxies take part in rotation
+	HealthHealthy HealthState = "healthy"
+
+	// HealthSuspect proxies have failed recent health checks but stay in
+	// rotation until they fail enough of them in a row
+	HealthSuspect HealthState = "suspect"
+
+	// HealthQuarantined proxies are out of rotation and re-probed with
+	// exponential backoff until they recover or are retired
+	HealthQuarantined HealthState = "quarantined"
+
+	// HealthRetired proxies stayed quarantined too long. They are no longer
+	// probed and wait for an operator to reinstate or remove them.
+	HealthRetired HealthState = "retired"
+)
+
+// InRotation reports whether proxies in the state may be selected
+func (s HealthState) InRotation() bool {
+	return s == "" || s == HealthHealthy || s == HealthSuspect
+}
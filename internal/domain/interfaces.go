@@ -32,6 +32,69 @@ type ProxyRepository interface {
 	GetNext(ctx context.Context) (*Proxy, error)
 }
 
+// PoolRepository defines storage operations for named groups of proxies.
+// Implementations must be safe for concurrent use.
+type PoolRepository interface {
+	// CreatePool creates an empty pool.
+	// Returns ErrPoolExists if a pool with the same name already exists.
+	CreatePool(ctx context.Context, name string) error
+
+	// DeletePool removes a pool and its memberships; the proxies themselves are kept.
+	// Returns ErrPoolNotFound if the pool doesn't exist.
+	DeletePool(ctx context.Context, name string) error
+
+	// ListPools returns the names of all pools in sorted order.
+	ListPools(ctx context.Context) ([]string, error)
+
+	// AddToPool adds a proxy to a pool. Adding an existing member is a no-op.
+	// Returns ErrPoolNotFound if the pool doesn't exist.
+	AddToPool(ctx context.Context, poolName, proxyID string) error
+
+	// RemoveFromPool removes a proxy from a pool.
+	// Returns ErrPoolNotFound if the pool doesn't exist and
+	// ErrProxyNotFound if the proxy isn't a member.
+	RemoveFromPool(ctx context.Context, poolName, proxyID string) error
+
+	// GetPoolMembers returns the IDs of the proxies in a pool in the order they were added.
+	// Returns ErrPoolNotFound if the pool doesn't exist.
+	GetPoolMembers(ctx context.Context, poolName string) ([]string, error)
+}
+
+// SessionRepository defines storage for session-to-proxy affinity.
+// Implementations must be safe for concurrent use.
+type SessionRepository interface {
+	// GetSession returns the session stored under key, even if it has expired.
+	// Returns ErrSessionNotFound if there is none.
+	GetSession(ctx context.Context, key string) (*Session, error)
+
+	// SaveSession creates or replaces a session.
+	SaveSession(ctx context.Context, session *Session) error
+
+	// DeleteSession removes a session.
+	// Returns ErrSessionNotFound if there is none.
+	DeleteSession(ctx context.Context, key string) error
+
+	// DeleteExpiredSessions removes every session that expired before the given time.
+	DeleteExpiredSessions(ctx context.Context, before time.Time) error
+}
+
+// BanRepository defines storage for per-domain proxy bans.
+// Implementations must be safe for concurrent use.
+type BanRepository interface {
+	// ListBans returns every stored ban, including expired ones.
+	ListBans(ctx context.Context) ([]*Ban, error)
+
+	// SaveBan creates or replaces the ban for the ban's proxy and domain.
+	SaveBan(ctx context.Context, ban *Ban) error
+
+	// DeleteBan removes the ban on a proxy for a domain.
+	// Returns ErrBanNotFound if there is none.
+	DeleteBan(ctx context.Context, proxyID, domain string) error
+
+	// DeleteExpiredBans removes every ban that expired before the given time.
+	DeleteExpiredBans(ctx context.Context, before time.Time) error
+}
+
 // ProxyProvider defines the minimal interface for getting proxies
 type ProxyProvider interface {
 	// GetProxy returns the next proxy according to the configured rotation strategy.
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -24,6 +26,21 @@ const (
 	SOCKS5 = SOCKS5Proxy
 )
 
+// ProxyTypeFromScheme maps a proxy URL scheme to its proxy type.
+// The https, socks4a and socks5h variants map to their base types.
+func ProxyTypeFromScheme(scheme string) (ProxyType, bool) {
+	switch strings.ToLower(scheme) {
+	case "http", "https":
+		return HTTPProxy, true
+	case "socks4", "socks4a":
+		return SOCKS4Proxy, true
+	case "socks5", "socks5h":
+		return SOCKS5Proxy, true
+	default:
+		return "", false
+	}
+}
+
 type ProxyMetrics struct {
 	SuccessCount   int64
 	FailureCount   int64
@@ -63,6 +80,21 @@ type Proxy struct {
 	Settings    ProxySettings
 	MaxRetries  int           // Maximum retry attempts
 	Timeout     time.Duration // Proxy-specific timeout
+
+	// RateLimit overrides the rotator's requests-per-second limit for this proxy; zero uses the default
+	RateLimit float64 `json:"rate_limit,omitempty"`
+	// RateBurst overrides the rotator's burst size for this proxy; zero uses the default
+	RateBurst int `json:"rate_burst,omitempty"`
+
+	// Tags are free-form labels such as "residential" or "premium" used to filter selection
+	Tags []string `json:"tags,omitempty"`
+
+	// Health is the proxy's place in the health lifecycle; empty means healthy
+	Health HealthState `json:"health,omitempty"`
+	// HealthFailures counts health checks and re-probes failed in a row
+	HealthFailures int `json:"health_failures,omitempty"`
+	// HealthSince is when the proxy entered an unhealthy state
+	HealthSince *time.Time `json:"health_since,omitempty"`
 }
 
 // ParseURL parses the proxy URL string into a URL object
@@ -73,6 +105,31 @@ func (p *Proxy) ParseURL() (*url.URL, error) {
 	return url.Parse(p.URL)
 }
 
+// HasCredentials reports whether credentials are stored in the Username or Password fields
+func (p *Proxy) HasCredentials() bool {
+	return p.Username != "" || p.Password != ""
+}
+
+// AuthURL parses the proxy URL and applies the stored credentials.
+// When Username or Password is set, the fields replace any userinfo
+// embedded in URL; otherwise the URL's own userinfo is kept.
+func (p *Proxy) AuthURL() (*url.URL, error) {
+	u, err := p.ParseURL()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.HasCredentials() {
+		if p.Password != "" {
+			u.User = url.UserPassword(p.Username, p.Password)
+		} else {
+			u.User = url.User(p.Username)
+		}
+	}
+
+	return u, nil
+}
+
 // IsValid checks if the proxy configuration is valid
 func (p *Proxy) IsValid() bool {
 	_, err := p.ParseURL()
@@ -84,6 +141,29 @@ func (p *Proxy) String() string {
 	return p.URL
 }
 
+// HasTags reports whether the proxy carries every one of the given tags
+func (p *Proxy) HasTags(tags ...string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(p.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// EncodeTags joins tags into the comma-separated form used by the SQL stores
+func EncodeTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+// DecodeTags splits a value produced by EncodeTags
+func DecodeTags(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
 // GetEnabled returns the proxy's enabled state
 func (p *Proxy) GetEnabled() bool {
 	return p.Enabled
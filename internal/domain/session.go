@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// Session pins a session key to the proxy serving it
+type Session struct {
+	Key       string    `json:"key"`
+	ProxyID   string    `json:"proxy_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the session has lapsed at the given time
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+)
+
+// banKey identifies a ban by proxy and domain
+type banKey struct {
+	proxyID string
+	domain  string
+}
+
+// memoryBanStore keeps per-domain proxy bans in memory
+type memoryBanStore struct {
+	bans map[banKey]domain.Ban
+	mu   sync.RWMutex
+}
+
+func newMemoryBanStore() *memoryBanStore {
+	return &memoryBanStore{
+		bans: make(map[banKey]domain.Ban),
+	}
+}
+
+// NewMemoryBanRepository creates an in-memory ban repository.
+// It can be paired with any ProxyRepository that lacks ban support.
+func NewMemoryBanRepository() domain.BanRepository {
+	return newMemoryBanStore()
+}
+
+// ListBans implements BanRepository.ListBans
+func (s *memoryBanStore) ListBans(ctx context.Context) ([]*domain.Ban, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bans := make([]*domain.Ban, 0, len(s.bans))
+	for _, ban := range s.bans {
+		ban := ban
+		bans = append(bans, &ban)
+	}
+	return bans, nil
+}
+
+// SaveBan implements BanRepository.SaveBan
+func (s *memoryBanStore) SaveBan(ctx context.Context, ban *domain.Ban) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bans[banKey{ban.ProxyID, ban.Domain}] = *ban
+	return nil
+}
+
+// DeleteBan implements BanRepository.DeleteBan
+func (s *memoryBanStore) DeleteBan(ctx context.Context, proxyID, domain string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := banKey{proxyID, domain}
+	if _, exists := s.bans[key]; !exists {
+		return ErrBanNotFound
+	}
+	delete(s.bans, key)
+	return nil
+}
+
+// DeleteExpiredBans implements BanRepository.DeleteExpiredBans
+func (s *memoryBanStore) DeleteExpiredBans(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, ban := range s.bans {
+		if ban.Expired(before) {
+			delete(s.bans, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/repository"
+)
+
+func TestMemoryBanRepository(t *testing.T) {
+	ctx := context.Background()
+	bans := repository.NewMemoryBanRepository()
+	now := time.Now()
+
+	if err := bans.DeleteBan(ctx, "p1", "example.com"); !errors.Is(err, repository.ErrBanNotFound) {
+		t.Errorf("DeleteBan() error = %v, want %v", err, repository.ErrBanNotFound)
+	}
+
+	for _, ban := range []*domain.Ban{
+		{ProxyID: "p1", Domain: "example.com", Reason: "status 403", ExpiresAt: now.Add(-time.Minute)},
+		{ProxyID: "p1", Domain: "example.org", Reason: "status 403", ExpiresAt: now.Add(time.Minute)},
+		{ProxyID: "p2", Domain: "example.com", Reason: "status 403", ExpiresAt: now.Add(time.Minute)},
+	} {
+		if err := bans.SaveBan(ctx, ban); err != nil {
+			t.Fatalf("SaveBan(%s, %s) error = %v", ban.ProxyID, ban.Domain, err)
+		}
+	}
+
+	// Saving again replaces the ban for the same proxy and domain
+	if err := bans.SaveBan(ctx, &domain.Ban{ProxyID: "p2", Domain: "example.com", Reason: "captcha", ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("SaveBan() error = %v", err)
+	}
+
+	list, err := bans.ListBans(ctx)
+	if err != nil {
+		t.Fatalf("ListBans() error = %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("ListBans() returned %d bans, want 3", len(list))
+	}
+	for _, ban := range list {
+		if ban.ProxyID == "p2" && ban.Reason != "captcha" {
+			t.Errorf("ban on p2 has reason %q, want the replacement", ban.Reason)
+		}
+	}
+
+	if err := bans.DeleteExpiredBans(ctx, now); err != nil {
+		t.Fatalf("DeleteExpiredBans() error = %v", err)
+	}
+	if err := bans.DeleteBan(ctx, "p1", "example.com"); !errors.Is(err, repository.ErrBanNotFound) {
+		t.Errorf("expired ban survived DeleteExpiredBans: %v", err)
+	}
+
+	if err := bans.DeleteBan(ctx, "p1", "example.org"); err != nil {
+		t.Fatalf("DeleteBan() error = %v", err)
+	}
+	if list, _ := bans.ListBans(ctx); len(list) != 1 {
+		t.Errorf("ListBans() returned %d bans after deletes, want 1", len(list))
+	}
+
+	// The memory proxy repository stores bans too
+	if _, ok := repository.NewMemoryRepository().(domain.BanRepository); !ok {
+		t.Error("memory repository does not implement BanRepository")
+	}
+}
@@ -12,4 +12,16 @@ var (
 
 	// ErrInvalidProxy is returned when proxy data validation fails
 	ErrInvalidProxy = errors.New("invalid proxy data")
+
+	// ErrPoolNotFound is returned when a pool cannot be found in the repository
+	ErrPoolNotFound = errors.New("pool not found")
+
+	// ErrPoolExists is returned when attempting to create a pool with an existing name
+	ErrPoolExists = errors.New("pool already exists")
+
+	// ErrSessionNotFound is returned when a session cannot be found in the repository
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrBanNotFound is returned when a ban cannot be found in the repository
+	ErrBanNotFound = errors.New("ban not found")
 )
@@ -0,0 +1,47 @@
+package gorm
+
+import (
+	"context"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/repository"
+	"gorm.io/gorm/clause"
+)
+
+// ListBans returns every stored ban
+func (r *proxyRepository) ListBans(ctx context.Context) ([]*domain.Ban, error) {
+	var models []BanModel
+	if err := r.db.WithContext(ctx).Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	bans := make([]*domain.Ban, len(models))
+	for i, model := range models {
+		bans[i] = &domain.Ban{ProxyID: model.ProxyID, Domain: model.Domain, Reason: model.Reason, ExpiresAt: model.ExpiresAt}
+	}
+	return bans, nil
+}
+
+// SaveBan creates or replaces a ban
+func (r *proxyRepository) SaveBan(ctx context.Context, ban *domain.Ban) error {
+	model := BanModel{ProxyID: ban.ProxyID, Domain: ban.Domain, Reason: ban.Reason, ExpiresAt: ban.ExpiresAt}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&model).Error
+}
+
+// DeleteBan removes the ban on a proxy for a domain
+func (r *proxyRepository) DeleteBan(ctx context.Context, proxyID, domain string) error {
+	result := r.db.WithContext(ctx).Delete(&BanModel{}, "proxy_id = ? AND domain = ?", proxyID, domain)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrBanNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredBans removes bans that expired before the given time
+func (r *proxyRepository) DeleteExpiredBans(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).Delete(&BanModel{}, "expires_at <= ?", before).Error
+}
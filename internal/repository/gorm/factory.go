@@ -48,7 +48,7 @@ func NewDB(opts storage.Options) (*gorm.DB, error) {
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
 	// Auto migrate models
-	if err := db.AutoMigrate(&ProxyModel{}); err != nil {
+	if err := db.AutoMigrate(&ProxyModel{}, &PoolModel{}, &PoolMemberModel{}, &SessionModel{}, &BanModel{}); err != nil {
 		return nil, err
 	}
 
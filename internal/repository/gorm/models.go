@@ -30,6 +30,12 @@ type ProxyModel struct {
 	TotalRequests  int64
 	AvgLatency     time.Duration
 	LastStatusCode int
+	RateLimit      float64
+	RateBurst      int
+	Tags           string // Comma-separated
+	Health         string
+	HealthFailures int
+	HealthSince    time.Time
 }
 
 // ToDomain converts a GORM model to a domain model
@@ -41,24 +47,36 @@ func (m *ProxyModel) ToDomain() (*domain.Proxy, error) {
 		lastUsed = &t
 	}
 
+	var healthSince *time.Time
+	if !m.HealthSince.IsZero() {
+		t := m.HealthSince
+		healthSince = &t
+	}
+
 	proxy := &domain.Proxy{
-		ID:          m.ID,
-		URL:         m.URL,
-		Type:        domain.ProxyType(m.Type),
-		Username:    m.Username,
-		Password:    m.Password,
-		CountryCode: m.CountryCode,
-		Weight:      m.Weight,
-		LastUsed:    lastUsed,
-		Latency:     m.Latency,
-		Enabled:     m.Enabled,
-		SuccessRate: m.SuccessRate,
-		UsageCount:  m.UsageCount,
-		ErrorCount:  m.ErrorCount,
-		CreatedAt:   m.CreatedAt,
-		UpdatedAt:   m.UpdatedAt,
-		MaxRetries:  m.MaxRetries,
-		Timeout:     m.Timeout,
+		ID:             m.ID,
+		URL:            m.URL,
+		Type:           domain.ProxyType(m.Type),
+		Username:       m.Username,
+		Password:       m.Password,
+		CountryCode:    m.CountryCode,
+		Weight:         m.Weight,
+		LastUsed:       lastUsed,
+		Latency:        m.Latency,
+		Enabled:        m.Enabled,
+		SuccessRate:    m.SuccessRate,
+		UsageCount:     m.UsageCount,
+		ErrorCount:     m.ErrorCount,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+		MaxRetries:     m.MaxRetries,
+		Timeout:        m.Timeout,
+		RateLimit:      m.RateLimit,
+		RateBurst:      m.RateBurst,
+		Tags:           domain.DecodeTags(m.Tags),
+		Health:         domain.HealthState(m.Health),
+		HealthFailures: m.HealthFailures,
+		HealthSince:    healthSince,
 		Metrics: domain.ProxyMetrics{
 			SuccessCount:   m.SuccessCount,
 			FailureCount:   m.FailureCount,
@@ -78,6 +96,10 @@ func FromDomain(proxy *domain.Proxy) *ProxyModel {
 	if proxy.LastUsed != nil {
 		lastUsed = *proxy.LastUsed
 	}
+	var healthSince time.Time
+	if proxy.HealthSince != nil {
+		healthSince = *proxy.HealthSince
+	}
 
 	return &ProxyModel{
 		ID:             proxy.ID,
@@ -100,5 +122,39 @@ func FromDomain(proxy *domain.Proxy) *ProxyModel {
 		TotalRequests:  proxy.Metrics.TotalRequests,
 		AvgLatency:     proxy.Metrics.AvgLatency,
 		LastStatusCode: proxy.Metrics.LastStatusCode,
+		RateLimit:      proxy.RateLimit,
+		RateBurst:      proxy.RateBurst,
+		Tags:           domain.EncodeTags(proxy.Tags),
+		Health:         string(proxy.Health),
+		HealthFailures: proxy.HealthFailures,
+		HealthSince:    healthSince,
 	}
 }
+
+// PoolModel is the GORM model for a named proxy pool
+type PoolModel struct {
+	Name      string    `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// PoolMemberModel is the GORM model linking a proxy to a pool
+type PoolMemberModel struct {
+	PoolName  string    `gorm:"primaryKey"`
+	ProxyID   string    `gorm:"primaryKey;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// SessionModel is the GORM model pinning a session key to a proxy
+type SessionModel struct {
+	SessionKey string    `gorm:"primaryKey"`
+	ProxyID    string    `gorm:"index"`
+	ExpiresAt  time.Time `gorm:"index"`
+}
+
+// BanModel is the GORM model keeping a proxy away from a domain
+type BanModel struct {
+	ProxyID   string `gorm:"primaryKey"`
+	Domain    string `gorm:"primaryKey"`
+	Reason    string
+	ExpiresAt time.Time `gorm:"index"`
+}
@@ -0,0 +1,104 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/greysquirr3l/lashes/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreatePool creates an empty pool
+func (r *proxyRepository) CreatePool(ctx context.Context, name string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := poolExists(tx, name)
+		if err == nil {
+			return repository.ErrPoolExists
+		}
+		if !errors.Is(err, repository.ErrPoolNotFound) {
+			return err
+		}
+		return tx.Create(&PoolModel{Name: name}).Error
+	})
+}
+
+// DeletePool removes a pool and its memberships
+func (r *proxyRepository) DeletePool(ctx context.Context, name string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&PoolModel{}, "name = ?", name)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return repository.ErrPoolNotFound
+		}
+		return tx.Delete(&PoolMemberModel{}, "pool_name = ?", name).Error
+	})
+}
+
+// ListPools returns the names of all pools
+func (r *proxyRepository) ListPools(ctx context.Context) ([]string, error) {
+	var names []string
+	if err := r.db.WithContext(ctx).Model(&PoolModel{}).Order("name").Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// AddToPool adds a proxy to a pool
+func (r *proxyRepository) AddToPool(ctx context.Context, poolName, proxyID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := poolExists(tx, poolName); err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&PoolMemberModel{PoolName: poolName, ProxyID: proxyID}).Error
+	})
+}
+
+// RemoveFromPool removes a proxy from a pool
+func (r *proxyRepository) RemoveFromPool(ctx context.Context, poolName, proxyID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := poolExists(tx, poolName); err != nil {
+			return err
+		}
+		result := tx.Delete(&PoolMemberModel{}, "pool_name = ? AND proxy_id = ?", poolName, proxyID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return repository.ErrProxyNotFound
+		}
+		return nil
+	})
+}
+
+// GetPoolMembers returns the IDs of the proxies in a pool
+func (r *proxyRepository) GetPoolMembers(ctx context.Context, poolName string) ([]string, error) {
+	db := r.db.WithContext(ctx)
+	if err := poolExists(db, poolName); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	if err := db.Model(&PoolMemberModel{}).
+		Where("pool_name = ?", poolName).
+		Order("created_at, proxy_id").
+		Pluck("proxy_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// poolExists returns ErrPoolNotFound if the named pool doesn't exist
+func poolExists(db *gorm.DB, name string) error {
+	var count int64
+	if err := db.Model(&PoolModel{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return repository.ErrPoolNotFound
+	}
+	return nil
+}
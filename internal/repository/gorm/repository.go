@@ -97,14 +97,18 @@ func (r *proxyRepository) Update(ctx context.Context, proxy *domain.Proxy) error
 
 // Delete removes a proxy by ID
 func (r *proxyRepository) Delete(ctx context.Context, id string) error {
-	result := r.db.WithContext(ctx).Delete(&ProxyModel{}, "id = ?", id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return repository.ErrProxyNotFound
-	}
-	return nil
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&ProxyModel{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return repository.ErrProxyNotFound
+		}
+
+		// Drop the proxy from any pools it belonged to
+		return tx.Delete(&PoolMemberModel{}, "proxy_id = ?", id).Error
+	})
 }
 
 // List retrieves all proxies
@@ -0,0 +1,47 @@
+package gorm
+
+import (
+	"context"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/repository"
+	"gorm.io/gorm/clause"
+)
+
+// GetSession returns the session stored under key
+func (r *proxyRepository) GetSession(ctx context.Context, key string) (*domain.Session, error) {
+	// Find instead of First: unknown sessions are routine and shouldn't be logged as errors
+	var model SessionModel
+	result := r.db.WithContext(ctx).Limit(1).Find(&model, "session_key = ?", key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, repository.ErrSessionNotFound
+	}
+	return &domain.Session{Key: model.SessionKey, ProxyID: model.ProxyID, ExpiresAt: model.ExpiresAt}, nil
+}
+
+// SaveSession creates or replaces a session
+func (r *proxyRepository) SaveSession(ctx context.Context, session *domain.Session) error {
+	model := SessionModel{SessionKey: session.Key, ProxyID: session.ProxyID, ExpiresAt: session.ExpiresAt}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&model).Error
+}
+
+// DeleteSession removes a session
+func (r *proxyRepository) DeleteSession(ctx context.Context, key string) error {
+	result := r.db.WithContext(ctx).Delete(&SessionModel{}, "session_key = ?", key)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredSessions removes sessions that expired before the given time
+func (r *proxyRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).Delete(&SessionModel{}, "expires_at <= ?", before).Error
+}
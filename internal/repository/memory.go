@@ -8,13 +8,22 @@ import (
 )
 
 type memoryRepository struct {
+	*memoryPoolStore
+	*memorySessionStore
+	*memoryBanStore
 	proxies map[string]*domain.Proxy
 	mu      sync.RWMutex
 }
 
+// NewMemoryRepository creates an in-memory repository.
+// The returned repository also implements domain.PoolRepository,
+// domain.SessionRepository and domain.BanRepository.
 func NewMemoryRepository() ProxyRepository {
 	return &memoryRepository{
-		proxies: make(map[string]*domain.Proxy),
+		memoryPoolStore:    newMemoryPoolStore(),
+		memorySessionStore: newMemorySessionStore(),
+		memoryBanStore:     newMemoryBanStore(),
+		proxies:            make(map[string]*domain.Proxy),
 	}
 }
 
@@ -64,6 +73,7 @@ func (r *memoryRepository) Delete(ctx context.Context, id string) error {
 		return ErrProxyNotFound
 	}
 	delete(r.proxies, id)
+	r.forget(id)
 	return nil
 }
 
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+)
+
+// memoryPoolStore keeps pool memberships in memory
+type memoryPoolStore struct {
+	pools map[string][]string
+	mu    sync.RWMutex
+}
+
+func newMemoryPoolStore() *memoryPoolStore {
+	return &memoryPoolStore{
+		pools: make(map[string][]string),
+	}
+}
+
+// NewMemoryPoolRepository creates an in-memory pool repository.
+// It can be paired with any ProxyRepository that lacks pool support.
+func NewMemoryPoolRepository() domain.PoolRepository {
+	return newMemoryPoolStore()
+}
+
+// CreatePool implements PoolRepository.CreatePool
+func (s *memoryPoolStore) CreatePool(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.pools[name]; exists {
+		return ErrPoolExists
+	}
+	s.pools[name] = []string{}
+	return nil
+}
+
+// DeletePool implements PoolRepository.DeletePool
+func (s *memoryPoolStore) DeletePool(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.pools[name]; !exists {
+		return ErrPoolNotFound
+	}
+	delete(s.pools, name)
+	return nil
+}
+
+// ListPools implements PoolRepository.ListPools
+func (s *memoryPoolStore) ListPools(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.pools))
+	for name := range s.pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// AddToPool implements PoolRepository.AddToPool
+func (s *memoryPoolStore) AddToPool(ctx context.Context, poolName, proxyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, exists := s.pools[poolName]
+	if !exists {
+		return ErrPoolNotFound
+	}
+	for _, id := range members {
+		if id == proxyID {
+			return nil
+		}
+	}
+	s.pools[poolName] = append(members, proxyID)
+	return nil
+}
+
+// RemoveFromPool implements PoolRepository.RemoveFromPool
+func (s *memoryPoolStore) RemoveFromPool(ctx context.Context, poolName, proxyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, exists := s.pools[poolName]
+	if !exists {
+		return ErrPoolNotFound
+	}
+	for i, id := range members {
+		if id == proxyID {
+			s.pools[poolName] = append(members[:i:i], members[i+1:]...)
+			return nil
+		}
+	}
+	return ErrProxyNotFound
+}
+
+// GetPoolMembers implements PoolRepository.GetPoolMembers
+func (s *memoryPoolStore) GetPoolMembers(ctx context.Context, poolName string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members, exists := s.pools[poolName]
+	if !exists {
+		return nil, ErrPoolNotFound
+	}
+	return append([]string(nil), members...), nil
+}
+
+// forget removes a proxy from every pool it belongs to
+func (s *memoryPoolStore) forget(proxyID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, members := range s.pools {
+		for i, id := range members {
+			if id == proxyID {
+				s.pools[name] = append(members[:i:i], members[i+1:]...)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/repository"
+)
+
+func TestMemoryPoolRepository(t *testing.T) {
+	ctx := context.Background()
+	pools := repository.NewMemoryPoolRepository()
+
+	t.Run("Create and list pools", func(t *testing.T) {
+		for _, name := range []string{"residential", "datacenter"} {
+			if err := pools.CreatePool(ctx, name); err != nil {
+				t.Fatalf("CreatePool(%q) error = %v", name, err)
+			}
+		}
+
+		got, err := pools.ListPools(ctx)
+		if err != nil {
+			t.Fatalf("ListPools() error = %v", err)
+		}
+		if want := []string{"datacenter", "residential"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("ListPools() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Create duplicate pool", func(t *testing.T) {
+		if err := pools.CreatePool(ctx, "residential"); !errors.Is(err, repository.ErrPoolExists) {
+			t.Errorf("CreatePool() error = %v, want %v", err, repository.ErrPoolExists)
+		}
+	})
+
+	t.Run("Members keep insertion order", func(t *testing.T) {
+		for _, id := range []string{"b", "a", "c", "a"} {
+			if err := pools.AddToPool(ctx, "residential", id); err != nil {
+				t.Fatalf("AddToPool(%q) error = %v", id, err)
+			}
+		}
+
+		got, err := pools.GetPoolMembers(ctx, "residential")
+		if err != nil {
+			t.Fatalf("GetPoolMembers() error = %v", err)
+		}
+		if want := []string{"b", "a", "c"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GetPoolMembers() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Remove member", func(t *testing.T) {
+		if err := pools.RemoveFromPool(ctx, "residential", "a"); err != nil {
+			t.Fatalf("RemoveFromPool() error = %v", err)
+		}
+		if err := pools.RemoveFromPool(ctx, "residential", "a"); !errors.Is(err, repository.ErrProxyNotFound) {
+			t.Errorf("RemoveFromPool() error = %v, want %v", err, repository.ErrProxyNotFound)
+		}
+
+		got, err := pools.GetPoolMembers(ctx, "residential")
+		if err != nil {
+			t.Fatalf("GetPoolMembers() error = %v", err)
+		}
+		if want := []string{"b", "c"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GetPoolMembers() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Unknown pool", func(t *testing.T) {
+		if err := pools.AddToPool(ctx, "mobile", "a"); !errors.Is(err, repository.ErrPoolNotFound) {
+			t.Errorf("AddToPool() error = %v, want %v", err, repository.ErrPoolNotFound)
+		}
+		if _, err := pools.GetPoolMembers(ctx, "mobile"); !errors.Is(err, repository.ErrPoolNotFound) {
+			t.Errorf("GetPoolMembers() error = %v, want %v", err, repository.ErrPoolNotFound)
+		}
+		if err := pools.DeletePool(ctx, "mobile"); !errors.Is(err, repository.ErrPoolNotFound) {
+			t.Errorf("DeletePool() error = %v, want %v", err, repository.ErrPoolNotFound)
+		}
+	})
+
+	t.Run("Delete pool", func(t *testing.T) {
+		if err := pools.DeletePool(ctx, "residential"); err != nil {
+			t.Fatalf("DeletePool() error = %v", err)
+		}
+		if _, err := pools.GetPoolMembers(ctx, "residential"); !errors.Is(err, repository.ErrPoolNotFound) {
+			t.Errorf("GetPoolMembers() error = %v, want %v", err, repository.ErrPoolNotFound)
+		}
+	})
+}
+
+func TestMemoryRepositoryDeleteRemovesPoolMembership(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+
+	pools, ok := repo.(domain.PoolRepository)
+	if !ok {
+		t.Fatal("memory repository does not implement domain.PoolRepository")
+	}
+
+	proxy := &domain.Proxy{ID: "p1", URL: "http://example.com:8080", Type: domain.HTTP, Enabled: true}
+	if err := repo.Create(ctx, proxy); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := pools.CreatePool(ctx, "datacenter"); err != nil {
+		t.Fatalf("CreatePool() error = %v", err)
+	}
+	if err := pools.AddToPool(ctx, "datacenter", proxy.ID); err != nil {
+		t.Fatalf("AddToPool() error = %v", err)
+	}
+
+	if err := repo.Delete(ctx, proxy.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	members, err := pools.GetPoolMembers(ctx, "datacenter")
+	if err != nil {
+		t.Fatalf("GetPoolMembers() error = %v", err)
+	}
+	if len(members) != 0 {
+		t.Errorf("GetPoolMembers() = %v, want no members after delete", members)
+	}
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+)
+
+// memorySessionStore keeps session affinity in memory
+type memorySessionStore struct {
+	sessions map[string]domain.Session
+	mu       sync.RWMutex
+}
+
+func newMemorySessionStore() *memorySessionStore {
+	return &memorySessionStore{
+		sessions: make(map[string]domain.Session),
+	}
+}
+
+// NewMemorySessionRepository creates an in-memory session repository.
+// It can be paired with any ProxyRepository that lacks session support.
+func NewMemorySessionRepository() domain.SessionRepository {
+	return newMemorySessionStore()
+}
+
+// GetSession implements SessionRepository.GetSession
+func (s *memorySessionStore) GetSession(ctx context.Context, key string) (*domain.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[key]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+// SaveSession implements SessionRepository.SaveSession
+func (s *memorySessionStore) SaveSession(ctx context.Context, session *domain.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session.Key] = *session
+	return nil
+}
+
+// DeleteSession implements SessionRepository.DeleteSession
+func (s *memorySessionStore) DeleteSession(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.sessions[key]; !exists {
+		return ErrSessionNotFound
+	}
+	delete(s.sessions, key)
+	return nil
+}
+
+// DeleteExpiredSessions implements SessionRepository.DeleteExpiredSessions
+func (s *memorySessionStore) DeleteExpiredSessions(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, session := range s.sessions {
+		if session.Expired(before) {
+			delete(s.sessions, key)
+		}
+	}
+	return nil
+}
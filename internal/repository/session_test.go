@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/repository"
+)
+
+func TestMemorySessionRepository(t *testing.T) {
+	ctx := context.Background()
+	sessions := repository.NewMemorySessionRepository()
+	now := time.Now()
+
+	if _, err := sessions.GetSession(ctx, "missing"); !errors.Is(err, repository.ErrSessionNotFound) {
+		t.Errorf("GetSession() error = %v, want %v", err, repository.ErrSessionNotFound)
+	}
+
+	for key, expires := range map[string]time.Time{"old": now.Add(-time.Minute), "live": now.Add(time.Minute)} {
+		if err := sessions.SaveSession(ctx, &domain.Session{Key: key, ProxyID: "p1", ExpiresAt: expires}); err != nil {
+			t.Fatalf("SaveSession(%q) error = %v", key, err)
+		}
+	}
+
+	// Saving again replaces the session
+	if err := sessions.SaveSession(ctx, &domain.Session{Key: "live", ProxyID: "p2", ExpiresAt: now.Add(time.Minute)}); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	if session, err := sessions.GetSession(ctx, "live"); err != nil || session.ProxyID != "p2" {
+		t.Errorf("GetSession() = %+v, %v; want proxy p2", session, err)
+	}
+
+	if err := sessions.DeleteExpiredSessions(ctx, now); err != nil {
+		t.Fatalf("DeleteExpiredSessions() error = %v", err)
+	}
+	if _, err := sessions.GetSession(ctx, "old"); !errors.Is(err, repository.ErrSessionNotFound) {
+		t.Errorf("expired session survived DeleteExpiredSessions: %v", err)
+	}
+
+	if err := sessions.DeleteSession(ctx, "live"); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+	if err := sessions.DeleteSession(ctx, "live"); !errors.Is(err, repository.ErrSessionNotFound) {
+		t.Errorf("DeleteSession() error = %v, want %v", err, repository.ErrSessionNotFound)
+	}
+
+	// The memory proxy repository stores sessions too
+	if _, ok := repository.NewMemoryRepository().(domain.SessionRepository); !ok {
+		t.Error("memory repository does not implement SessionRepository")
+	}
+}
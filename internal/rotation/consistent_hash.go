@@ -0,0 +1,210 @@
+package rotation
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+)
+
+// Default settings for the consistent-hash strategy
+const (
+	DefaultVirtualNodes = 100
+	DefaultSubsetSize   = 2
+)
+
+// HashConfig tunes the consistent-hash strategy
+type HashConfig struct {
+	// VirtualNodes is the number of ring positions per proxy. More nodes
+	// spread hosts more evenly at the cost of a larger ring.
+	VirtualNodes int
+
+	// SubsetSize is the number of proxies each host is spread across
+	SubsetSize int
+}
+
+// withDefaults fills in unset fields
+func (c HashConfig) withDefaults() HashConfig {
+	if c.VirtualNodes <= 0 {
+		c.VirtualNodes = DefaultVirtualNodes
+	}
+	if c.SubsetSize <= 0 {
+		c.SubsetSize = DefaultSubsetSize
+	}
+	return c
+}
+
+// ringNode is one virtual node on the hash ring
+type ringNode struct {
+	hash    uint64
+	proxyID string
+}
+
+// consistentHashStrategy maps each target host to a small, stable subset of
+// proxies and spreads the host's requests across that subset. Adding or
+// removing a proxy only moves the hosts whose subset it joins or leaves.
+type consistentHashStrategy struct {
+	config HashConfig
+
+	mu   sync.Mutex
+	ring []ringNode
+
+	// members maps the proxies on the ring to the selection they were last a candidate in
+	members    map[string]uint64
+	selections uint64
+}
+
+// NewConsistentHashStrategy creates a strategy that hashes target hosts onto a ring of proxies
+func NewConsistentHashStrategy(config HashConfig) Strategy {
+	return &consistentHashStrategy{
+		config:  config.withDefaults(),
+		members: make(map[string]uint64),
+	}
+}
+
+var _ CriteriaStrategy = (*consistentHashStrategy)(nil)
+
+// Next picks a random proxy, since there is no host to hash
+func (s *consistentHashStrategy) Next(ctx context.Context, proxies []*domain.Proxy) (*domain.Proxy, error) {
+	return s.NextFor(ctx, proxies, Criteria{})
+}
+
+// NextFor picks one of the proxies the target host hashes to
+func (s *consistentHashStrategy) NextFor(ctx context.Context, proxies []*domain.Proxy, criteria Criteria) (*domain.Proxy, error) {
+	switch len(proxies) {
+	case 0:
+		return nil, ErrNoProxiesAvailable
+	case 1:
+		return proxies[0], nil
+	}
+
+	if criteria.TargetHost == "" {
+		i, err := randomIndex(len(proxies))
+		if err != nil {
+			return nil, err
+		}
+		return proxies[i], nil
+	}
+
+	subset := s.subset(proxies, criteria.TargetHost)
+
+	i, err := randomIndex(len(subset))
+	if err != nil {
+		return nil, err
+	}
+	return subset[i], nil
+}
+
+// subset walks the ring clockwise from the host's hash and collects the
+// first SubsetSize distinct proxies among the candidates
+func (s *consistentHashStrategy) subset(proxies []*domain.Proxy, host string) []*domain.Proxy {
+	candidates := make(map[string]*domain.Proxy, len(proxies))
+	for _, proxy := range proxies {
+		candidates[proxy.ID] = proxy
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sync(candidates)
+
+	size := min(s.config.SubsetSize, len(candidates))
+	subset := make([]*domain.Proxy, 0, size)
+
+	hash := hashKey(host)
+	start := sort.Search(len(s.ring), func(i int) bool { return s.ring[i].hash >= hash })
+	for i := 0; i < len(s.ring) && len(subset) < size; i++ {
+		node := s.ring[(start+i)%len(s.ring)]
+		proxy, ok := candidates[node.proxyID]
+		if !ok || containsProxy(subset, proxy) {
+			continue
+		}
+		subset = append(subset, proxy)
+	}
+	return subset
+}
+
+// sync adds new candidates to the ring. Proxies missing from the candidates
+// stay on the ring and are skipped during the walk, and are only removed once
+// they haven't been a candidate for staleAfter selections. Ring positions
+// depend only on the proxy ID, so removing stale proxies never moves a host.
+func (s *consistentHashStrategy) sync(candidates map[string]*domain.Proxy) {
+	s.selections++
+
+	added := false
+	for id := range candidates {
+		_, known := s.members[id]
+		s.members[id] = s.selections
+		if known {
+			continue
+		}
+		for v := 0; v < s.config.VirtualNodes; v++ {
+			s.ring = append(s.ring, ringNode{hash: hashKey(id + "#" + strconv.Itoa(v)), proxyID: id})
+		}
+		added = true
+	}
+
+	if added {
+		sort.Slice(s.ring, func(i, j int) bool {
+			if s.ring[i].hash != s.ring[j].hash {
+				return s.ring[i].hash < s.ring[j].hash
+			}
+			return s.ring[i].proxyID < s.ring[j].proxyID
+		})
+	}
+
+	if s.selections%staleAfter == 0 {
+		s.prune()
+	}
+}
+
+// prune takes proxies that haven't been a candidate for staleAfter
+// selections off the ring, keeping the remaining nodes in order
+func (s *consistentHashStrategy) prune() {
+	stale := false
+	for id, seen := range s.members {
+		if s.selections-seen >= staleAfter {
+			delete(s.members, id)
+			stale = true
+		}
+	}
+	if !stale {
+		return
+	}
+
+	ring := s.ring[:0]
+	for _, node := range s.ring {
+		if _, ok := s.members[node.proxyID]; ok {
+			ring = append(ring, node)
+		}
+	}
+	s.ring = ring
+}
+
+// hashKey hashes a string onto the ring
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+
+	// FNV clusters similar keys; a finalizer spreads them around the ring
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// containsProxy reports whether proxy is already in proxies
+func containsProxy(proxies []*domain.Proxy, proxy *domain.Proxy) bool {
+	for _, p := range proxies {
+		if p == proxy {
+			return true
+		}
+	}
+	return false
+}
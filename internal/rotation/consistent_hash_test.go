@@ -0,0 +1,139 @@
+package rotation_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/rotation"
+)
+
+func TestConsistentHashStrategy(t *testing.T) {
+	ctx := context.Background()
+
+	newProxies := func(n int) []*domain.Proxy {
+		proxies := make([]*domain.Proxy, n)
+		for i := range proxies {
+			proxies[i] = &domain.Proxy{ID: fmt.Sprintf("proxy-%d", i)}
+		}
+		return proxies
+	}
+
+	nextFor := func(t *testing.T, s rotation.Strategy, proxies []*domain.Proxy, host string) string {
+		t.Helper()
+		cs, ok := s.(rotation.CriteriaStrategy)
+		if !ok {
+			t.Fatal("consistent-hash strategy does not implement CriteriaStrategy")
+		}
+		proxy, err := cs.NextFor(ctx, proxies, rotation.Criteria{TargetHost: host})
+		if err != nil {
+			t.Fatalf("NextFor failed: %v", err)
+		}
+		return proxy.ID
+	}
+
+	// assign maps every host to its proxy with a subset size of one
+	assign := func(t *testing.T, s rotation.Strategy, proxies []*domain.Proxy, hosts int) map[string]string {
+		t.Helper()
+		assignment := make(map[string]string, hosts)
+		for i := 0; i < hosts; i++ {
+			host := fmt.Sprintf("site-%d.example.com", i)
+			assignment[host] = nextFor(t, s, proxies, host)
+		}
+		return assignment
+	}
+
+	t.Run("Hosts stay within their subset", func(t *testing.T) {
+		proxies := newProxies(10)
+		s := rotation.NewConsistentHashStrategy(rotation.HashConfig{SubsetSize: 2})
+		other := rotation.NewConsistentHashStrategy(rotation.HashConfig{SubsetSize: 2})
+
+		seen := map[string]bool{}
+		for i := 0; i < 100; i++ {
+			seen[nextFor(t, s, proxies, "shop.example.com")] = true
+			seen[nextFor(t, other, proxies, "shop.example.com")] = true
+		}
+		if len(seen) != 2 {
+			t.Errorf("host used %d proxies, want a subset of 2: %v", len(seen), seen)
+		}
+	})
+
+	t.Run("Hosts spread across proxies", func(t *testing.T) {
+		proxies := newProxies(10)
+		s := rotation.NewConsistentHashStrategy(rotation.HashConfig{SubsetSize: 1})
+
+		load := map[string]int{}
+		for _, id := range assign(t, s, proxies, 2000) {
+			load[id]++
+		}
+		for _, proxy := range proxies {
+			if n := load[proxy.ID]; n < 100 || n > 400 {
+				t.Errorf("%s got %d of 2000 hosts, want roughly 200", proxy.ID, n)
+			}
+		}
+	})
+
+	t.Run("Adding a proxy remaps few hosts", func(t *testing.T) {
+		proxies := newProxies(11)
+		s := rotation.NewConsistentHashStrategy(rotation.HashConfig{SubsetSize: 1})
+
+		before := assign(t, s, proxies[:10], 2000)
+		after := assign(t, s, proxies, 2000)
+
+		moved := 0
+		for host, id := range after {
+			if id == before[host] {
+				continue
+			}
+			moved++
+			if id != "proxy-10" {
+				t.Fatalf("%s moved from %s to %s, want it to move only to the new proxy", host, before[host], id)
+			}
+		}
+		if moved == 0 || moved > 400 {
+			t.Errorf("%d of 2000 hosts moved, want about 1/11", moved)
+		}
+	})
+
+	t.Run("Removing a proxy only remaps its hosts", func(t *testing.T) {
+		proxies := newProxies(10)
+		s := rotation.NewConsistentHashStrategy(rotation.HashConfig{SubsetSize: 1})
+
+		before := assign(t, s, proxies, 2000)
+		after := assign(t, s, proxies[1:], 2000)
+
+		for host, id := range after {
+			if id != before[host] && before[host] != "proxy-0" {
+				t.Fatalf("%s moved from %s to %s although its proxy is still there", host, before[host], id)
+			}
+		}
+	})
+
+	t.Run("Filtered candidates don't remap hosts", func(t *testing.T) {
+		proxies := newProxies(10)
+		s := rotation.NewConsistentHashStrategy(rotation.HashConfig{SubsetSize: 1})
+		other := rotation.NewConsistentHashStrategy(rotation.HashConfig{SubsetSize: 1})
+
+		// Alternate full and filtered selections past the point where unseen proxies are forgotten
+		for i := 0; i < 1500; i++ {
+			host := fmt.Sprintf("site-%d.example.com", i%2000)
+			nextFor(t, s, proxies, host)
+			nextFor(t, s, proxies[8:], host)
+		}
+
+		got, want := assign(t, s, proxies, 2000), assign(t, other, proxies, 2000)
+		for host, id := range want {
+			if got[host] != id {
+				t.Fatalf("%s maps to %s after filtered selections, want %s", host, got[host], id)
+			}
+		}
+	})
+
+	t.Run("Without a host", func(t *testing.T) {
+		s := rotation.NewConsistentHashStrategy(rotation.HashConfig{})
+		if _, err := s.Next(ctx, newProxies(3)); err != nil {
+			t.Errorf("Next failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package rotation
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+)
+
+// Criteria describes the request a proxy is being selected for and which
+// proxies may serve it. Zero-valued fields don't restrict the selection.
+type Criteria struct {
+	// TargetHost is the host the request will be sent to, if known
+	TargetHost string
+
+	// CountryCode limits selection to proxies in a country, compared case-insensitively
+	CountryCode string
+
+	// Type limits selection to one proxy type
+	Type domain.ProxyType
+
+	// Tags limits selection to proxies carrying every listed tag
+	Tags []string
+
+	// MinSuccessRate skips proxies whose success rate is below it.
+	// Proxies that haven't been used yet have no success rate and pass.
+	MinSuccessRate float64
+
+	// MaxLatency skips proxies whose last measured latency is above it.
+	// Proxies that haven't been measured yet pass.
+	MaxLatency time.Duration
+
+	// ExcludeIDs lists proxies that must not be chosen
+	ExcludeIDs []string
+}
+
+// CriteriaStrategy is implemented by strategies that take the request into account.
+// Callers that know the request use NextFor instead of Next.
+type CriteriaStrategy interface {
+	Strategy
+	NextFor(ctx context.Context, proxies []*domain.Proxy, criteria Criteria) (*domain.Proxy, error)
+}
+
+// Matches reports whether a proxy satisfies the criteria's filters
+func (c Criteria) Matches(proxy *domain.Proxy) bool {
+	if slices.Contains(c.ExcludeIDs, proxy.ID) {
+		return false
+	}
+	if c.CountryCode != "" && !strings.EqualFold(proxy.CountryCode, c.CountryCode) {
+		return false
+	}
+	if c.Type != "" && proxy.Type != c.Type {
+		return false
+	}
+	if !proxy.HasTags(c.Tags...) {
+		return false
+	}
+	if c.MinSuccessRate > 0 && proxy.SuccessRate < c.MinSuccessRate {
+		// A new proxy has neither a success rate nor any usage
+		if proxy.SuccessRate > 0 || proxy.UsageCount > 0 {
+			return false
+		}
+	}
+	if c.MaxLatency > 0 && proxy.Latency > 0 && time.Duration(proxy.Latency)*time.Millisecond > c.MaxLatency {
+		return false
+	}
+	return true
+}
+
+// Filter returns the proxies that match the criteria
+func (c Criteria) Filter(proxies []*domain.Proxy) []*domain.Proxy {
+	var matched []*domain.Proxy
+	for _, proxy := range proxies {
+		if c.Matches(proxy) {
+			matched = append(matched, proxy)
+		}
+	}
+	return matched
+}
@@ -6,7 +6,16 @@
 // - Random: Select proxies at random with equal probability
 // - Weighted: Select proxies based on their assigned weights
 // - LeastUsed: Prioritize proxies with lower usage counts
+// - SmoothWeighted: Interleave proxies deterministically in proportion to their weights
+// - Latency: Prefer proxies with low observed latency and error rates
+// - ConsistentHash: Map each target host to a small, stable set of proxies
+// - Thompson: Sample each proxy's success posterior, optionally per target host
 //
 // All strategies implement the Strategy interface, which provides
-// a consistent API for proxy selection.
+// a consistent API for proxy selection. Strategies that also implement
+// Observer are fed the outcome of every request, and those implementing
+// CriteriaStrategy are told about the request being made.
+//
+// Custom strategies are added with Register and created through NewStrategy
+// like the built-in ones.
 package rotation
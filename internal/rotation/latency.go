@@ -0,0 +1,207 @@
+package rotation
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+)
+
+// Default settings for the latency strategy
+const (
+	DefaultLatencySmoothing = 0.3
+	DefaultLatencyDecayTime = time.Minute * 5
+	DefaultErrorPenalty     = 4.0
+)
+
+// latencySweepEvery is how many observations pass between sweeps for stats
+// that have all but decayed away
+const latencySweepEvery = 1024
+
+// Feedback describes the outcome of a request made through a proxy
+type Feedback struct {
+	ProxyID string
+	Host    string
+	Latency time.Duration
+	Success bool
+}
+
+// Observer is implemented by strategies that learn from request outcomes
+type Observer interface {
+	Observe(feedback Feedback)
+}
+
+// LatencyConfig tunes the latency strategy
+type LatencyConfig struct {
+	// Smoothing is the weight of each new sample in the moving averages, between 0 and 1
+	Smoothing float64
+
+	// DecayTime is how quickly old measurements lose their influence. After
+	// DecayTime without feedback a proxy's stats count for about a third.
+	DecayTime time.Duration
+
+	// ErrorPenalty scales how much the error rate inflates a proxy's score.
+	// With the default, a proxy failing half its requests scores like one
+	// three times as slow.
+	ErrorPenalty float64
+}
+
+// withDefaults fills in unset fields
+func (c LatencyConfig) withDefaults() LatencyConfig {
+	if c.Smoothing <= 0 || c.Smoothing > 1 {
+		c.Smoothing = DefaultLatencySmoothing
+	}
+	if c.DecayTime <= 0 {
+		c.DecayTime = DefaultLatencyDecayTime
+	}
+	if c.ErrorPenalty <= 0 {
+		c.ErrorPenalty = DefaultErrorPenalty
+	}
+	return c
+}
+
+// latencyStats holds the moving averages for one proxy
+type latencyStats struct {
+	latency   float64 // milliseconds
+	errorRate float64
+	updated   time.Time
+}
+
+// latencyStrategy prefers proxies with a low moving average of latency and
+// errors. It samples two candidates and takes the better one, so a single
+// fast proxy is not sent every request.
+type latencyStrategy struct {
+	config LatencyConfig
+
+	mu    sync.Mutex
+	stats map[string]*latencyStats
+	now   func() time.Time
+
+	observations uint64
+}
+
+// NewLatencyStrategy creates a latency-aware strategy
+func NewLatencyStrategy(config LatencyConfig) Strategy {
+	return &latencyStrategy{
+		config: config.withDefaults(),
+		stats:  make(map[string]*latencyStats),
+		now:    time.Now,
+	}
+}
+
+var _ Observer = (*latencyStrategy)(nil)
+
+// Next picks the better of two randomly chosen proxies
+func (s *latencyStrategy) Next(ctx context.Context, proxies []*domain.Proxy) (*domain.Proxy, error) {
+	switch len(proxies) {
+	case 0:
+		return nil, ErrNoProxiesAvailable
+	case 1:
+		return proxies[0], nil
+	}
+
+	i, err := randomIndex(len(proxies))
+	if err != nil {
+		return nil, err
+	}
+	j, err := randomIndex(len(proxies) - 1)
+	if err != nil {
+		return nil, err
+	}
+	if j >= i {
+		j++
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.score(proxies[j], now) < s.score(proxies[i], now) {
+		return proxies[j], nil
+	}
+	return proxies[i], nil
+}
+
+// Observe folds a request outcome into the proxy's moving averages
+func (s *latencyStrategy) Observe(feedback Feedback) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	sample := float64(feedback.Latency) / float64(time.Millisecond)
+	failed := 0.0
+	if !feedback.Success {
+		failed = 1
+	}
+
+	s.observations++
+	if s.observations%latencySweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	stats, ok := s.stats[feedback.ProxyID]
+	if !ok {
+		s.stats[feedback.ProxyID] = &latencyStats{latency: sample, errorRate: failed, updated: now}
+		return
+	}
+
+	// A proxy that fails fast must not look faster for it
+	if !feedback.Success {
+		sample = max(sample, stats.latency)
+	}
+
+	// Stale averages give way to the new sample faster
+	alpha := max(s.config.Smoothing, 1-s.freshness(stats, now))
+	if feedback.Latency > 0 {
+		stats.latency += alpha * (sample - stats.latency)
+	}
+	stats.errorRate += alpha * (failed - stats.errorRate)
+	stats.updated = now
+}
+
+// score ranks a proxy; lower is better. Measurements fade as they age, so
+// proxies that have not been tried for a while get picked again eventually.
+// Proxies without feedback fall back to their stored latency.
+func (s *latencyStrategy) score(proxy *domain.Proxy, now time.Time) float64 {
+	stats, ok := s.stats[proxy.ID]
+	if !ok {
+		return float64(proxy.Latency)
+	}
+
+	freshness := s.freshness(stats, now)
+	return stats.latency * freshness * (1 + s.config.ErrorPenalty*stats.errorRate*freshness)
+}
+
+// freshness returns how much of a proxy's stats still count, from 1 down to 0
+func (s *latencyStrategy) freshness(stats *latencyStats, now time.Time) float64 {
+	age := now.Sub(stats.updated)
+	if age <= 0 {
+		return 1
+	}
+	return math.Exp(-float64(age) / float64(s.config.DecayTime))
+}
+
+// sweep drops stats that have all but decayed away, so proxies that were
+// removed or rotated out don't stay in the map for the life of the process.
+// A proxy seen again afterwards starts over from its stored latency.
+func (s *latencyStrategy) sweep(now time.Time) {
+	for id, stats := range s.stats {
+		if s.freshness(stats, now) < 0.001 {
+			delete(s.stats, id)
+		}
+	}
+}
+
+// randomIndex returns a uniformly random index below n
+func randomIndex(n int) (int, error) {
+	nBig, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate random number: %w", err)
+	}
+	return int(nBig.Int64()), nil
+}
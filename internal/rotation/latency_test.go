@@ -0,0 +1,131 @@
+package rotation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/rotation"
+)
+
+func TestLatencyStrategy(t *testing.T) {
+	ctx := context.Background()
+
+	newStrategy := func(config rotation.LatencyConfig) (rotation.Strategy, rotation.Observer) {
+		s := rotation.NewLatencyStrategy(config)
+		observer, ok := s.(rotation.Observer)
+		if !ok {
+			t.Fatal("latency strategy does not implement Observer")
+		}
+		return s, observer
+	}
+
+	selections := func(t *testing.T, s rotation.Strategy, proxies []*domain.Proxy, n int) map[string]int {
+		t.Helper()
+		counts := map[string]int{}
+		for i := 0; i < n; i++ {
+			proxy, err := s.Next(ctx, proxies)
+			if err != nil {
+				t.Fatalf("Next failed: %v", err)
+			}
+			counts[proxy.ID]++
+		}
+		return counts
+	}
+
+	t.Run("Prefers low latency", func(t *testing.T) {
+		s, observer := newStrategy(rotation.LatencyConfig{})
+		proxies := []*domain.Proxy{{ID: "fast"}, {ID: "slow"}}
+
+		observer.Observe(rotation.Feedback{ProxyID: "fast", Latency: 10 * time.Millisecond, Success: true})
+		observer.Observe(rotation.Feedback{ProxyID: "slow", Latency: 500 * time.Millisecond, Success: true})
+
+		if counts := selections(t, s, proxies, 50); counts["fast"] != 50 {
+			t.Errorf("selections = %v, want every request on the fast proxy", counts)
+		}
+	})
+
+	t.Run("Spreads load beyond the fastest proxy", func(t *testing.T) {
+		s, observer := newStrategy(rotation.LatencyConfig{})
+		proxies := []*domain.Proxy{{ID: "fast"}, {ID: "medium"}, {ID: "slow"}}
+
+		for id, latency := range map[string]time.Duration{"fast": 10, "medium": 20, "slow": 30} {
+			observer.Observe(rotation.Feedback{ProxyID: id, Latency: latency * time.Millisecond, Success: true})
+		}
+
+		counts := selections(t, s, proxies, 300)
+		if counts["slow"] != 0 {
+			t.Errorf("slowest proxy picked %d times, want 0 with two choices", counts["slow"])
+		}
+		if counts["medium"] == 0 || counts["fast"] <= counts["medium"] {
+			t.Errorf("selections = %v, want most on fast and some on medium", counts)
+		}
+	})
+
+	t.Run("Errors outweigh latency", func(t *testing.T) {
+		s, observer := newStrategy(rotation.LatencyConfig{})
+		proxies := []*domain.Proxy{{ID: "failing"}, {ID: "healthy"}}
+
+		observer.Observe(rotation.Feedback{ProxyID: "failing", Latency: 100 * time.Millisecond, Success: true})
+		observer.Observe(rotation.Feedback{ProxyID: "healthy", Latency: 200 * time.Millisecond, Success: true})
+		for i := 0; i < 5; i++ {
+			// Failing fast must not make the proxy look better
+			observer.Observe(rotation.Feedback{ProxyID: "failing", Latency: time.Millisecond, Success: false})
+		}
+
+		if counts := selections(t, s, proxies, 20); counts["healthy"] != 20 {
+			t.Errorf("selections = %v, want every request on the healthy proxy", counts)
+		}
+	})
+
+	t.Run("Unmeasured proxies use their stored latency", func(t *testing.T) {
+		s, observer := newStrategy(rotation.LatencyConfig{})
+		proxies := []*domain.Proxy{{ID: "known"}, {ID: "new", Latency: 1000}}
+
+		observer.Observe(rotation.Feedback{ProxyID: "known", Latency: 50 * time.Millisecond, Success: true})
+
+		if counts := selections(t, s, proxies, 20); counts["known"] != 20 {
+			t.Errorf("selections = %v, want every request on the known proxy", counts)
+		}
+	})
+
+	t.Run("Stale measurements decay", func(t *testing.T) {
+		s, observer := newStrategy(rotation.LatencyConfig{DecayTime: 10 * time.Millisecond})
+		proxies := []*domain.Proxy{{ID: "stale"}, {ID: "recent"}}
+
+		observer.Observe(rotation.Feedback{ProxyID: "stale", Latency: 500 * time.Millisecond, Success: false})
+		time.Sleep(100 * time.Millisecond)
+		observer.Observe(rotation.Feedback{ProxyID: "recent", Latency: 50 * time.Millisecond, Success: true})
+
+		proxy, err := s.Next(ctx, proxies)
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if proxy.ID != "stale" {
+			t.Errorf("Next() = %s, want the stale proxy to be retried", proxy.ID)
+		}
+	})
+
+	t.Run("Forgets removed proxies", func(t *testing.T) {
+		s, observer := newStrategy(rotation.LatencyConfig{DecayTime: time.Millisecond})
+
+		observer.Observe(rotation.Feedback{ProxyID: "removed", Latency: 5 * time.Millisecond, Success: true})
+		time.Sleep(50 * time.Millisecond)
+
+		// Enough traffic through the remaining proxy to trigger a sweep
+		for i := 0; i < 1024; i++ {
+			observer.Observe(rotation.Feedback{ProxyID: "remaining", Latency: 50 * time.Millisecond, Success: true})
+		}
+
+		// Decayed stats would score near zero; forgotten ones fall back to the stored latency
+		proxies := []*domain.Proxy{{ID: "removed", Latency: 1000}, {ID: "remaining"}}
+		proxy, err := s.Next(ctx, proxies)
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if proxy.ID != "remaining" {
+			t.Errorf("Next() = %s, want the removed proxy's stats to have been dropped", proxy.ID)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package rotation
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Factory creates a new instance of a strategy. It is called once for the
+// rotator and once for every pool, so each gets its own state.
+type Factory func() Strategy
+
+// ErrStrategyExists is returned when registering a name that is already taken
+var ErrStrategyExists = errors.New("rotation strategy already registered")
+
+// registry holds the strategies added with Register
+var registry = struct {
+	sync.RWMutex
+	factories map[StrategyType]Factory
+}{factories: make(map[StrategyType]Factory)}
+
+// Register makes a custom strategy available to NewStrategy under name.
+// Built-in names and names registered before can't be replaced.
+func Register(name StrategyType, factory Factory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("%w: a name and factory are required", ErrInvalidStrategy)
+	}
+	if builtinStrategy(name) != nil {
+		return fmt.Errorf("%w: %s", ErrStrategyExists, name)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.factories[name]; ok {
+		return fmt.Errorf("%w: %s", ErrStrategyExists, name)
+	}
+	registry.factories[name] = factory
+	return nil
+}
+
+// Registered reports whether name is a built-in or registered strategy
+func Registered(name StrategyType) bool {
+	if builtinStrategy(name) != nil {
+		return true
+	}
+	_, ok := lookup(name)
+	return ok
+}
+
+// lookup returns the factory registered under name
+func lookup(name StrategyType) (Factory, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	factory, ok := registry.factories[name]
+	return factory, ok
+}
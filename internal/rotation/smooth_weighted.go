@@ -0,0 +1,96 @@
+package rotation
+
+import (
+	"context"
+	"sync"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+)
+
+// smoothWeightedStrategy implements nginx's smooth weighted round-robin. Each
+// selection adds every candidate's weight to its running total, picks the
+// highest total and takes the sum of the weights off the winner. Over any
+// window of sum(weights) selections each proxy is picked exactly in proportion
+// to its weight, with the picks spread out rather than bunched together.
+type smoothWeightedStrategy struct {
+	mu      sync.Mutex
+	current map[string]*smoothTotal
+
+	// selections counts calls with more than one candidate
+	selections uint64
+}
+
+// smoothTotal is a proxy's running total and the selection it was last a candidate in
+type smoothTotal struct {
+	current int64
+	seen    uint64
+}
+
+// NewSmoothWeightedStrategy creates a deterministic weighted round-robin strategy
+func NewSmoothWeightedStrategy() Strategy {
+	return &smoothWeightedStrategy{current: make(map[string]*smoothTotal)}
+}
+
+// Next selects the proxy with the highest running total. Totals are kept per
+// proxy ID, so proxies joining, leaving or being skipped for a request don't
+// disturb the rotation of the others.
+func (s *smoothWeightedStrategy) Next(ctx context.Context, proxies []*domain.Proxy) (*domain.Proxy, error) {
+	switch len(proxies) {
+	case 0:
+		return nil, ErrNoProxiesAvailable
+	case 1:
+		return proxies[0], nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.selections++
+
+	var best *domain.Proxy
+	var bestTotal *smoothTotal
+	var total int64
+	for _, proxy := range proxies {
+		t, ok := s.current[proxy.ID]
+		if !ok {
+			t = &smoothTotal{}
+			s.current[proxy.ID] = t
+		}
+		t.seen = s.selections
+
+		weight := smoothWeight(proxy)
+		total += weight
+		t.current += weight
+
+		// Ties go to the lowest ID so the order doesn't depend on the slice
+		if best == nil || t.current > bestTotal.current ||
+			(t.current == bestTotal.current && proxy.ID < best.ID) {
+			best, bestTotal = proxy, t
+		}
+	}
+
+	bestTotal.current -= total
+
+	if s.selections%staleAfter == 0 {
+		s.prune()
+	}
+	return best, nil
+}
+
+// prune forgets proxies that haven't been a candidate for staleAfter
+// selections, so a pool that churns through proxies doesn't grow the map forever
+func (s *smoothWeightedStrategy) prune() {
+	for id, t := range s.current {
+		if s.selections-t.seen >= staleAfter {
+			delete(s.current, id)
+		}
+	}
+}
+
+// smoothWeight returns a proxy's weight, counting unset weights as one
+func smoothWeight(proxy *domain.Proxy) int64 {
+	if proxy.Weight <= 0 {
+		return 1
+	}
+	return int64(proxy.Weight)
+}
@@ -0,0 +1,101 @@
+package rotation_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/rotation"
+)
+
+func TestSmoothWeightedStrategy(t *testing.T) {
+	ctx := context.Background()
+
+	sequence := func(t *testing.T, s rotation.Strategy, proxies []*domain.Proxy, n int) string {
+		t.Helper()
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			proxy, err := s.Next(ctx, proxies)
+			if err != nil {
+				t.Fatalf("Next failed: %v", err)
+			}
+			b.WriteString(proxy.ID)
+		}
+		return b.String()
+	}
+
+	t.Run("Interleaves by weight", func(t *testing.T) {
+		s := rotation.NewSmoothWeightedStrategy()
+		proxies := []*domain.Proxy{{ID: "a", Weight: 5}, {ID: "b", Weight: 1}, {ID: "c", Weight: 1}}
+
+		if got := sequence(t, s, proxies, 14); got != "aabacaaaabacaa" {
+			t.Errorf("sequence = %s, want aabacaaaabacaa", got)
+		}
+	})
+
+	t.Run("Exact proportions per cycle", func(t *testing.T) {
+		s := rotation.NewSmoothWeightedStrategy()
+		proxies := []*domain.Proxy{{ID: "a", Weight: 3}, {ID: "b", Weight: 2}, {ID: "c"}}
+
+		for cycle := 0; cycle < 5; cycle++ {
+			got := sequence(t, s, proxies, 6)
+			if strings.Count(got, "a") != 3 || strings.Count(got, "b") != 2 || strings.Count(got, "c") != 1 {
+				t.Errorf("cycle %d = %s, want a three times, b twice and c once", cycle, got)
+			}
+		}
+	})
+
+	t.Run("Independent of slice order", func(t *testing.T) {
+		forward := rotation.NewSmoothWeightedStrategy()
+		reverse := rotation.NewSmoothWeightedStrategy()
+		proxies := []*domain.Proxy{{ID: "a", Weight: 2}, {ID: "b", Weight: 2}, {ID: "c", Weight: 1}}
+		reversed := []*domain.Proxy{proxies[2], proxies[1], proxies[0]}
+
+		if got, want := sequence(t, reverse, reversed, 10), sequence(t, forward, proxies, 10); got != want {
+			t.Errorf("reversed sequence = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("Stable across pool changes", func(t *testing.T) {
+		s := rotation.NewSmoothWeightedStrategy()
+		a, b, c := &domain.Proxy{ID: "a", Weight: 2}, &domain.Proxy{ID: "b", Weight: 1}, &domain.Proxy{ID: "c", Weight: 1}
+
+		sequence(t, s, []*domain.Proxy{a, b, c}, 3)
+		// c is skipped for a while, then returns
+		sequence(t, s, []*domain.Proxy{a, b}, 7)
+
+		got := sequence(t, s, []*domain.Proxy{a, b, c}, 40)
+		if strings.Count(got, "a") != 20 || strings.Count(got, "b") != 10 || strings.Count(got, "c") != 10 {
+			t.Errorf("sequence = %s, want a:b:c at 2:1:1", got)
+		}
+	})
+
+	t.Run("Proportional with filtered candidates", func(t *testing.T) {
+		s := rotation.NewSmoothWeightedStrategy()
+		proxies := []*domain.Proxy{
+			{ID: "a", Weight: 5}, {ID: "b", Weight: 1}, {ID: "c", Weight: 1}, {ID: "d", Weight: 1}, {ID: "e", Weight: 1},
+		}
+
+		// Criteria, bans and breakers narrow the candidates for some requests
+		var full strings.Builder
+		for i := 0; i < 900; i++ {
+			full.WriteString(sequence(t, s, proxies, 1))
+			sequence(t, s, proxies[3:], 1)
+		}
+
+		got := full.String()
+		for id, want := range map[string]int{"a": 500, "b": 100, "c": 100, "d": 100, "e": 100} {
+			if n := strings.Count(got, id); n < want-2 || n > want+2 {
+				t.Errorf("%s picked %d times in full selections, want about %d", id, n, want)
+			}
+		}
+	})
+
+	t.Run("Empty pool", func(t *testing.T) {
+		s := rotation.NewSmoothWeightedStrategy()
+		if _, err := s.Next(ctx, nil); err != rotation.ErrNoProxiesAvailable {
+			t.Errorf("Next() error = %v, want %v", err, rotation.ErrNoProxiesAvailable)
+		}
+	})
+}
@@ -17,10 +17,15 @@ type StrategyType string
 
 // Available rotation strategies with consistent naming
 const (
-	RoundRobinStrategy StrategyType = "round-robin"
-	RandomStrategy     StrategyType = "random"
-	WeightedStrategy   StrategyType = "weighted"
-	LeastUsedStrategy  StrategyType = "least-used"
+	RoundRobinStrategy     StrategyType = "round-robin"
+	RandomStrategy         StrategyType = "random"
+	WeightedStrategy       StrategyType = "weighted"
+	LeastUsedStrategy      StrategyType = "least-used"
+	LatencyStrategy        StrategyType = "latency"
+	HashStrategy           StrategyType = "consistent-hash"
+	ThompsonStrategy       StrategyType = "thompson"
+	ThompsonHostStrategy   StrategyType = "thompson-per-host"
+	SmoothWeightedStrategy StrategyType = "smooth-weighted"
 )
 
 // Deprecated: Legacy constants for backward compatibility
@@ -38,24 +43,56 @@ var (
 	ErrInvalidStrategy    = errors.New("invalid rotation strategy")
 )
 
+// staleAfter is how many selections a proxy can be missing from the
+// candidates before strategies that keep per-proxy state forget it. Candidate
+// lists are often filtered, so absence alone doesn't mean a proxy is gone.
+const staleAfter = 1000
+
 // Strategy defines the interface for proxy rotation strategies
 type Strategy interface {
 	Next(ctx context.Context, proxies []*domain.Proxy) (*domain.Proxy, error)
 }
 
-// NewStrategy creates a new rotation strategy based on the provided type
+// NewStrategy creates a new rotation strategy based on the provided type.
+// Names added with Register are looked up after the built-in strategies.
 func NewStrategy(strategyType StrategyType) (Strategy, error) {
+	if strategy := builtinStrategy(strategyType); strategy != nil {
+		return strategy, nil
+	}
+
+	if factory, ok := lookup(strategyType); ok {
+		if strategy := factory(); strategy != nil {
+			return strategy, nil
+		}
+		return nil, fmt.Errorf("%w: factory for %s returned nil", ErrInvalidStrategy, strategyType)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrInvalidStrategy, strategyType)
+}
+
+// builtinStrategy creates one of the bundled strategies, or returns nil for other names
+func builtinStrategy(strategyType StrategyType) Strategy {
 	switch strategyType {
 	case RoundRobinStrategy, "RoundRobin":
-		return NewRoundRobinStrategy(), nil
+		return NewRoundRobinStrategy()
 	case RandomStrategy, "Random":
-		return &randomStrategy{}, nil
+		return &randomStrategy{}
 	case WeightedStrategy, "Weighted":
-		return &weightedStrategy{}, nil
+		return &weightedStrategy{}
 	case LeastUsedStrategy, "LeastUsed":
-		return &leastUsedStrategy{}, nil
+		return &leastUsedStrategy{}
+	case LatencyStrategy:
+		return NewLatencyStrategy(LatencyConfig{})
+	case HashStrategy:
+		return NewConsistentHashStrategy(HashConfig{})
+	case ThompsonStrategy:
+		return NewThompsonStrategy(BanditConfig{})
+	case ThompsonHostStrategy:
+		return NewThompsonStrategy(BanditConfig{PerHost: true})
+	case SmoothWeightedStrategy:
+		return NewSmoothWeightedStrategy()
 	default:
-		return nil, fmt.Errorf("%w: %s", ErrInvalidStrategy, strategyType)
+		return nil
 	}
 }
 
@@ -35,6 +35,31 @@ func TestValidRotationStrategies(t *testing.T) {
 			strategy: rotation.LeastUsedStrategy,
 			valid:    true,
 		},
+		{
+			name:     "Latency Strategy",
+			strategy: rotation.LatencyStrategy,
+			valid:    true,
+		},
+		{
+			name:     "Consistent Hash Strategy",
+			strategy: rotation.HashStrategy,
+			valid:    true,
+		},
+		{
+			name:     "Thompson Strategy",
+			strategy: rotation.ThompsonStrategy,
+			valid:    true,
+		},
+		{
+			name:     "Thompson Per-Host Strategy",
+			strategy: rotation.ThompsonHostStrategy,
+			valid:    true,
+		},
+		{
+			name:     "Smooth Weighted Strategy",
+			strategy: rotation.SmoothWeightedStrategy,
+			valid:    true,
+		},
 		{
 			name:     "Invalid Strategy",
 			strategy: rotation.StrategyType("invalid-strategy-name"),
@@ -66,6 +91,11 @@ func TestStrategyImplementations(t *testing.T) {
 		rotation.RandomStrategy,
 		rotation.WeightedStrategy,
 		rotation.LeastUsedStrategy,
+		rotation.LatencyStrategy,
+		rotation.HashStrategy,
+		rotation.ThompsonStrategy,
+		rotation.ThompsonHostStrategy,
+		rotation.SmoothWeightedStrategy,
 	}
 
 	for _, strategyType := range strategies {
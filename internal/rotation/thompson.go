@@ -0,0 +1,225 @@
+package rotation
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+)
+
+// Default settings for the Thompson-sampling strategy
+const (
+	DefaultBanditHalfLife = time.Minute * 10
+	DefaultBanditPrior    = 1.0
+)
+
+// banditSweepEvery is how many observations pass between sweeps for arms
+// whose evidence has decayed away
+const banditSweepEvery = 1024
+
+// BanditConfig tunes the Thompson-sampling strategy
+type BanditConfig struct {
+	// PerHost keeps a separate posterior for each target host, falling back
+	// to the proxy's overall posterior for hosts it hasn't served yet
+	PerHost bool
+
+	// HalfLife is how long it takes for an outcome to count half as much.
+	// Forgetting old outcomes lets recovered proxies win traffic back.
+	HalfLife time.Duration
+
+	// PriorSuccesses and PriorFailures form the Beta prior every proxy starts
+	// from. The default of one each is a uniform prior.
+	PriorSuccesses float64
+	PriorFailures  float64
+}
+
+// withDefaults fills in unset fields
+func (c BanditConfig) withDefaults() BanditConfig {
+	if c.HalfLife <= 0 {
+		c.HalfLife = DefaultBanditHalfLife
+	}
+	if c.PriorSuccesses <= 0 {
+		c.PriorSuccesses = DefaultBanditPrior
+	}
+	if c.PriorFailures <= 0 {
+		c.PriorFailures = DefaultBanditPrior
+	}
+	return c
+}
+
+// banditArm holds the decayed outcome counts for one proxy, or one proxy and host
+type banditArm struct {
+	successes float64
+	failures  float64
+	updated   time.Time
+}
+
+// thompsonStrategy treats every proxy as a bandit arm with a Beta posterior
+// over its success probability. Each selection draws from every candidate's
+// posterior and takes the highest draw, so proxies with little or stale
+// evidence keep getting the occasional request while good ones get most.
+type thompsonStrategy struct {
+	config BanditConfig
+
+	mu           sync.Mutex
+	arms         map[string]*banditArm
+	observations int
+	now          func() time.Time
+}
+
+// NewThompsonStrategy creates a Thompson-sampling strategy
+func NewThompsonStrategy(config BanditConfig) Strategy {
+	return &thompsonStrategy{
+		config: config.withDefaults(),
+		arms:   make(map[string]*banditArm),
+		now:    time.Now,
+	}
+}
+
+var (
+	_ Observer         = (*thompsonStrategy)(nil)
+	_ CriteriaStrategy = (*thompsonStrategy)(nil)
+)
+
+// Next samples the proxies' overall posteriors
+func (s *thompsonStrategy) Next(ctx context.Context, proxies []*domain.Proxy) (*domain.Proxy, error) {
+	return s.NextFor(ctx, proxies, Criteria{})
+}
+
+// NextFor samples every candidate's posterior and returns the highest draw
+func (s *thompsonStrategy) NextFor(ctx context.Context, proxies []*domain.Proxy, criteria Criteria) (*domain.Proxy, error) {
+	switch len(proxies) {
+	case 0:
+		return nil, ErrNoProxiesAvailable
+	case 1:
+		return proxies[0], nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	var best *domain.Proxy
+	bestDraw := -1.0
+	for _, proxy := range proxies {
+		successes, failures := s.evidence(proxy.ID, criteria.TargetHost, now)
+		draw := sampleBeta(s.config.PriorSuccesses+successes, s.config.PriorFailures+failures)
+		if draw > bestDraw {
+			best, bestDraw = proxy, draw
+		}
+	}
+	return best, nil
+}
+
+// Observe adds a request outcome to the proxy's posterior
+func (s *thompsonStrategy) Observe(feedback Feedback) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.record(feedback.ProxyID, feedback.Success, now)
+	if s.config.PerHost && feedback.Host != "" {
+		s.record(armKey(feedback.ProxyID, feedback.Host), feedback.Success, now)
+	}
+
+	s.observations++
+	if s.observations%banditSweepEvery == 0 {
+		s.sweep(now)
+	}
+}
+
+// evidence returns the decayed outcome counts to sample from
+func (s *thompsonStrategy) evidence(proxyID, host string, now time.Time) (successes, failures float64) {
+	if s.config.PerHost && host != "" {
+		if arm, ok := s.arms[armKey(proxyID, host)]; ok {
+			return s.decayed(arm, now)
+		}
+	}
+	if arm, ok := s.arms[proxyID]; ok {
+		return s.decayed(arm, now)
+	}
+	return 0, 0
+}
+
+// record decays an arm to now and adds one outcome
+func (s *thompsonStrategy) record(key string, success bool, now time.Time) {
+	arm, ok := s.arms[key]
+	if !ok {
+		arm = &banditArm{}
+		s.arms[key] = arm
+	}
+
+	arm.successes, arm.failures = s.decayed(arm, now)
+	arm.updated = now
+	if success {
+		arm.successes++
+	} else {
+		arm.failures++
+	}
+}
+
+// decayed returns an arm's counts discounted by their age
+func (s *thompsonStrategy) decayed(arm *banditArm, now time.Time) (successes, failures float64) {
+	age := now.Sub(arm.updated)
+	if age <= 0 {
+		return arm.successes, arm.failures
+	}
+	factor := math.Exp2(-float64(age) / float64(s.config.HalfLife))
+	return arm.successes * factor, arm.failures * factor
+}
+
+// sweep drops arms whose evidence has all but decayed away
+func (s *thompsonStrategy) sweep(now time.Time) {
+	for key, arm := range s.arms {
+		if successes, failures := s.decayed(arm, now); successes+failures < 0.01 {
+			delete(s.arms, key)
+		}
+	}
+}
+
+// armKey identifies the arm for a proxy and target host
+func armKey(proxyID, host string) string {
+	return proxyID + "|" + host
+}
+
+// sampleBeta draws from a Beta(a, b) distribution
+func sampleBeta(a, b float64) float64 {
+	x := sampleGamma(a)
+	y := sampleGamma(b)
+	if x+y == 0 {
+		return 0.5
+	}
+	return x / (x + y)
+}
+
+// sampleGamma draws from a Gamma(shape, 1) distribution using the
+// Marsaglia-Tsang method
+func sampleGamma(shape float64) float64 {
+	if shape < 1 {
+		// Boost the shape above one and scale the draw back down
+		// #nosec G404 -- sampling for load distribution, not security
+		return sampleGamma(shape+1) * math.Pow(rand.Float64(), 1/shape)
+	}
+
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		// #nosec G404 -- sampling for load distribution, not security
+		x := rand.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+
+		// #nosec G404 -- sampling for load distribution, not security
+		u := rand.Float64()
+		if u < 1-0.0331*x*x*x*x || math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package rotation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/rotation"
+)
+
+func TestThompsonStrategy(t *testing.T) {
+	ctx := context.Background()
+
+	newStrategy := func(config rotation.BanditConfig) (rotation.CriteriaStrategy, rotation.Observer) {
+		s := rotation.NewThompsonStrategy(config)
+		criteria, ok := s.(rotation.CriteriaStrategy)
+		if !ok {
+			t.Fatal("thompson strategy does not implement CriteriaStrategy")
+		}
+		observer, ok := s.(rotation.Observer)
+		if !ok {
+			t.Fatal("thompson strategy does not implement Observer")
+		}
+		return criteria, observer
+	}
+
+	selections := func(t *testing.T, s rotation.CriteriaStrategy, proxies []*domain.Proxy, host string, n int) map[string]int {
+		t.Helper()
+		counts := map[string]int{}
+		for i := 0; i < n; i++ {
+			proxy, err := s.NextFor(ctx, proxies, rotation.Criteria{TargetHost: host})
+			if err != nil {
+				t.Fatalf("NextFor failed: %v", err)
+			}
+			counts[proxy.ID]++
+		}
+		return counts
+	}
+
+	observe := func(observer rotation.Observer, id, host string, successes, failures int) {
+		for i := 0; i < successes; i++ {
+			observer.Observe(rotation.Feedback{ProxyID: id, Host: host, Success: true})
+		}
+		for i := 0; i < failures; i++ {
+			observer.Observe(rotation.Feedback{ProxyID: id, Host: host, Success: false})
+		}
+	}
+
+	t.Run("Prefers successful proxies", func(t *testing.T) {
+		s, observer := newStrategy(rotation.BanditConfig{})
+		proxies := []*domain.Proxy{{ID: "good"}, {ID: "bad"}}
+
+		observe(observer, "good", "", 50, 2)
+		observe(observer, "bad", "", 2, 50)
+
+		if counts := selections(t, s, proxies, "", 200); counts["good"] < 190 {
+			t.Errorf("selections = %v, want nearly every request on the good proxy", counts)
+		}
+	})
+
+	t.Run("Explores unproven proxies", func(t *testing.T) {
+		s, observer := newStrategy(rotation.BanditConfig{})
+		proxies := []*domain.Proxy{{ID: "known"}, {ID: "new"}}
+
+		// A middling record leaves room for a proxy nothing is known about
+		observe(observer, "known", "", 6, 4)
+
+		counts := selections(t, s, proxies, "", 500)
+		if counts["new"] < 50 || counts["known"] < 50 {
+			t.Errorf("selections = %v, want both proxies explored", counts)
+		}
+	})
+
+	t.Run("Forgets old failures", func(t *testing.T) {
+		s, observer := newStrategy(rotation.BanditConfig{HalfLife: 5 * time.Millisecond})
+		proxies := []*domain.Proxy{{ID: "recovered"}, {ID: "steady"}}
+
+		observe(observer, "recovered", "", 0, 100)
+		time.Sleep(100 * time.Millisecond)
+		observe(observer, "steady", "", 3, 3)
+
+		if counts := selections(t, s, proxies, "", 500); counts["recovered"] < 50 {
+			t.Errorf("selections = %v, want the recovered proxy tried again", counts)
+		}
+	})
+
+	t.Run("Per-host posteriors", func(t *testing.T) {
+		s, observer := newStrategy(rotation.BanditConfig{PerHost: true})
+		proxies := []*domain.Proxy{{ID: "a"}, {ID: "b"}}
+
+		observe(observer, "a", "one.example.com", 50, 0)
+		observe(observer, "a", "two.example.com", 0, 50)
+		observe(observer, "b", "one.example.com", 0, 50)
+		observe(observer, "b", "two.example.com", 50, 0)
+
+		if counts := selections(t, s, proxies, "one.example.com", 100); counts["a"] < 95 {
+			t.Errorf("one.example.com selections = %v, want proxy a", counts)
+		}
+		if counts := selections(t, s, proxies, "two.example.com", 100); counts["b"] < 95 {
+			t.Errorf("two.example.com selections = %v, want proxy b", counts)
+		}
+	})
+
+	t.Run("Shared posterior ignores hosts", func(t *testing.T) {
+		s, observer := newStrategy(rotation.BanditConfig{})
+		proxies := []*domain.Proxy{{ID: "a"}, {ID: "b"}}
+
+		observe(observer, "a", "one.example.com", 50, 0)
+		observe(observer, "b", "two.example.com", 0, 50)
+
+		if counts := selections(t, s, proxies, "two.example.com", 100); counts["a"] < 95 {
+			t.Errorf("selections = %v, want proxy a for every host", counts)
+		}
+	})
+
+	t.Run("Empty pool", func(t *testing.T) {
+		s, _ := newStrategy(rotation.BanditConfig{})
+		if _, err := s.Next(ctx, nil); err != rotation.ErrNoProxiesAvailable {
+			t.Errorf("Next() error = %v, want %v", err, rotation.ErrNoProxiesAvailable)
+		}
+	})
+}
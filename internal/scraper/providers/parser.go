@@ -2,14 +2,19 @@ package providers
 
 import (
 	"bufio"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/scraper"
 )
 
 func (p *freeProxyProvider) parseTextList(resp *http.Response) ([]*domain.Proxy, error) {
@@ -74,27 +79,374 @@ func (p *freeProxyProvider) parseProxyScrape(resp *http.Response) ([]*domain.Pro
 	return proxies, nil
 }
 
-// ParseProxyLine parses a line of text into a proxy object.
-// This exported function can be used by external packages needing to convert
-// IP:Port formatted strings to proxy objects.
+// Proxy list parsing errors. All of them match scraper.ErrInvalidFormat.
+var (
+	ErrEmptyLine         = fmt.Errorf("%w: empty line", scraper.ErrInvalidFormat)
+	ErrUnrecognized      = fmt.Errorf("%w: unrecognized proxy format", scraper.ErrInvalidFormat)
+	ErrMissingHost       = fmt.Errorf("%w: missing host", scraper.ErrInvalidFormat)
+	ErrInvalidPort       = fmt.Errorf("%w: invalid port", scraper.ErrInvalidFormat)
+	ErrUnsupportedScheme = fmt.Errorf("%w: unsupported proxy scheme", scraper.ErrInvalidFormat)
+)
+
+// ParseError describes a proxy list line that could not be parsed.
+// Text holds the original line, which may contain credentials, so it
+// is deliberately left out of the error message.
+type ParseError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+// Error implements the error interface
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseProxyLine parses a single proxy entry into a proxy object.
+// See ParseProxyList for the supported formats; entries without a
+// scheme are treated as HTTP proxies.
 func ParseProxyLine(line string) (*domain.Proxy, error) {
-	line = strings.TrimSpace(line)
+	return parseLine(line, domain.HTTPProxy)
+}
+
+// ParseProxyList parses a proxy list in the formats providers commonly ship:
+//
+//	ip:port
+//	ip:port:user:pass
+//	user:pass@ip:port
+//	scheme://[user:pass@]host:port
+//	[ipv6]:port
+//
+// Blank lines and lines starting with # or // are ignored, as is anything
+// after " #" on a line. A first line that looks like a CSV header (with
+// host/ip and port columns, or a proxy/url column) switches the parser to
+// CSV mode; username, password, type and country columns are also read.
+//
+// defaultType applies to entries without a scheme. Credentials are stored
+// in the Username and Password fields rather than in the URL. Entries are
+// deduplicated by host:port, keeping the first occurrence.
+//
+// Lines that can't be parsed are reported as *ParseError values joined
+// into the returned error; the proxies that did parse are still returned.
+func ParseProxyList(r io.Reader, defaultType domain.ProxyType) ([]*domain.Proxy, error) {
+	if defaultType == "" {
+		defaultType = domain.HTTPProxy
+	}
+
+	var (
+		proxies   = []*domain.Proxy{}
+		errs      []error
+		seen      = make(map[string]bool)
+		header    *csvHeader
+		firstLine = true
+		lineNo    int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNo++
+		raw := scanner.Text()
+
+		line := strings.TrimSpace(strings.TrimPrefix(raw, "\ufeff"))
+		if line == "" || isComment(line) {
+			continue
+		}
+
+		if firstLine {
+			firstLine = false
+			if h, ok := parseCSVHeader(line); ok {
+				header = h
+				continue
+			}
+		}
+
+		var (
+			proxy *domain.Proxy
+			err   error
+		)
+		if header != nil {
+			proxy, err = header.parseRow(line, defaultType)
+		} else {
+			proxy, err = parseLine(line, defaultType)
+		}
+		if err != nil {
+			errs = append(errs, &ParseError{Line: lineNo, Text: raw, Err: err})
+			continue
+		}
+
+		key := hostKey(proxy)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		proxies = append(proxies, proxy)
+	}
+
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return proxies, errors.Join(errs...)
+}
+
+// proxyEntry holds the parts of a proxy entry before it becomes a domain.Proxy
+type proxyEntry struct {
+	scheme    string
+	proxyType domain.ProxyType
+	host      string
+	port      string
+	username  string
+	password  string
+	country   string
+}
+
+// parseLine parses a single non-CSV proxy entry
+func parseLine(line string, defaultType domain.ProxyType) (*domain.Proxy, error) {
+	line = stripComment(strings.TrimSpace(line))
 	if line == "" {
-		return nil, fmt.Errorf("empty line")
+		return nil, ErrEmptyLine
 	}
 
-	parsedURL, err := url.Parse("http://" + line)
+	entry := proxyEntry{proxyType: defaultType}
+
+	switch {
+	case strings.Contains(line, "://"):
+		u, err := url.Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnrecognized, err)
+		}
+
+		scheme := strings.ToLower(u.Scheme)
+		proxyType, ok := domain.ProxyTypeFromScheme(scheme)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+		}
+
+		entry.scheme = scheme
+		entry.proxyType = proxyType
+		entry.host = u.Hostname()
+		entry.port = u.Port()
+		if u.User != nil {
+			entry.username = u.User.Username()
+			entry.password, _ = u.User.Password()
+		}
+
+	case strings.Contains(line, "@"):
+		// user:pass@host:port; the password may itself contain '@'
+		at := strings.LastIndex(line, "@")
+		entry.username, entry.password, _ = strings.Cut(line[:at], ":")
+
+		var err error
+		if entry.host, entry.port, err = splitHostPort(line[at+1:]); err != nil {
+			return nil, err
+		}
+
+	case strings.HasPrefix(line, "["):
+		var err error
+		if entry.host, entry.port, err = splitHostPort(line); err != nil {
+			return nil, err
+		}
+
+	default:
+		parts := strings.Split(line, ":")
+		switch len(parts) {
+		case 2:
+			entry.host, entry.port = parts[0], parts[1]
+		case 4:
+			entry.host, entry.port = parts[0], parts[1]
+			entry.username, entry.password = parts[2], parts[3]
+		default:
+			return nil, ErrUnrecognized
+		}
+	}
+
+	return entry.toProxy()
+}
+
+// toProxy validates the entry and converts it to a domain.Proxy
+func (e proxyEntry) toProxy() (*domain.Proxy, error) {
+	if e.host == "" {
+		return nil, ErrMissingHost
+	}
+
+	port, err := strconv.Atoi(e.port)
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPort, e.port)
+	}
+
+	scheme := e.scheme
+	if scheme == "" {
+		scheme = string(e.proxyType)
+	}
+
+	proxyURL := &url.URL{
+		Scheme: scheme,
+		Host:   net.JoinHostPort(e.host, e.port),
+	}
+
+	return &domain.Proxy{
+		ID:          uuid.New().String(),
+		URL:         proxyURL.String(),
+		Type:        e.proxyType,
+		Username:    e.username,
+		Password:    e.password,
+		CountryCode: strings.ToUpper(e.country),
+		Enabled:     true,
+		MaxRetries:  3,
+	}, nil
+}
+
+// csvHeader maps known column names to their positions in a CSV proxy list
+type csvHeader struct {
+	columns map[string]int
+}
+
+// csvColumnNames maps accepted header names to the field they hold
+var csvColumnNames = map[string]string{
+	"proxy":        "proxy",
+	"url":          "proxy",
+	"ip":           "host",
+	"host":         "host",
+	"hostname":     "host",
+	"address":      "host",
+	"ip_address":   "host",
+	"server":       "host",
+	"port":         "port",
+	"username":     "username",
+	"user":         "username",
+	"login":        "username",
+	"password":     "password",
+	"pass":         "password",
+	"type":         "type",
+	"protocol":     "type",
+	"scheme":       "type",
+	"proxy_type":   "type",
+	"country":      "country",
+	"country_code": "country",
+	"countrycode":  "country",
+}
+
+// csvNameReplacer normalises header names such as "IP Address" to "ip_address"
+var csvNameReplacer = strings.NewReplacer(" ", "_", "-", "_")
+
+// parseCSVHeader reports whether line is a CSV header it can use
+func parseCSVHeader(line string) (*csvHeader, bool) {
+	if !strings.Contains(line, ",") {
+		return nil, false
+	}
+
+	fields, err := readCSVLine(line)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+		return nil, false
 	}
 
-	proxy := &domain.Proxy{
-		ID:         uuid.New().String(),
-		URL:        parsedURL.String(), // Convert URL to string
-		Type:       domain.HTTPProxy,   // Use proper constant
-		Enabled:    true,
-		MaxRetries: 3,
+	h := &csvHeader{columns: make(map[string]int)}
+	for i, field := range fields {
+		name := csvNameReplacer.Replace(strings.ToLower(strings.TrimSpace(field)))
+		if column, ok := csvColumnNames[name]; ok {
+			if _, dup := h.columns[column]; !dup {
+				h.columns[column] = i
+			}
+		}
 	}
 
-	return proxy, nil
+	_, hasProxy := h.columns["proxy"]
+	_, hasHost := h.columns["host"]
+	_, hasPort := h.columns["port"]
+	if !hasProxy && !(hasHost && hasPort) {
+		return nil, false
+	}
+
+	return h, true
+}
+
+// parseRow parses a CSV data row
+func (h *csvHeader) parseRow(line string, defaultType domain.ProxyType) (*domain.Proxy, error) {
+	fields, err := readCSVLine(line)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnrecognized, err)
+	}
+
+	get := func(column string) string {
+		if i, ok := h.columns[column]; ok && i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	entry := proxyEntry{proxyType: defaultType}
+	if typ := strings.ToLower(get("type")); typ != "" {
+		proxyType, ok := domain.ProxyTypeFromScheme(typ)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, typ)
+		}
+		entry.scheme = typ
+		entry.proxyType = proxyType
+	}
+
+	if value := get("proxy"); value != "" {
+		proxy, err := parseLine(value, entry.proxyType)
+		if err != nil {
+			return nil, err
+		}
+		if country := get("country"); country != "" {
+			proxy.CountryCode = strings.ToUpper(country)
+		}
+		return proxy, nil
+	}
+
+	entry.host = strings.Trim(get("host"), "[]")
+	entry.port = get("port")
+	entry.username = get("username")
+	entry.password = get("password")
+	entry.country = get("country")
+
+	return entry.toProxy()
+}
+
+// readCSVLine splits a single CSV line into fields
+func readCSVLine(line string) ([]string, error) {
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader.Read()
+}
+
+// splitHostPort splits host:port, accepting bracketed IPv6 hosts
+func splitHostPort(hostport string) (string, string, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrUnrecognized, err)
+	}
+	return host, port, nil
+}
+
+// isComment reports whether a trimmed line is a comment
+func isComment(line string) bool {
+	return strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//")
+}
+
+// stripComment removes a trailing " # comment" from a line
+func stripComment(line string) string {
+	if i := strings.Index(line, " #"); i >= 0 {
+		line = line[:i]
+	}
+	if i := strings.Index(line, "\t#"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+// hostKey returns the lowercase host:port of a proxy for deduplication
+func hostKey(proxy *domain.Proxy) string {
+	u, err := url.Parse(proxy.URL)
+	if err != nil {
+		return proxy.URL
+	}
+	return strings.ToLower(u.Host)
 }
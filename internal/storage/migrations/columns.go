@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Column is a column added to an existing table by a migration
+type Column struct {
+	Name       string
+	Definition string
+}
+
+// AddMissingColumns adds the columns a table doesn't have yet, so tables
+// created before a column was introduced pick it up on the next migration
+func AddMissingColumns(db *sql.DB, table string, columns []Column) error {
+	rows, err := db.Query(`SELECT * FROM ` + table + ` LIMIT 0`)
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	existing, err := rows.Columns()
+	if closeErr := rows.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+
+	have := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		have[strings.ToLower(name)] = true
+	}
+
+	for _, c := range columns {
+		if have[c.Name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, c.Name, c.Definition)); err != nil {
+			return fmt.Errorf("failed to execute migration: %w", err)
+		}
+	}
+	return nil
+}
@@ -8,6 +8,17 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// postgresProxyColumns are the proxies columns added since the table was first
+// created, for tables created by an older version
+var postgresProxyColumns = []Column{
+	{Name: "rate_limit", Definition: "DOUBLE PRECISION DEFAULT 0"},
+	{Name: "rate_burst", Definition: "INTEGER DEFAULT 0"},
+	{Name: "tags", Definition: "TEXT DEFAULT ''"},
+	{Name: "health", Definition: "TEXT DEFAULT ''"},
+	{Name: "health_failures", Definition: "INTEGER DEFAULT 0"},
+	{Name: "health_since", Definition: "TIMESTAMP WITH TIME ZONE"},
+}
+
 type postgresMigrator struct {
 	db *sql.DB
 }
@@ -34,11 +45,42 @@ func (m *postgresMigrator) Migrate(opts storage.Options) error {
             total_requests BIGINT DEFAULT 0,
             avg_latency BIGINT DEFAULT 0,
             last_status_code INTEGER DEFAULT 0,
+            rate_limit DOUBLE PRECISION DEFAULT 0,
+            rate_burst INTEGER DEFAULT 0,
+            tags TEXT DEFAULT '',
+            health TEXT DEFAULT '',
+            health_failures INTEGER DEFAULT 0,
+            health_since TIMESTAMP WITH TIME ZONE,
             created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
             updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
         );`,
 		`CREATE INDEX IF NOT EXISTS idx_proxies_type ON proxies(type);`,
 		`CREATE INDEX IF NOT EXISTS idx_proxies_is_active ON proxies(is_active);`,
+		`CREATE TABLE IF NOT EXISTS proxy_pools (
+            name TEXT PRIMARY KEY,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );`,
+		`CREATE TABLE IF NOT EXISTS proxy_pool_members (
+            pool_name TEXT NOT NULL REFERENCES proxy_pools(name) ON DELETE CASCADE,
+            proxy_id TEXT NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (pool_name, proxy_id)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_proxy_pool_members_proxy_id ON proxy_pool_members(proxy_id);`,
+		`CREATE TABLE IF NOT EXISTS proxy_sessions (
+            session_key TEXT PRIMARY KEY,
+            proxy_id TEXT NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+            expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_proxy_sessions_expires_at ON proxy_sessions(expires_at);`,
+		`CREATE TABLE IF NOT EXISTS proxy_bans (
+            proxy_id TEXT NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+            domain TEXT NOT NULL,
+            reason TEXT NOT NULL DEFAULT '',
+            expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+            PRIMARY KEY (proxy_id, domain)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_proxy_bans_expires_at ON proxy_bans(expires_at);`,
 	}
 
 	for _, query := range queries {
@@ -46,10 +88,10 @@ func (m *postgresMigrator) Migrate(opts storage.Options) error {
 			return fmt.Errorf("failed to execute migration: %w", err)
 		}
 	}
-	return nil
+	return AddMissingColumns(m.db, "proxies", postgresProxyColumns)
 }
 
 func (m *postgresMigrator) Drop() error {
-	_, err := m.db.Exec(`DROP TABLE IF EXISTS proxies CASCADE;`)
+	_, err := m.db.Exec(`DROP TABLE IF EXISTS proxy_bans, proxy_sessions, proxy_pool_members, proxy_pools, proxies CASCADE;`)
 	return err
 }
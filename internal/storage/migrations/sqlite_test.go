@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/greysquirr3l/lashes/internal/storage"
+)
+
+// baselineSchema is the proxies table as created by the first release
+const baselineSchema = `
+	CREATE TABLE proxies (
+		id TEXT PRIMARY KEY,
+		url TEXT NOT NULL,
+		type TEXT NOT NULL,
+		last_used TIMESTAMP,
+		last_check TIMESTAMP,
+		latency INTEGER,
+		is_active BOOLEAN DEFAULT TRUE,
+		weight INTEGER DEFAULT 1,
+		max_retries INTEGER DEFAULT 3,
+		timeout_ms INTEGER DEFAULT 30000,
+		success_count INTEGER DEFAULT 0,
+		failure_count INTEGER DEFAULT 0,
+		total_requests INTEGER DEFAULT 0,
+		avg_latency INTEGER DEFAULT 0,
+		last_status_code INTEGER DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)
+`
+
+func TestSQLiteMigrateUpgradesBaselineSchema(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "lashes.db"))
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(baselineSchema); err != nil {
+		t.Fatalf("creating baseline schema failed: %v", err)
+	}
+
+	migrator := NewSQLiteMigrator(db)
+	for i := 0; i < 2; i++ {
+		if err := migrator.Migrate(storage.Options{}); err != nil {
+			t.Fatalf("Migrate run %d failed: %v", i+1, err)
+		}
+	}
+
+	for _, c := range sqliteProxyColumns {
+		if _, err := db.Exec(`SELECT ` + c.Name + ` FROM proxies`); err != nil {
+			t.Errorf("column %s missing after Migrate: %v", c.Name, err)
+		}
+	}
+}
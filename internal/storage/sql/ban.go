@@ -0,0 +1,83 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/repository"
+)
+
+// ListBans returns every stored ban
+func (r *sqlRepository) ListBans(ctx context.Context) ([]*domain.Ban, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT proxy_id, domain, reason, expires_at FROM proxy_bans`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bans: %w", err)
+	}
+	// Read errors are reported by rows.Err below
+	defer func() { _ = rows.Close() }()
+
+	var bans []*domain.Ban
+	for rows.Next() {
+		var ban domain.Ban
+		if err := rows.Scan(&ban.ProxyID, &ban.Domain, &ban.Reason, &ban.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ban: %w", err)
+		}
+		bans = append(bans, &ban)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list bans: %w", err)
+	}
+	return bans, nil
+}
+
+// SaveBan creates or replaces a ban
+func (r *sqlRepository) SaveBan(ctx context.Context, ban *domain.Ban) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+        INSERT INTO proxy_bans (proxy_id, domain, reason, expires_at) VALUES (?, ?, ?, ?)
+        ON CONFLICT (proxy_id, domain) DO UPDATE SET reason = excluded.reason, expires_at = excluded.expires_at
+    `
+
+	if _, err := r.db.ExecContext(ctx, query, ban.ProxyID, ban.Domain, ban.Reason, ban.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to save ban: %w", err)
+	}
+	return nil
+}
+
+// DeleteBan removes the ban on a proxy for a domain
+func (r *sqlRepository) DeleteBan(ctx context.Context, proxyID, domain string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM proxy_bans WHERE proxy_id = ? AND domain = ?`, proxyID, domain)
+	if err != nil {
+		return fmt.Errorf("failed to delete ban: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrBanNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredBans removes bans that expired before the given time
+func (r *sqlRepository) DeleteExpiredBans(ctx context.Context, before time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM proxy_bans WHERE expires_at <= ?`, before); err != nil {
+		return fmt.Errorf("failed to delete expired bans: %w", err)
+	}
+	return nil
+}
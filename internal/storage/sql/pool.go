@@ -0,0 +1,169 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/repository"
+)
+
+// CreatePool creates an empty pool
+func (r *sqlRepository) CreatePool(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `INSERT INTO proxy_pools (name, created_at) VALUES (?, ?)`
+
+	if _, err := r.db.ExecContext(ctx, query, name, time.Now()); err != nil {
+		if IsConstraintViolation(err) {
+			return repository.ErrPoolExists
+		}
+		return fmt.Errorf("failed to create pool: %w", err)
+	}
+	return nil
+}
+
+// DeletePool removes a pool and its memberships
+func (r *sqlRepository) DeletePool(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			// The transaction was already committed or the connection is gone
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM proxy_pools WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete pool: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrPoolNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM proxy_pool_members WHERE pool_name = ?`, name); err != nil {
+		return fmt.Errorf("failed to delete pool members: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ListPools returns the names of all pools
+func (r *sqlRepository) ListPools(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.queryStrings(ctx, `SELECT name FROM proxy_pools ORDER BY name`)
+}
+
+// AddToPool adds a proxy to a pool
+func (r *sqlRepository) AddToPool(ctx context.Context, poolName, proxyID string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if err := r.poolExists(ctx, poolName); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO proxy_pool_members (pool_name, proxy_id, created_at) VALUES (?, ?, ?)`
+
+	if _, err := r.db.ExecContext(ctx, query, poolName, proxyID, time.Now()); err != nil {
+		if IsConstraintViolation(err) {
+			// Already a member
+			return nil
+		}
+		return fmt.Errorf("failed to add proxy to pool: %w", err)
+	}
+	return nil
+}
+
+// RemoveFromPool removes a proxy from a pool
+func (r *sqlRepository) RemoveFromPool(ctx context.Context, poolName, proxyID string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if err := r.poolExists(ctx, poolName); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM proxy_pool_members WHERE pool_name = ? AND proxy_id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, poolName, proxyID)
+	if err != nil {
+		return fmt.Errorf("failed to remove proxy from pool: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrProxyNotFound
+	}
+	return nil
+}
+
+// GetPoolMembers returns the IDs of the proxies in a pool
+func (r *sqlRepository) GetPoolMembers(ctx context.Context, poolName string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if err := r.poolExists(ctx, poolName); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT proxy_id FROM proxy_pool_members WHERE pool_name = ? ORDER BY created_at, proxy_id`
+
+	return r.queryStrings(ctx, query, poolName)
+}
+
+// poolExists returns ErrPoolNotFound if the named pool doesn't exist
+func (r *sqlRepository) poolExists(ctx context.Context, name string) error {
+	var found string
+	err := r.db.QueryRowContext(ctx, `SELECT name FROM proxy_pools WHERE name = ?`, name).Scan(&found)
+	if err != nil {
+		if IsNoRowsError(err) {
+			return repository.ErrPoolNotFound
+		}
+		return fmt.Errorf("failed to get pool: %w", err)
+	}
+	return nil
+}
+
+// queryStrings runs a query that selects a single text column
+func (r *sqlRepository) queryStrings(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pools: %w", err)
+	}
+	// Read errors are reported by rows.Err below
+	defer func() { _ = rows.Close() }()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to scan pool row: %w", err)
+		}
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pool rows: %w", err)
+	}
+
+	return values, nil
+}
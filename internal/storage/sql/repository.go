@@ -9,6 +9,7 @@ import (
 
 	"github.com/greysquirr3l/lashes/internal/domain"
 	"github.com/greysquirr3l/lashes/internal/repository"
+	"github.com/greysquirr3l/lashes/internal/storage/migrations"
 )
 
 const (
@@ -28,12 +29,66 @@ const (
 		success_rate REAL DEFAULT 0,
 		usage_count INTEGER DEFAULT 0,
 		error_count INTEGER DEFAULT 0,
+		rate_limit REAL DEFAULT 0,
+		rate_burst INTEGER DEFAULT 0,
+		tags TEXT DEFAULT '',
+		health TEXT DEFAULT '',
+		health_failures INTEGER DEFAULT 0,
+		health_since TIMESTAMP,
 		created_at TIMESTAMP NOT NULL,
 		updated_at TIMESTAMP NOT NULL
 	)
 	`
+
+	// SQL statements to create the pool tables
+	createPoolTableSQL = `
+	CREATE TABLE IF NOT EXISTS proxy_pools (
+		name TEXT PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL
+	)
+	`
+
+	createPoolMemberTableSQL = `
+	CREATE TABLE IF NOT EXISTS proxy_pool_members (
+		pool_name TEXT NOT NULL,
+		proxy_id TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (pool_name, proxy_id)
+	)
+	`
+
+	// SQL statement to create the session table
+	createSessionTableSQL = `
+	CREATE TABLE IF NOT EXISTS proxy_sessions (
+		session_key TEXT PRIMARY KEY,
+		proxy_id TEXT NOT NULL,
+		expires_at TIMESTAMP NOT NULL
+	)
+	`
+
+	// SQL statement to create the ban table
+	createBanTableSQL = `
+	CREATE TABLE IF NOT EXISTS proxy_bans (
+		proxy_id TEXT NOT NULL,
+		domain TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		expires_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (proxy_id, domain)
+	)
+	`
 )
 
+// proxyColumns are the proxies columns added since the table was first
+// created. init adds any of them that an older table lacks.
+var proxyColumns = []migrations.Column{
+	{Name: "rate_limit", Definition: "REAL DEFAULT 0"},
+	{Name: "rate_burst", Definition: "INTEGER DEFAULT 0"},
+	{Name: "tags", Definition: "TEXT DEFAULT ''"},
+	{Name: "health", Definition: "TEXT DEFAULT ''"},
+	{Name: "health_failures", Definition: "INTEGER DEFAULT 0"},
+	{Name: "health_since", Definition: "TIMESTAMP"},
+}
+
 type sqlRepository struct {
 	db      *sql.DB
 	timeout time.Duration
@@ -60,9 +115,14 @@ func NewSQLRepository(db *sql.DB, timeout time.Duration) repository.ProxyReposit
 }
 
 // init creates the necessary database tables if they don't exist
+// and adds columns missing from tables created by older versions
 func (r *sqlRepository) init() error {
-	_, err := r.db.Exec(createTableSQL)
-	return err
+	for _, stmt := range []string{createTableSQL, createPoolTableSQL, createPoolMemberTableSQL, createSessionTableSQL, createBanTableSQL} {
+		if _, err := r.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return migrations.AddMissingColumns(r.db, "proxies", proxyColumns)
 }
 
 func (r *sqlRepository) Create(ctx context.Context, proxy *domain.Proxy) error {
@@ -74,8 +134,9 @@ func (r *sqlRepository) Create(ctx context.Context, proxy *domain.Proxy) error {
         INSERT INTO proxies (
             id, url, type, username, password, country_code, weight, 
             last_used, enabled, latency, success_rate, 
-            usage_count, error_count, created_at, updated_at
-        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+            usage_count, error_count, rate_limit, rate_burst, tags,
+            health, health_failures, health_since, created_at, updated_at
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
 	now := time.Now()
@@ -98,6 +159,12 @@ func (r *sqlRepository) Create(ctx context.Context, proxy *domain.Proxy) error {
 		proxy.SuccessRate,
 		proxy.UsageCount,
 		proxy.ErrorCount,
+		proxy.RateLimit,
+		proxy.RateBurst,
+		domain.EncodeTags(proxy.Tags),
+		string(proxy.Health),
+		proxy.HealthFailures,
+		proxy.HealthSince,
 		proxy.CreatedAt,
 		proxy.UpdatedAt,
 	)
@@ -131,6 +198,11 @@ func (r *sqlRepository) Delete(ctx context.Context, id string) error {
 		return repository.ErrProxyNotFound
 	}
 
+	// Drop the proxy from any pools it belonged to
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM proxy_pool_members WHERE proxy_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete pool memberships: %w", err)
+	}
+
 	return nil
 }
 
@@ -143,13 +215,15 @@ func (r *sqlRepository) GetByID(ctx context.Context, id string) (*domain.Proxy,
 	SELECT 
 		id, url, type, username, password, country_code, weight, 
 		last_used, enabled, latency, success_rate, 
-		usage_count, error_count, created_at, updated_at
+		usage_count, error_count, rate_limit, rate_burst, tags,
+		health, health_failures, health_since, created_at, updated_at
 	FROM proxies 
 	WHERE id = ?
 	`
 
 	proxy := &domain.Proxy{}
-	var lastUsed, createdAt, updatedAt sql.NullTime
+	var lastUsed, healthSince, createdAt, updatedAt sql.NullTime
+	var tags, health sql.NullString
 
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&proxy.ID,
@@ -165,6 +239,12 @@ func (r *sqlRepository) GetByID(ctx context.Context, id string) (*domain.Proxy,
 		&proxy.SuccessRate,
 		&proxy.UsageCount,
 		&proxy.ErrorCount,
+		&proxy.RateLimit,
+		&proxy.RateBurst,
+		&tags,
+		&health,
+		&proxy.HealthFailures,
+		&healthSince,
 		&createdAt,
 		&updatedAt,
 	)
@@ -176,6 +256,14 @@ func (r *sqlRepository) GetByID(ctx context.Context, id string) (*domain.Proxy,
 	}
 
 	// Convert nullable fields
+	proxy.Tags = domain.DecodeTags(tags.String)
+	proxy.Health = domain.HealthState(health.String)
+
+	if healthSince.Valid {
+		t := healthSince.Time
+		proxy.HealthSince = &t
+	}
+
 	if lastUsed.Valid {
 		t := lastUsed.Time
 		proxy.LastUsed = &t
@@ -245,7 +333,8 @@ func (r *sqlRepository) List(ctx context.Context) ([]*domain.Proxy, error) {
 	defer cancel()
 
 	query := `SELECT id, url, type, last_used, enabled, latency, 
-              weight, max_retries, timeout_ms FROM proxies`
+              weight, max_retries, timeout_ms, rate_limit, rate_burst, tags,
+              health, health_failures, health_since FROM proxies`
 
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
@@ -262,6 +351,8 @@ func (r *sqlRepository) List(ctx context.Context) ([]*domain.Proxy, error) {
 	for rows.Next() {
 		var proxy domain.Proxy
 		var urlStr string
+		var tags, health sql.NullString
+		var healthSince sql.NullTime
 
 		err := rows.Scan(
 			&proxy.ID,
@@ -273,6 +364,12 @@ func (r *sqlRepository) List(ctx context.Context) ([]*domain.Proxy, error) {
 			&proxy.Weight,
 			&proxy.MaxRetries,
 			&proxy.Timeout,
+			&proxy.RateLimit,
+			&proxy.RateBurst,
+			&tags,
+			&health,
+			&proxy.HealthFailures,
+			&healthSince,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan proxy: %w", err)
@@ -280,6 +377,12 @@ func (r *sqlRepository) List(ctx context.Context) ([]*domain.Proxy, error) {
 
 		// Set URL directly
 		proxy.URL = urlStr
+		proxy.Tags = domain.DecodeTags(tags.String)
+		proxy.Health = domain.HealthState(health.String)
+		if healthSince.Valid {
+			t := healthSince.Time
+			proxy.HealthSince = &t
+		}
 		proxies = append(proxies, &proxy)
 	}
 
@@ -299,7 +402,9 @@ func (r *sqlRepository) Update(ctx context.Context, proxy *domain.Proxy) error {
         UPDATE proxies SET
             url = ?, type = ?, username = ?, password = ?, country_code = ?, 
             weight = ?, last_used = ?, enabled = ?, latency = ?, 
-            success_rate = ?, usage_count = ?, error_count = ?, updated_at = ?
+            success_rate = ?, usage_count = ?, error_count = ?,
+            rate_limit = ?, rate_burst = ?, tags = ?,
+            health = ?, health_failures = ?, health_since = ?, updated_at = ?
         WHERE id = ?
     `
 
@@ -318,6 +423,12 @@ func (r *sqlRepository) Update(ctx context.Context, proxy *domain.Proxy) error {
 		proxy.SuccessRate,
 		proxy.UsageCount,
 		proxy.ErrorCount,
+		proxy.RateLimit,
+		proxy.RateBurst,
+		domain.EncodeTags(proxy.Tags),
+		string(proxy.Health),
+		proxy.HealthFailures,
+		proxy.HealthSince,
 		proxy.UpdatedAt,
 		proxy.ID,
 	)
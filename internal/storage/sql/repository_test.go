@@ -0,0 +1,104 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// baselineSchema is the proxies table as created by the first release
+const baselineSchema = `
+	CREATE TABLE proxies (
+		id TEXT PRIMARY KEY,
+		url TEXT NOT NULL,
+		type TEXT NOT NULL,
+		username TEXT,
+		password TEXT,
+		country_code TEXT,
+		weight INTEGER DEFAULT 1,
+		last_used TIMESTAMP,
+		enabled BOOLEAN DEFAULT true,
+		latency INTEGER DEFAULT 0,
+		success_rate REAL DEFAULT 0,
+		usage_count INTEGER DEFAULT 0,
+		error_count INTEGER DEFAULT 0,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)
+`
+
+// openBaselineDB opens a SQLite database whose proxies table has the baseline schema
+func openBaselineDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "lashes.db"))
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(baselineSchema); err != nil {
+		t.Fatalf("creating baseline schema failed: %v", err)
+	}
+	return db
+}
+
+func TestInitUpgradesBaselineSchema(t *testing.T) {
+	db := openBaselineDB(t)
+
+	repo := &sqlRepository{db: db, timeout: time.Second}
+	if err := repo.init(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	// A second run finds the columns in place and leaves them alone
+	if err := repo.init(); err != nil {
+		t.Fatalf("second init failed: %v", err)
+	}
+
+	for _, c := range proxyColumns {
+		if _, err := db.Exec(`SELECT ` + c.Name + ` FROM proxies`); err != nil {
+			t.Errorf("column %s missing after init: %v", c.Name, err)
+		}
+	}
+}
+
+func TestBaselineSchemaRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSQLRepository(openBaselineDB(t), time.Second)
+
+	since := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	proxy := &domain.Proxy{
+		ID:             "p1",
+		URL:            "http://127.0.0.1:8080",
+		Type:           domain.HTTP,
+		Enabled:        true,
+		RateLimit:      2.5,
+		RateBurst:      4,
+		Tags:           []string{"residential"},
+		Health:         domain.HealthQuarantined,
+		HealthFailures: 3,
+		HealthSince:    &since,
+	}
+	if err := repo.Create(ctx, proxy); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, "p1")
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.RateLimit != 2.5 || got.RateBurst != 4 || !got.HasTags("residential") {
+		t.Errorf("rate limit and tags = %v, %v, %v; want 2.5, 4, [residential]", got.RateLimit, got.RateBurst, got.Tags)
+	}
+	if got.Health != domain.HealthQuarantined || got.HealthFailures != 3 {
+		t.Errorf("health = %q after %d failures, want quarantined after 3", got.Health, got.HealthFailures)
+	}
+	if got.HealthSince == nil || !got.HealthSince.Equal(since) {
+		t.Errorf("HealthSince = %v, want %v", got.HealthSince, since)
+	}
+}
@@ -0,0 +1,75 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/repository"
+)
+
+// GetSession returns the session stored under key
+func (r *sqlRepository) GetSession(ctx context.Context, key string) (*domain.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `SELECT session_key, proxy_id, expires_at FROM proxy_sessions WHERE session_key = ?`
+
+	var session domain.Session
+	err := r.db.QueryRowContext(ctx, query, key).Scan(&session.Key, &session.ProxyID, &session.ExpiresAt)
+	if err != nil {
+		if IsNoRowsError(err) {
+			return nil, repository.ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &session, nil
+}
+
+// SaveSession creates or replaces a session
+func (r *sqlRepository) SaveSession(ctx context.Context, session *domain.Session) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+        INSERT INTO proxy_sessions (session_key, proxy_id, expires_at) VALUES (?, ?, ?)
+        ON CONFLICT (session_key) DO UPDATE SET proxy_id = excluded.proxy_id, expires_at = excluded.expires_at
+    `
+
+	if _, err := r.db.ExecContext(ctx, query, session.Key, session.ProxyID, session.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// DeleteSession removes a session
+func (r *sqlRepository) DeleteSession(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM proxy_sessions WHERE session_key = ?`, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredSessions removes sessions that expired before the given time
+func (r *sqlRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM proxy_sessions WHERE expires_at <= ?`, before); err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return nil
+}
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/greysquirr3l/lashes/internal/domain"
-	"github.com/greysquirr3l/lashes/internal/rotation"
 	"github.com/greysquirr3l/lashes/internal/storage"
 )
 
@@ -41,6 +40,11 @@ type ProxyRotator interface {
 	// GetProxy returns the next proxy according to the configured rotation strategy.
 	GetProxy(ctx context.Context) (*Proxy, error)
 
+	// GetProxyWith returns the next proxy that matches the criteria, such as a
+	// country, type or set of tags. Strategies that take criteria into account
+	// are given them as well.
+	GetProxyWith(ctx context.Context, criteria Criteria) (*Proxy, error)
+
 	// AddProxy adds a new proxy to the rotation pool.
 	// The proxy URL should be in the format scheme://host:port
 	// Supported schemes are http, socks4, and socks5.
@@ -54,6 +58,14 @@ type ProxyRotator interface {
 	// The client is configured with the rotator's timeout and retry settings.
 	Client(ctx context.Context) (*http.Client, error)
 
+	// RoundTripper returns an http.RoundTripper that picks a proxy from the rotation
+	// on every request, reusing connections per proxy.
+	RoundTripper() http.RoundTripper
+
+	// RotatingClient returns an http.Client that rotates proxies on every request.
+	// Unlike Client, it is meant to be shared across many requests and goroutines.
+	RotatingClient() *http.Client
+
 	// List returns all available proxies in the pool.
 	List(ctx context.Context) ([]*Proxy, error)
 
@@ -77,8 +89,13 @@ type Options struct {
 	// Storage configuration (optional, defaults to in-memory)
 	Storage *storage.Options
 
-	// Strategy defines how proxies are rotated (round-robin, random, weighted, least-used)
-	Strategy rotation.StrategyType
+	// Strategy defines how proxies are rotated (round-robin, random, weighted, smooth-weighted, least-used, latency, consistent-hash, thompson, thompson-per-host)
+	// or names a strategy added with RegisterStrategy
+	Strategy StrategyType
+
+	// CustomStrategy, when set, is used instead of Strategy. It is called for
+	// the rotator and for every pool, so each gets its own instance.
+	CustomStrategy StrategyFactory
 
 	// ValidationTimeout sets the maximum time to wait for proxy validation
 	ValidationTimeout time.Duration
@@ -95,8 +112,54 @@ type Options struct {
 	// RetryDelay sets the delay between retry attempts
 	RetryDelay time.Duration
 
+	// Backoff calculates retry delays for the rotating client.
+	// Defaults to a ConstantBackoff of RetryDelay.
+	Backoff BackoffStrategy
+
+	// MaxRetryAfter is the longest Retry-After header the rotating client
+	// waits out before a retry. Responses asking for longer are retried
+	// through another proxy after the usual backoff delay. Defaults to a minute.
+	MaxRetryAfter time.Duration
+
+	// RetryOnStatus lists the response status codes the rotating client retries
+	// through a different proxy. Defaults to 407, 429, 502, 503 and 504.
+	RetryOnStatus []int
+
+	// RetryNonIdempotent allows the rotating client to retry methods such as POST.
+	// Requests with a body are only retried when they can be replayed via GetBody.
+	RetryNonIdempotent bool
+
 	// RequestTimeout sets the maximum time to wait for proxy requests
 	RequestTimeout time.Duration
+
+	// CircuitBreaker enables per-proxy circuit breakers when set.
+	// Proxies whose breaker is open are skipped during selection.
+	CircuitBreaker *CircuitBreakerConfig
+
+	// RateLimit enables per-proxy rate limiting when set.
+	// Proxies without a free token are skipped during selection.
+	RateLimit *RateLimitConfig
+
+	// SessionTTL is how long a session keeps its proxy after its last use.
+	// Defaults to DefaultSessionTTL.
+	SessionTTL time.Duration
+
+	// BanDetection enables automatic per-domain bans when set. Responses
+	// that look like a block are retried through another proxy, and the
+	// proxy is skipped for that host until the ban expires.
+	BanDetection *BanDetectionConfig
+
+	// PassiveHealth quarantines proxies based on rotating-client outcomes when set
+	PassiveHealth *PassiveHealthConfig
+
+	// Quarantine configures how quarantined proxies are re-probed and retired.
+	// Defaults to DefaultQuarantineConfig.
+	Quarantine *QuarantineConfig
+
+	// ResponseClassifier decides which rotating-client responses count as
+	// successes, soft failures, bans, captchas or transient errors.
+	// Defaults to DefaultResponseClassifier, which looks at the status only.
+	ResponseClassifier ResponseClassifier
 }
 
 // New creates a new proxy rotator with the given options.
@@ -109,8 +172,8 @@ func New(opts Options) (ProxyRotator, error) {
 // Uses in-memory storage and round-robin rotation strategy.
 func DefaultOptions() Options {
 	return Options{
-		Storage:           nil,                         // Use in-memory storage by default
-		Strategy:          rotation.RoundRobinStrategy, // Use non-deprecated constant
+		Storage:           nil, // Use in-memory storage by default
+		Strategy:          RoundRobinStrategy,
 		ValidationTimeout: time.Second * 10,
 		ValidateOnStart:   true,
 		TestURL:           "https://api.ipify.org?format=json",
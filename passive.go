@@ -0,0 +1,51 @@
+package lashes
+
+// PassiveHealthConfig decides when live traffic through the rotating client
+// quarantines a proxy
+type PassiveHealthConfig struct {
+	// ConsecutiveErrors quarantines a proxy after this many connection
+	// errors in a row. Defaults to 5.
+	ConsecutiveErrors int
+
+	// FailureRateThreshold quarantines a proxy when this share (0-1] of
+	// its last WindowSize requests ended in a connection error. Error
+	// statuses from the target site don't count. Defaults to 0.5.
+	FailureRateThreshold float64
+
+	// WindowSize is the number of recent requests the failure rate covers.
+	// Defaults to 20.
+	WindowSize int
+
+	// MinimumRequests is the number of requests the window needs before its
+	// failure rate is trusted. Defaults to 10.
+	MinimumRequests int
+}
+
+// DefaultPassiveHealthConfig returns sensible defaults for passive health checking
+func DefaultPassiveHealthConfig() PassiveHealthConfig {
+	return PassiveHealthConfig{
+		ConsecutiveErrors:    5,
+		FailureRateThreshold: 0.5,
+		WindowSize:           20,
+		MinimumRequests:      10,
+	}
+}
+
+// withDefaults fills in unset fields from DefaultPassiveHealthConfig
+func (c PassiveHealthConfig) withDefaults() PassiveHealthConfig {
+	defaults := DefaultPassiveHealthConfig()
+	if c.ConsecutiveErrors <= 0 {
+		c.ConsecutiveErrors = defaults.ConsecutiveErrors
+	}
+	if c.FailureRateThreshold <= 0 || c.FailureRateThreshold > 1 {
+		c.FailureRateThreshold = defaults.FailureRateThreshold
+	}
+	if c.WindowSize <= 0 {
+		c.WindowSize = defaults.WindowSize
+	}
+	if c.MinimumRequests <= 0 {
+		c.MinimumRequests = defaults.MinimumRequests
+	}
+	c.MinimumRequests = min(c.MinimumRequests, c.WindowSize)
+	return c
+}
@@ -3,6 +3,9 @@ package lashes
 import (
 	"context"
 	"errors"
+
+	"github.com/greysquirr3l/lashes/internal/repository"
+	"github.com/greysquirr3l/lashes/internal/rotation"
 )
 
 // PoolManager provides methods for managing groups of proxies
@@ -10,9 +13,12 @@ type PoolManager interface {
 	// CreatePool creates a new proxy pool with the given name
 	CreatePool(ctx context.Context, name string) error
 
-	// DeletePool removes a proxy pool
+	// DeletePool removes a proxy pool; its proxies stay in the rotator
 	DeletePool(ctx context.Context, name string) error
 
+	// ListPools returns the names of all pools
+	ListPools(ctx context.Context) ([]string, error)
+
 	// AddToPool adds a proxy to a pool
 	AddToPool(ctx context.Context, poolName, proxyID string) error
 
@@ -28,10 +34,132 @@ type PoolManager interface {
 
 // Pool related errors
 var (
-	ErrPoolNotFound = errors.New("pool not found")
-	ErrPoolExists   = errors.New("pool already exists")
+	ErrPoolNotFound    = repository.ErrPoolNotFound
+	ErrPoolExists      = repository.ErrPoolExists
+	ErrInvalidPoolName = errors.New("pool name cannot be empty")
 )
 
+var _ PoolManager = (*rotator)(nil)
+
+// CreatePool creates a new, empty proxy pool
+func (r *rotator) CreatePool(ctx context.Context, name string) error {
+	if name == "" {
+		return ErrInvalidPoolName
+	}
+	return r.pools.CreatePool(ctx, name)
+}
+
+// DeletePool removes a proxy pool
+func (r *rotator) DeletePool(ctx context.Context, name string) error {
+	if err := r.pools.DeletePool(ctx, name); err != nil {
+		return err
+	}
+
+	r.poolMu.Lock()
+	delete(r.poolStrategies, name)
+	r.poolMu.Unlock()
+
+	return nil
+}
+
+// ListPools returns the names of all pools
+func (r *rotator) ListPools(ctx context.Context) ([]string, error) {
+	return r.pools.ListPools(ctx)
+}
+
+// AddToPool adds an existing proxy to a pool.
+// A proxy can belong to any number of pools.
+func (r *rotator) AddToPool(ctx context.Context, poolName, proxyID string) error {
+	if _, err := r.repo.GetByID(ctx, proxyID); err != nil {
+		return err
+	}
+	return r.pools.AddToPool(ctx, poolName, proxyID)
+}
+
+// RemoveFromPool removes a proxy from a pool without deleting the proxy
+func (r *rotator) RemoveFromPool(ctx context.Context, poolName, proxyID string) error {
+	return r.pools.RemoveFromPool(ctx, poolName, proxyID)
+}
+
+// GetPoolProxies returns all proxies in a pool in the order they were added
+func (r *rotator) GetPoolProxies(ctx context.Context, poolName string) ([]*Proxy, error) {
+	memberIDs, err := r.pools.GetPoolMembers(ctx, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	allProxies, err := r.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*Proxy, len(allProxies))
+	for _, proxy := range allProxies {
+		byID[proxy.ID] = proxy
+	}
+
+	proxies := make([]*Proxy, 0, len(memberIDs))
+	for _, id := range memberIDs {
+		// Members whose proxy has since been removed are skipped
+		if proxy, ok := byID[id]; ok {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	return proxies, nil
+}
+
+// GetNextFromPool returns the next proxy from a pool using the pool's own
+// strategy instance. Disabled proxies are skipped just as in GetProxy.
+func (r *rotator) GetNextFromPool(ctx context.Context, poolName string) (*Proxy, error) {
+	proxies, err := r.GetPoolProxies(ctx, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy, err := r.poolStrategy(poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.selectProxy(ctx, strategy, proxies, selection{})
+}
+
+// observe passes a request outcome to the rotator's strategy and every pool
+// strategy that learns from feedback
+func (r *rotator) observe(feedback rotation.Feedback) {
+	if observer, ok := r.strategy.(rotation.Observer); ok {
+		observer.Observe(feedback)
+	}
+
+	r.poolMu.Lock()
+	defer r.poolMu.Unlock()
+
+	for _, strategy := range r.poolStrategies {
+		if observer, ok := strategy.(rotation.Observer); ok {
+			observer.Observe(feedback)
+		}
+	}
+}
+
+// poolStrategy returns the strategy instance for a pool, creating it on first use
+func (r *rotator) poolStrategy(poolName string) (rotation.Strategy, error) {
+	r.poolMu.Lock()
+	defer r.poolMu.Unlock()
+
+	if strategy, ok := r.poolStrategies[poolName]; ok {
+		return strategy, nil
+	}
+
+	strategy, err := newStrategy(r.opts)
+	if err != nil {
+		return nil, err
+	}
+	r.poolStrategies[poolName] = strategy
+
+	return strategy, nil
+}
+
 // GetProxiesByCountry returns all proxies for a specific country
 func (r *rotator) GetProxiesByCountry(ctx context.Context, countryCode string) ([]*Proxy, error) {
 	allProxies, err := r.List(ctx)
@@ -0,0 +1,127 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPoolManager(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	ids := map[string]string{}
+	for i, name := range []string{"res-1", "res-2", "dc-1"} {
+		proxyURL := fmt.Sprintf("http://proxy%d.example.com:8080", i)
+		if err := r.AddProxy(ctx, proxyURL, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+		proxies, _ := r.List(ctx)
+		for _, p := range proxies {
+			if p.URL == proxyURL {
+				ids[name] = p.ID
+			}
+		}
+	}
+
+	for _, pool := range []string{"residential", "datacenter"} {
+		if err := r.CreatePool(ctx, pool); err != nil {
+			t.Fatalf("CreatePool(%q) failed: %v", pool, err)
+		}
+	}
+	for _, name := range []string{"res-1", "res-2"} {
+		if err := r.AddToPool(ctx, "residential", ids[name]); err != nil {
+			t.Fatalf("AddToPool failed: %v", err)
+		}
+	}
+	if err := r.AddToPool(ctx, "datacenter", ids["dc-1"]); err != nil {
+		t.Fatalf("AddToPool failed: %v", err)
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		if err := r.CreatePool(ctx, "residential"); !errors.Is(err, ErrPoolExists) {
+			t.Errorf("CreatePool() error = %v, want %v", err, ErrPoolExists)
+		}
+		if err := r.CreatePool(ctx, ""); !errors.Is(err, ErrInvalidPoolName) {
+			t.Errorf("CreatePool(\"\") error = %v, want %v", err, ErrInvalidPoolName)
+		}
+		if _, err := r.GetNextFromPool(ctx, "mobile"); !errors.Is(err, ErrPoolNotFound) {
+			t.Errorf("GetNextFromPool() error = %v, want %v", err, ErrPoolNotFound)
+		}
+		if err := r.AddToPool(ctx, "residential", "no-such-proxy"); err == nil {
+			t.Error("AddToPool() with unknown proxy succeeded, want error")
+		}
+	})
+
+	t.Run("Selection stays within the pool", func(t *testing.T) {
+		seen := map[string]int{}
+		for i := 0; i < 4; i++ {
+			proxy, err := r.GetNextFromPool(ctx, "residential")
+			if err != nil {
+				t.Fatalf("GetNextFromPool failed: %v", err)
+			}
+			seen[proxy.ID]++
+		}
+
+		if seen[ids["res-1"]] != 2 || seen[ids["res-2"]] != 2 {
+			t.Errorf("residential selections = %v, want two of each member", seen)
+		}
+		if seen[ids["dc-1"]] != 0 {
+			t.Error("GetNextFromPool returned a proxy from another pool")
+		}
+	})
+
+	t.Run("Disabled proxies are skipped", func(t *testing.T) {
+		proxy, err := r.repo.GetByID(ctx, ids["res-1"])
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		proxy.Enabled = false
+		defer func() { proxy.Enabled = true }()
+
+		for i := 0; i < 3; i++ {
+			got, err := r.GetNextFromPool(ctx, "residential")
+			if err != nil {
+				t.Fatalf("GetNextFromPool failed: %v", err)
+			}
+			if got.ID != ids["res-2"] {
+				t.Errorf("GetNextFromPool() = %s, want %s", got.ID, ids["res-2"])
+			}
+		}
+	})
+
+	t.Run("Removed proxies leave the pool", func(t *testing.T) {
+		if err := r.RemoveFromPool(ctx, "datacenter", ids["dc-1"]); err != nil {
+			t.Fatalf("RemoveFromPool failed: %v", err)
+		}
+		if _, err := r.GetNextFromPool(ctx, "datacenter"); !errors.Is(err, ErrNoProxiesAvailable) {
+			t.Errorf("GetNextFromPool() error = %v, want %v", err, ErrNoProxiesAvailable)
+		}
+
+		// The proxy itself is still part of the rotator
+		if _, err := r.repo.GetByID(ctx, ids["dc-1"]); err != nil {
+			t.Errorf("GetByID after RemoveFromPool failed: %v", err)
+		}
+	})
+
+	t.Run("Delete pool", func(t *testing.T) {
+		if err := r.DeletePool(ctx, "datacenter"); err != nil {
+			t.Fatalf("DeletePool failed: %v", err)
+		}
+		pools, err := r.ListPools(ctx)
+		if err != nil {
+			t.Fatalf("ListPools failed: %v", err)
+		}
+		if len(pools) != 1 || pools[0] != "residential" {
+			t.Errorf("ListPools() = %v, want [residential]", pools)
+		}
+	})
+}
@@ -0,0 +1,413 @@
+package lashes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+)
+
+// QuarantineConfig decides how quarantined proxies are re-probed and when
+// they are given up on
+type QuarantineConfig struct {
+	// ProbeURL is fetched through quarantined proxies to see whether they
+	// have recovered. Defaults to Options.TestURL.
+	ProbeURL string
+
+	// InitialBackoff is the wait before the first re-probe. Defaults to 30 seconds.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between re-probes, which doubles after every
+	// failed probe. Defaults to 10 minutes.
+	MaxBackoff time.Duration
+
+	// RetireAfter retires proxies that are still failing after this long in
+	// quarantine. Zero keeps re-probing them indefinitely.
+	RetireAfter time.Duration
+
+	// DeleteRetired removes retired proxies from the rotator. Otherwise they
+	// are kept, flagged as retired, for an operator to review.
+	DeleteRetired bool
+}
+
+// DefaultQuarantineConfig returns sensible defaults for quarantined proxies
+func DefaultQuarantineConfig() QuarantineConfig {
+	return QuarantineConfig{
+		InitialBackoff: time.Second * 30,
+		MaxBackoff:     time.Minute * 10,
+	}
+}
+
+// withDefaults fills in unset fields from DefaultQuarantineConfig
+func (c QuarantineConfig) withDefaults() QuarantineConfig {
+	defaults := DefaultQuarantineConfig()
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = defaults.InitialBackoff
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		c.MaxBackoff = max(defaults.MaxBackoff, c.InitialBackoff)
+	}
+	return c
+}
+
+// proxyHealth is the health tracked for one proxy
+type proxyHealth struct {
+	state     HealthState
+	since     time.Time
+	lastError string
+
+	// failures counts health checks and re-probes failed in a row
+	failures int
+
+	// consecutive counts connection errors in a row in live traffic
+	consecutive int
+
+	// outcomes is a ring buffer of whether recent requests failed
+	outcomes []bool
+	next     int
+	total    int
+	failed   int
+
+	// backoff is the wait before the next re-probe of a quarantined proxy
+	backoff   time.Duration
+	nextProbe time.Time
+	timer     *time.Timer
+
+	// generation identifies the current quarantine, so probes scheduled for
+	// an earlier one are ignored
+	generation uint64
+}
+
+// add records whether a request failed
+func (h *proxyHealth) add(failed bool) {
+	if h.total == len(h.outcomes) {
+		// Overwrite the oldest outcome
+		if h.outcomes[h.next] {
+			h.failed--
+		}
+	} else {
+		h.total++
+	}
+
+	h.outcomes[h.next] = failed
+	if failed {
+		h.failed++
+	}
+	h.next = (h.next + 1) % len(h.outcomes)
+}
+
+// healthTracker merges the results of health checks with passive signals
+// from live traffic into a single lifecycle state per proxy. Proxies without
+// a record are healthy.
+//
+// Methods that change a proxy's state return its new status and true, so the
+// rotator can store it with the proxy.
+type healthTracker struct {
+	config  QuarantineConfig
+	passive PassiveHealthConfig
+
+	// observing is set when live traffic counts towards health
+	observing bool
+
+	// probe re-probes a quarantined proxy and reports back through reprobed
+	probe func(proxyID string, generation uint64)
+
+	// ctx bounds re-probes and is canceled by close
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.RWMutex
+	proxies map[string]*proxyHealth
+	closed  bool
+
+	// quarantines numbers the times proxies entered quarantine
+	quarantines uint64
+}
+
+// newHealthTracker creates a tracker. Live traffic only counts when passive is set.
+func newHealthTracker(config QuarantineConfig, passive *PassiveHealthConfig) *healthTracker {
+	t := &healthTracker{
+		config:  config.withDefaults(),
+		passive: DefaultPassiveHealthConfig(),
+		proxies: make(map[string]*proxyHealth),
+	}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	if passive != nil {
+		t.passive = passive.withDefaults()
+		t.observing = true
+	}
+	return t
+}
+
+// load restores the lifecycle state stored with proxies. Quarantined proxies
+// are re-probed starting from InitialBackoff.
+func (t *healthTracker) load(proxies []*domain.Proxy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for _, proxy := range proxies {
+		if proxy.Health == "" || proxy.Health == HealthHealthy {
+			continue
+		}
+
+		h := t.entry(proxy.ID)
+		h.state = proxy.Health
+		h.failures = proxy.HealthFailures
+		h.since = now
+		if proxy.HealthSince != nil {
+			h.since = *proxy.HealthSince
+		}
+		if h.state == HealthQuarantined {
+			t.startProbing(proxy.ID, h)
+		}
+	}
+}
+
+// inRotation reports whether a proxy may be selected
+func (t *healthTracker) inRotation(proxyID string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	h, ok := t.proxies[proxyID]
+	return !ok || h.state.InRotation()
+}
+
+// entry returns the health of a proxy, creating it if needed. The caller holds t.mu.
+func (t *healthTracker) entry(proxyID string) *proxyHealth {
+	h, ok := t.proxies[proxyID]
+	if !ok {
+		h = &proxyHealth{state: HealthHealthy, since: time.Now(), outcomes: make([]bool, t.passive.WindowSize)}
+		t.proxies[proxyID] = h
+	}
+	return h
+}
+
+// observe records the outcome of a request made through a proxy. Only
+// connection errors count against it: error statuses come from the target
+// site, and one struggling site must not quarantine every proxy that hits it.
+func (t *healthTracker) observe(proxyID string, err error) (HealthStatus, bool) {
+	if !t.observing {
+		return HealthStatus{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.entry(proxyID)
+	if !h.state.InRotation() {
+		// Requests already in flight when the proxy was quarantined
+		return HealthStatus{}, false
+	}
+
+	if err != nil {
+		h.consecutive++
+		h.lastError = err.Error()
+	} else {
+		h.consecutive = 0
+	}
+	h.add(err != nil)
+
+	switch {
+	case h.consecutive >= t.passive.ConsecutiveErrors:
+		t.quarantine(proxyID, h, fmt.Sprintf("%d consecutive connection errors: %s", h.consecutive, h.lastError))
+	case h.total >= t.passive.MinimumRequests && float64(h.failed)/float64(h.total) >= t.passive.FailureRateThreshold:
+		t.quarantine(proxyID, h, fmt.Sprintf("%d of the last %d requests failed", h.failed, h.total))
+	default:
+		return HealthStatus{}, false
+	}
+	return newHealthStatus(proxyID, h), true
+}
+
+// probed records the result of a health check. A proxy becomes suspect when
+// a check fails and is quarantined after maxFailures failures in a row.
+// Retired proxies are left alone.
+func (t *healthTracker) probed(proxyID string, err error, maxFailures int) (HealthStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.proxies[proxyID]
+	if ok && h.state == HealthRetired {
+		return HealthStatus{}, false
+	}
+
+	if err == nil {
+		if !ok || (h.state == HealthHealthy && h.failures == 0) {
+			return HealthStatus{}, false
+		}
+		t.restore(proxyID)
+		return HealthStatus{ProxyID: proxyID, State: HealthHealthy}, true
+	}
+
+	h = t.entry(proxyID)
+	if h.state == HealthQuarantined {
+		// Re-probes decide when it comes back
+		return HealthStatus{}, false
+	}
+
+	h.failures++
+	h.lastError = err.Error()
+	if h.failures >= max(maxFailures, 1) {
+		t.quarantine(proxyID, h, fmt.Sprintf("%d failed health checks: %s", h.failures, h.lastError))
+	} else if h.state == HealthHealthy {
+		h.state = HealthSuspect
+		h.since = time.Now()
+	}
+	return newHealthStatus(proxyID, h), true
+}
+
+// reprobed records the result of a scheduled re-probe. A proxy that is still
+// failing is probed again after a longer wait, or retired once it has been
+// quarantined for RetireAfter.
+func (t *healthTracker) reprobed(proxyID string, generation uint64, err error) (HealthStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.proxies[proxyID]
+	if t.closed || !ok || h.state != HealthQuarantined || h.generation != generation {
+		// The proxy recovered or was removed while the probe ran, or the
+		// tracker was closed
+		return HealthStatus{}, false
+	}
+
+	if err == nil {
+		t.restore(proxyID)
+		return HealthStatus{ProxyID: proxyID, State: HealthHealthy}, true
+	}
+
+	h.failures++
+	h.lastError = err.Error()
+
+	now := time.Now()
+	if t.config.RetireAfter > 0 && now.Sub(h.since) >= t.config.RetireAfter {
+		h.state = HealthRetired
+		h.since = now
+		h.nextProbe = time.Time{}
+		return newHealthStatus(proxyID, h), true
+	}
+
+	h.backoff = min(h.backoff*2, t.config.MaxBackoff)
+	t.schedule(proxyID, h)
+	return newHealthStatus(proxyID, h), true
+}
+
+// quarantine takes a proxy out of rotation and starts re-probing it. The caller holds t.mu.
+func (t *healthTracker) quarantine(proxyID string, h *proxyHealth, reason string) {
+	h.state = HealthQuarantined
+	h.since = time.Now()
+	h.lastError = reason
+	t.startProbing(proxyID, h)
+}
+
+// startProbing schedules the first re-probe of a new quarantine. The caller holds t.mu.
+func (t *healthTracker) startProbing(proxyID string, h *proxyHealth) {
+	t.quarantines++
+	h.generation = t.quarantines
+	h.backoff = t.config.InitialBackoff
+	t.schedule(proxyID, h)
+}
+
+// schedule arms the timer for a quarantined proxy's next re-probe. The caller holds t.mu.
+func (t *healthTracker) schedule(proxyID string, h *proxyHealth) {
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+
+	h.nextProbe = time.Now().Add(h.backoff)
+	if t.probe == nil || t.closed {
+		return
+	}
+
+	generation := h.generation
+	h.timer = time.AfterFunc(h.backoff, func() {
+		t.probe(proxyID, generation)
+	})
+}
+
+// restore returns a proxy to rotation with a clean record. The caller holds t.mu.
+func (t *healthTracker) restore(proxyID string) {
+	if h, ok := t.proxies[proxyID]; ok && h.timer != nil {
+		h.timer.Stop()
+	}
+	delete(t.proxies, proxyID)
+}
+
+// close stops every scheduled re-probe and cancels those in progress.
+// Health is still tracked, but quarantined proxies are no longer re-probed.
+func (t *healthTracker) close() {
+	t.mu.Lock()
+	t.closed = true
+	for _, h := range t.proxies {
+		if h.timer != nil {
+			h.timer.Stop()
+			h.timer = nil
+		}
+	}
+	t.mu.Unlock()
+
+	t.cancel()
+}
+
+// forget drops the health of a proxy, returning it to the healthy state
+func (t *healthTracker) forget(proxyID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.restore(proxyID)
+}
+
+// status returns a proxy's health
+func (t *healthTracker) status(proxyID string) HealthStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	h, ok := t.proxies[proxyID]
+	if !ok {
+		return HealthStatus{ProxyID: proxyID, State: HealthHealthy}
+	}
+	return newHealthStatus(proxyID, h)
+}
+
+// snapshot returns the health of every proxy with a record, sorted by proxy ID
+func (t *healthTracker) snapshot() []HealthStatus {
+	t.mu.RLock()
+	statuses := make([]HealthStatus, 0, len(t.proxies))
+	for id, h := range t.proxies {
+		if h.clean() {
+			// Passive checks track every proxy with traffic; only problems are reported
+			continue
+		}
+		statuses = append(statuses, newHealthStatus(id, h))
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ProxyID < statuses[j].ProxyID
+	})
+	return statuses
+}
+
+// clean reports whether a proxy is healthy with no failures on record
+func (h *proxyHealth) clean() bool {
+	return (h.state == "" || h.state == HealthHealthy) && h.failures == 0 && h.consecutive == 0 && h.failed == 0
+}
+
+// newHealthStatus converts tracked health into a status entry. The caller holds t.mu.
+func newHealthStatus(proxyID string, h *proxyHealth) HealthStatus {
+	status := HealthStatus{
+		ProxyID:           proxyID,
+		State:             h.state,
+		Failures:          h.failures,
+		ConsecutiveErrors: h.consecutive,
+		RecentRequests:    h.total,
+		RecentFailures:    h.failed,
+		LastError:         h.lastError,
+		Since:             h.since,
+	}
+	if h.state == HealthQuarantined {
+		status.NextProbe = h.nextProbe
+	}
+	return status
+}
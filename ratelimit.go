@@ -2,8 +2,13 @@ package lashes
 
 import (
 	"context"
+	"errors"
+	"math"
+	"net/http"
 	"sync"
+	"time"
 
+	"github.com/greysquirr3l/lashes/internal/rotation"
 	"golang.org/x/time/rate"
 )
 
@@ -22,20 +27,112 @@ type RateLimiter interface {
 	SetBurst(burst int)
 }
 
+// maxRateLimitPoll caps how long GetProxyWait sleeps before checking again
+const maxRateLimitPoll = time.Second
+
+// RateLimitConfig configures per-proxy rate limiting
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained rate allowed through each proxy.
+	// Zero leaves the overall per-proxy rate unlimited.
+	RequestsPerSecond float64
+
+	// Burst is the number of requests a proxy may take at once. Zero
+	// defaults to RequestsPerSecond rounded up, and at least one.
+	Burst int
+
+	// HostRequestsPerSecond and HostBurst set the default budget for requests
+	// from one proxy to one target host. Zero disables the default, so only
+	// hosts matched by HostRules are limited.
+	HostRequestsPerSecond float64
+	HostBurst             int
+
+	// HostRules override the default per-host budget for matching hosts.
+	// Exact host names take precedence over wildcards.
+	HostRules []HostRateRule
+
+	// HostIdleTimeout is how long an unused per-host limiter is kept
+	HostIdleTimeout time.Duration
+
+	// MaxHostLimiters caps the number of per-host limiters kept in memory
+	MaxHostLimiters int
+
+	// Adaptive tunes how limits back off on throttling responses;
+	// unset fields use DefaultAdaptiveRateConfig
+	Adaptive AdaptiveRateConfig
+
+	// DisableAdaptive turns off backing off on throttling responses
+	DisableAdaptive bool
+}
+
+// RateLimitProvider is implemented by rotators that can skip proxies
+// that have used up their request budget
+type RateLimitProvider interface {
+	// UseRateLimit installs a new ProxyRateLimiter, replacing any existing one.
+	// A burst of zero defaults to the rate rounded up.
+	UseRateLimit(requestsPerSecond float64, burst int) *ProxyRateLimiter
+
+	// RateLimits returns the installed limiter, or nil if rate limiting is disabled
+	RateLimits() *ProxyRateLimiter
+
+	// GetProxyWait is like GetProxy but blocks until a proxy has capacity
+	// or the context is done
+	GetProxyWait(ctx context.Context) (*Proxy, error)
+
+	// SetProxyRateLimit stores a per-proxy override with the proxy and applies it.
+	// Zero values restore the limiter defaults.
+	SetProxyRateLimit(ctx context.Context, proxyID string, requestsPerSecond float64, burst int) error
+}
+
+var _ RateLimitProvider = (*rotator)(nil)
+
 // ProxyRateLimiter manages rate limiting for individual proxies
+// and, when host rules are configured, for each proxy and target host
 type ProxyRateLimiter struct {
 	limiters sync.Map // map[string]*rate.Limiter
 	defaults struct {
 		limit rate.Limit
 		burst int
 	}
+	hosts    *hostLimiters
+	adaptive *adaptiveLimits // nil when adaptive limiting is disabled
 }
 
-// NewProxyRateLimiter creates a new rate limiter for proxies
+// NewProxyRateLimiter creates a new rate limiter for proxies. A burst of zero
+// defaults to the rate rounded up, and at least one.
 func NewProxyRateLimiter(requestsPerSecond float64, burst int) *ProxyRateLimiter {
 	prl := &ProxyRateLimiter{}
 	prl.defaults.limit = rate.Limit(requestsPerSecond)
-	prl.defaults.burst = burst
+	prl.defaults.burst = defaultBurst(prl.defaults.limit, burst)
+	prl.hosts = newHostLimiters(newHostRules(nil, nil), 0, 0)
+	prl.adaptive = newAdaptiveLimits(DefaultAdaptiveRateConfig())
+	prl.hosts.onEvict = prl.adaptive.drop
+	return prl
+}
+
+// NewProxyRateLimiterFromConfig creates a rate limiter with per-proxy
+// and per-(proxy, host) budgets
+func NewProxyRateLimiterFromConfig(config RateLimitConfig) *ProxyRateLimiter {
+	limit := rate.Limit(config.RequestsPerSecond)
+	if config.RequestsPerSecond <= 0 {
+		limit = rate.Inf
+	}
+
+	var fallback *HostRateRule
+	if config.HostRequestsPerSecond > 0 {
+		fallback = &HostRateRule{
+			RequestsPerSecond: config.HostRequestsPerSecond,
+			Burst:             config.HostBurst,
+		}
+	}
+
+	prl := &ProxyRateLimiter{}
+	prl.defaults.limit = limit
+	prl.defaults.burst = defaultBurst(limit, config.Burst)
+	prl.hosts = newHostLimiters(newHostRules(config.HostRules, fallback), config.HostIdleTimeout, config.MaxHostLimiters)
+	if !config.DisableAdaptive {
+		prl.adaptive = newAdaptiveLimits(config.Adaptive)
+		prl.hosts.onEvict = prl.adaptive.drop
+	}
 	return prl
 }
 
@@ -50,11 +147,198 @@ func (prl *ProxyRateLimiter) GetLimiter(proxyID string) *rate.Limiter {
 	}
 
 	// Create new limiter with default settings
-	limiter := rate.NewLimiter(prl.defaults.limit, prl.defaults.burst)
-	prl.limiters.Store(proxyID, limiter)
+	limiter, _ := prl.limiters.LoadOrStore(proxyID, rate.NewLimiter(prl.defaults.limit, prl.defaults.burst))
+	return limiter.(*rate.Limiter)
+}
+
+// limiterFor returns a proxy's limiter with the proxy's stored override applied
+func (prl *ProxyRateLimiter) limiterFor(proxy *Proxy) *rate.Limiter {
+	limiter := prl.GetLimiter(proxy.ID)
+	if prl.adaptive != nil && prl.adaptive.adjusted(hostLimiterKey(proxy.ID, "")) {
+		// The override is restored as the limit recovers
+		return limiter
+	}
+	if proxy.RateLimit > 0 && limiter.Limit() != rate.Limit(proxy.RateLimit) {
+		limiter.SetLimit(rate.Limit(proxy.RateLimit))
+	}
+	if proxy.RateBurst > 0 && limiter.Burst() != proxy.RateBurst {
+		limiter.SetBurst(proxy.RateBurst)
+	}
 	return limiter
 }
 
+// ready reports whether a proxy has a token available for a target host,
+// without taking it. An empty host only checks the proxy's own budget.
+func (prl *ProxyRateLimiter) ready(proxy *Proxy, host string) bool {
+	if prl.blockedFor(proxy.ID, host) > 0 || !limiterReady(prl.limiterFor(proxy)) {
+		return false
+	}
+	if hostLimiter := prl.hosts.lookup(proxy.ID, host); hostLimiter != nil {
+		return limiterReady(hostLimiter)
+	}
+	return true
+}
+
+// allow takes a token for a proxy and target host if both budgets have one
+func (prl *ProxyRateLimiter) allow(proxy *Proxy, host string) bool {
+	return prl.take(prl.limiterFor(proxy), proxy.ID, host)
+}
+
+// take reserves a token from the proxy limiter and, if a rule applies, the
+// host limiter, releasing both unless each is available right away
+func (prl *ProxyRateLimiter) take(proxyLimiter *rate.Limiter, proxyID, host string) bool {
+	if prl.blockedFor(proxyID, host) > 0 {
+		return false
+	}
+
+	now := time.Now()
+
+	proxyRes := proxyLimiter.ReserveN(now, 1)
+	if !proxyRes.OK() || proxyRes.DelayFrom(now) > 0 {
+		proxyRes.CancelAt(now)
+		return false
+	}
+
+	hostLimiter := prl.hosts.get(proxyID, host)
+	if hostLimiter == nil {
+		return true
+	}
+
+	hostRes := hostLimiter.ReserveN(now, 1)
+	if !hostRes.OK() || hostRes.DelayFrom(now) > 0 {
+		hostRes.CancelAt(now)
+		proxyRes.CancelAt(now)
+		return false
+	}
+	return true
+}
+
+// delay estimates how long until a proxy has a token available for a host.
+// It reports false if a limiter involved never refills.
+func (prl *ProxyRateLimiter) delay(proxy *Proxy, host string) (time.Duration, bool) {
+	wait, ok := limiterDelay(prl.limiterFor(proxy))
+	if !ok {
+		return 0, false
+	}
+	wait = max(wait, prl.blockedFor(proxy.ID, host))
+
+	if hostLimiter := prl.hosts.lookup(proxy.ID, host); hostLimiter != nil {
+		hostWait, ok := limiterDelay(hostLimiter)
+		if !ok {
+			return 0, false
+		}
+		wait = max(wait, hostWait)
+	}
+	return wait, true
+}
+
+// limiterReady reports whether a limiter has a token available
+func limiterReady(limiter *rate.Limiter) bool {
+	return limiter.Limit() == rate.Inf || limiter.Tokens() >= 1
+}
+
+// limiterDelay estimates how long until a limiter has a token available.
+// It reports false if the limiter never refills.
+func limiterDelay(limiter *rate.Limiter) (time.Duration, bool) {
+	tokens := limiter.Tokens()
+	if limiter.Limit() == rate.Inf || tokens >= 1 {
+		return 0, true
+	}
+	if limiter.Limit() <= 0 || limiter.Burst() < 1 {
+		return 0, false
+	}
+	return time.Duration((1 - tokens) / float64(limiter.Limit()) * float64(time.Second)), true
+}
+
+// replaceLimiter gives a proxy a fresh, full limiter built from its
+// stored override, falling back to the defaults for unset fields
+func (prl *ProxyRateLimiter) replaceLimiter(proxy *Proxy) {
+	limit, burst := prl.defaults.limit, prl.defaults.burst
+	if proxy.RateLimit > 0 {
+		limit = rate.Limit(proxy.RateLimit)
+	}
+	if proxy.RateBurst > 0 {
+		burst = proxy.RateBurst
+	}
+	prl.limiters.Store(proxy.ID, rate.NewLimiter(limit, defaultBurst(limit, burst)))
+}
+
+// defaultBurst returns burst, or for a finite rate with no burst, the rate
+// rounded up. A limiter with a burst of zero never admits a request.
+func defaultBurst(limit rate.Limit, burst int) int {
+	if burst > 0 || limit <= 0 || limit == rate.Inf {
+		return burst
+	}
+	return max(1, int(math.Ceil(float64(limit))))
+}
+
+// forget drops the limiters of a proxy that has left the rotation
+func (prl *ProxyRateLimiter) forget(proxyID string) {
+	prl.limiters.Delete(proxyID)
+	prl.hosts.forget(proxyID)
+	if prl.adaptive != nil {
+		prl.adaptive.forget(proxyID)
+	}
+}
+
+// blockedFor returns how long a proxy must still sit out for a host
+func (prl *ProxyRateLimiter) blockedFor(proxyID, host string) time.Duration {
+	if prl.adaptive == nil {
+		return 0
+	}
+	return prl.adaptive.blockedFor(proxyID, host)
+}
+
+// adaptiveTarget returns the limiter that governs requests from a proxy to
+// a host, and the key its adjustments are stored under
+func (prl *ProxyRateLimiter) adaptiveTarget(proxyID, host string) (string, *rate.Limiter) {
+	if hostLimiter := prl.hosts.get(proxyID, host); hostLimiter != nil {
+		return hostLimiterKey(proxyID, host), hostLimiter
+	}
+	return hostLimiterKey(proxyID, ""), prl.GetLimiter(proxyID)
+}
+
+// ReportThrottled tells the limiter that a target host throttled a proxy.
+// The governing limit is cut, and the proxy sits out for the host for
+// retryAfter, or the configured default penalty when retryAfter is zero.
+// It does nothing when adaptive limiting is disabled.
+func (prl *ProxyRateLimiter) ReportThrottled(proxyID, host string, retryAfter time.Duration) {
+	if prl.adaptive == nil {
+		return
+	}
+
+	if retryAfter <= 0 {
+		retryAfter = prl.adaptive.config.DefaultPenalty
+	}
+	prl.adaptive.block(hostLimiterKey(proxyID, host), retryAfter)
+
+	key, limiter := prl.adaptiveTarget(proxyID, host)
+	prl.adaptive.decrease(key, limiter)
+}
+
+// ReportSuccess tells the limiter that a request from a proxy to a host
+// succeeded, letting a reduced limit recover
+func (prl *ProxyRateLimiter) ReportSuccess(proxyID, host string) {
+	if prl.adaptive == nil {
+		return
+	}
+
+	key := hostLimiterKey(proxyID, "")
+	if prl.hosts.lookup(proxyID, host) != nil {
+		key = hostLimiterKey(proxyID, host)
+	}
+	prl.adaptive.increase(key)
+}
+
+// observe feeds a response from the rotating client into the adaptive limits
+func (prl *ProxyRateLimiter) observe(proxyID, host string, resp *http.Response) {
+	if retryAfter, throttled := isThrottled(resp); throttled {
+		prl.ReportThrottled(proxyID, host, retryAfter)
+	} else if resp.StatusCode < http.StatusBadRequest {
+		prl.ReportSuccess(proxyID, host)
+	}
+}
+
 // Wait blocks until the rate limit for a proxy allows an event to happen
 func (prl *ProxyRateLimiter) Wait(ctx context.Context, proxyID string) error {
 	return prl.GetLimiter(proxyID).Wait(ctx)
@@ -65,15 +349,120 @@ func (prl *ProxyRateLimiter) Allow(proxyID string) bool {
 	return prl.GetLimiter(proxyID).Allow()
 }
 
-// SetProxyLimit updates the rate limit for a specific proxy
+// AllowHost reports whether a request from a proxy to a target host may
+// happen now, taking a token from both budgets if so
+func (prl *ProxyRateLimiter) AllowHost(proxyID, host string) bool {
+	return prl.take(prl.GetLimiter(proxyID), proxyID, host)
+}
+
+// WaitHost blocks until both the proxy's budget and its budget for the
+// target host allow a request, or the context is done
+func (prl *ProxyRateLimiter) WaitHost(ctx context.Context, proxyID, host string) error {
+	if err := prl.GetLimiter(proxyID).Wait(ctx); err != nil {
+		return err
+	}
+	if hostLimiter := prl.hosts.get(proxyID, host); hostLimiter != nil {
+		return hostLimiter.Wait(ctx)
+	}
+	return nil
+}
+
+// SetProxyLimit updates the rate limit for a specific proxy in memory. A burst
+// of zero defaults to the rate rounded up. Use the rotator's SetProxyRateLimit
+// to store the override with the proxy.
 func (prl *ProxyRateLimiter) SetProxyLimit(proxyID string, limit rate.Limit, burst int) {
 	limiter := prl.GetLimiter(proxyID)
 	limiter.SetLimit(limit)
-	limiter.SetBurst(burst)
+	limiter.SetBurst(defaultBurst(limit, burst))
 }
 
-// UseRateLimit applies rate limiting to a rotator
+// UseRateLimit applies rate limiting to a rotator.
+// Proxy selection skips proxies without a free token; GetProxyWait and the
+// rotating client wait for one instead.
 func (r *rotator) UseRateLimit(requestsPerSecond float64, burst int) *ProxyRateLimiter {
 	rateLimiter := NewProxyRateLimiter(requestsPerSecond, burst)
+	r.limiter.Store(rateLimiter)
 	return rateLimiter
 }
+
+// RateLimits returns the rotator's rate limiter, or nil if none is installed
+func (r *rotator) RateLimits() *ProxyRateLimiter {
+	return r.limiter.Load()
+}
+
+// SetProxyRateLimit stores a per-proxy rate limit override and applies it
+func (r *rotator) SetProxyRateLimit(ctx context.Context, proxyID string, requestsPerSecond float64, burst int) error {
+	if requestsPerSecond < 0 || burst < 0 {
+		return ErrInvalidOptions
+	}
+
+	proxy, err := r.repo.GetByID(ctx, proxyID)
+	if err != nil {
+		return err
+	}
+
+	proxy.RateLimit = requestsPerSecond
+	proxy.RateBurst = burst
+	if err := r.repo.Update(ctx, proxy); err != nil {
+		return err
+	}
+
+	if limiter := r.limiter.Load(); limiter != nil {
+		limiter.replaceLimiter(proxy)
+	}
+	return nil
+}
+
+// GetProxyWait returns the next proxy, waiting for one to have capacity
+// when every candidate is rate limited
+func (r *rotator) GetProxyWait(ctx context.Context) (*Proxy, error) {
+	return r.nextProxyWait(ctx, selection{})
+}
+
+// nextProxyWait is nextProxy, waiting out rate limits until ctx is done
+func (r *rotator) nextProxyWait(ctx context.Context, sel selection) (*Proxy, error) {
+	return r.selectWait(ctx, r.strategy, r.repo.List, sel)
+}
+
+// selectWait is selectProxy over the proxies returned by list, waiting out
+// rate limits until ctx is done. The list is reloaded after every wait.
+func (r *rotator) selectWait(ctx context.Context, strategy rotation.Strategy, list func(context.Context) ([]*Proxy, error), sel selection) (*Proxy, error) {
+	for {
+		proxies, err := list(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		proxy, err := r.selectProxy(ctx, strategy, proxies, sel)
+		if !errors.Is(err, ErrRateLimited) {
+			return proxy, err
+		}
+
+		timer := time.NewTimer(r.rateLimitDelay(proxies, sel))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// rateLimitDelay estimates how long until one of the proxies has a token
+func (r *rotator) rateLimitDelay(proxies []*Proxy, sel selection) time.Duration {
+	limiter := r.limiter.Load()
+	if limiter == nil {
+		return 0
+	}
+
+	wait := maxRateLimitPoll
+	for _, proxy := range proxies {
+		if !sel.matches(proxy) {
+			continue
+		}
+		if delay, ok := limiter.delay(proxy, sel.TargetHost); ok && delay < wait {
+			wait = delay
+		}
+	}
+	return wait
+}
@@ -0,0 +1,245 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRotatorRateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	newLimitedRotator := func(t *testing.T, config RateLimitConfig, proxies int) *rotator {
+		t.Helper()
+
+		opts := DefaultOptions()
+		opts.ValidateOnStart = false
+		opts.RateLimit = &config
+
+		r, err := newRotator(opts)
+		if err != nil {
+			t.Fatalf("newRotator failed: %v", err)
+		}
+		for i := 0; i < proxies; i++ {
+			if err := r.AddProxy(ctx, fmt.Sprintf("http://proxy%d.example.com:8080", i), HTTP); err != nil {
+				t.Fatalf("AddProxy failed: %v", err)
+			}
+		}
+		return r
+	}
+
+	t.Run("Exhausted proxies are skipped", func(t *testing.T) {
+		r := newLimitedRotator(t, RateLimitConfig{RequestsPerSecond: 0.01, Burst: 1}, 2)
+
+		seen := map[string]bool{}
+		for i := 0; i < 2; i++ {
+			proxy, err := r.GetProxy(ctx)
+			if err != nil {
+				t.Fatalf("GetProxy %d failed: %v", i, err)
+			}
+			seen[proxy.ID] = true
+		}
+		if len(seen) != 2 {
+			t.Errorf("GetProxy returned %d distinct proxies, want 2", len(seen))
+		}
+
+		if _, err := r.GetProxy(ctx); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("GetProxy() error = %v, want %v", err, ErrRateLimited)
+		}
+	})
+
+	t.Run("GetProxyWait blocks until a token is free", func(t *testing.T) {
+		r := newLimitedRotator(t, RateLimitConfig{RequestsPerSecond: 20, Burst: 1}, 1)
+
+		if _, err := r.GetProxyWait(ctx); err != nil {
+			t.Fatalf("GetProxyWait failed: %v", err)
+		}
+
+		start := time.Now()
+		if _, err := r.GetProxyWait(ctx); err != nil {
+			t.Fatalf("GetProxyWait failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+			t.Errorf("GetProxyWait returned after %v, want it to wait for the next token", elapsed)
+		}
+
+		shortCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		if _, err := r.GetProxyWait(shortCtx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("GetProxyWait() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("Zero burst defaults to the rate", func(t *testing.T) {
+		r := newLimitedRotator(t, RateLimitConfig{RequestsPerSecond: 2.5}, 1)
+
+		for i := 0; i < 3; i++ {
+			if _, err := r.GetProxy(ctx); err != nil {
+				t.Fatalf("GetProxy %d failed: %v", i, err)
+			}
+		}
+		if _, err := r.GetProxy(ctx); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("GetProxy() error = %v after a burst of 3, want %v", err, ErrRateLimited)
+		}
+
+		limiter := r.UseRateLimit(10, 0)
+		if burst := limiter.GetLimiter("proxy").Burst(); burst != 10 {
+			t.Errorf("UseRateLimit burst = %d, want 10", burst)
+		}
+	})
+
+	t.Run("Per-proxy overrides are stored with the proxy", func(t *testing.T) {
+		r := newLimitedRotator(t, RateLimitConfig{RequestsPerSecond: 0.01, Burst: 1}, 1)
+
+		proxies, err := r.List(ctx)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		id := proxies[0].ID
+
+		if err := r.SetProxyRateLimit(ctx, id, 0.01, 3); err != nil {
+			t.Fatalf("SetProxyRateLimit failed: %v", err)
+		}
+
+		stored, err := r.repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if stored.RateLimit != 0.01 || stored.RateBurst != 3 {
+			t.Errorf("stored override = %v/%d, want 0.01/3", stored.RateLimit, stored.RateBurst)
+		}
+
+		for i := 0; i < 3; i++ {
+			if _, err := r.GetProxy(ctx); err != nil {
+				t.Fatalf("GetProxy %d failed: %v", i, err)
+			}
+		}
+		if _, err := r.GetProxy(ctx); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("GetProxy() error = %v, want %v", err, ErrRateLimited)
+		}
+
+		if err := r.SetProxyRateLimit(ctx, "missing", 1, 1); err == nil {
+			t.Error("SetProxyRateLimit() for an unknown proxy succeeded, want error")
+		}
+	})
+}
+
+func TestHostRules(t *testing.T) {
+	t.Run("Patterns", func(t *testing.T) {
+		testCases := []struct {
+			pattern string
+			host    string
+			want    bool
+		}{
+			{"api.example.com", "api.example.com", true},
+			{"api.example.com", "API.Example.com.", true},
+			{"api.example.com", "www.example.com", false},
+			{"*.example.com", "api.example.com", true},
+			{"*.example.com", "a.b.example.com", true},
+			{"*.example.com", "example.com", false},
+			{"*.example.com", "badexample.com", false},
+		}
+
+		for _, tc := range testCases {
+			if got := hostMatches(tc.pattern, tc.host); got != tc.want {
+				t.Errorf("hostMatches(%q, %q) = %v, want %v", tc.pattern, tc.host, got, tc.want)
+			}
+		}
+	})
+
+	t.Run("Precedence", func(t *testing.T) {
+		rules := newHostRules([]HostRateRule{
+			{Host: "*.example.com", RequestsPerSecond: 1},
+			{Host: "*.api.example.com", RequestsPerSecond: 2},
+			{Host: "login.api.example.com", RequestsPerSecond: 3},
+		}, &HostRateRule{RequestsPerSecond: 4})
+
+		for host, want := range map[string]float64{
+			"login.api.example.com": 3,
+			"v1.api.example.com":    2,
+			"www.example.com":       1,
+			"other.test":            4,
+		} {
+			rule, ok := rules.match(host)
+			if !ok || rule.RequestsPerSecond != want {
+				t.Errorf("match(%q) = %+v, %v; want a rule with %v requests per second", host, rule, ok, want)
+			}
+		}
+	})
+
+	t.Run("Idle and excess limiters are evicted", func(t *testing.T) {
+		rules := newHostRules(nil, &HostRateRule{RequestsPerSecond: 1, Burst: 1})
+
+		store := newHostLimiters(rules, time.Hour, 2)
+		for _, host := range []string{"a.test", "b.test", "c.test"} {
+			store.get("proxy", host)
+		}
+		if n := store.len(); n != 2 {
+			t.Errorf("store holds %d limiters, want 2", n)
+		}
+		if store.lookup("proxy", "a.test") != nil {
+			t.Error("least recently used limiter was not evicted")
+		}
+
+		store = newHostLimiters(rules, 10*time.Millisecond, 100)
+		store.get("proxy", "a.test")
+		time.Sleep(20 * time.Millisecond)
+		store.get("proxy", "b.test")
+		if store.lookup("proxy", "a.test") != nil {
+			t.Error("idle limiter was not evicted")
+		}
+	})
+}
+
+func TestRotatingClientHostRateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.RateLimit = &RateLimitConfig{
+		HostRules: []HostRateRule{{Host: "*.example.com", RequestsPerSecond: 0.01, Burst: 1}},
+	}
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var hits int32
+	srv := newTestProxyServer(t, "only", &hits)
+	if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+		t.Fatalf("AddProxy failed: %v", err)
+	}
+
+	get := func(target string) error {
+		reqCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := r.RotatingClient().Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	if err := get("http://a.example.com/"); err != nil {
+		t.Fatalf("first request failed: %v", err)
+	}
+	if err := get("http://a.example.com/again"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("second request to the same host: error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Other hosts have their own budget, and unmatched hosts aren't limited
+	for _, target := range []string{"http://b.example.com/", "http://other.test/", "http://other.test/"} {
+		if err := get(target); err != nil {
+			t.Errorf("request to %s failed: %v", target, err)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,21 +18,34 @@ import (
 	"github.com/greysquirr3l/lashes/internal/validation"
 )
 
-// BackoffStrategy defines how retry delays should be calculated
-type BackoffStrategy interface {
-	// NextDelay returns the delay to wait before the next retry attempt
-	NextDelay(attempt int) time.Duration
-}
-
 // Remove unused code to fix linter warnings
 // The commented out code could be reimplemented if needed in the future
 
 // rotator is the implementation of the ProxyRotator interface
 type rotator struct {
-	repo     domain.ProxyRepository
-	strategy rotation.Strategy
-	opts     Options
-	metrics  MetricsCollector
+	repo       domain.ProxyRepository
+	pools      domain.PoolRepository
+	sessions   domain.SessionRepository
+	bans       *banRegistry
+	detector   *banDetector
+	health     *healthTracker
+	classifier ResponseClassifier
+	strategy   rotation.Strategy
+	opts       Options
+	metrics    MetricsCollector
+	transport  *client.RotatingTransport
+	breakers   atomic.Pointer[CircuitBreakerManager]
+	limiter    atomic.Pointer[ProxyRateLimiter]
+
+	// sessionPurge is when expired sessions were last deleted, in Unix nanoseconds
+	sessionPurge atomic.Int64
+
+	// sessionPins serialises pinning a session to a new proxy
+	sessionPins sessionLocks
+
+	// poolStrategies holds a separate strategy instance for each pool
+	poolStrategies map[string]rotation.Strategy
+	poolMu         sync.Mutex
 }
 
 func newRotator(opts Options) (*rotator, error) {
@@ -52,47 +67,184 @@ func newRotator(opts Options) (*rotator, error) {
 		})
 	}
 
-	strategy, err := rotation.NewStrategy(opts.Strategy)
+	strategy, err := newStrategy(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the repository's own pool storage when it has one
+	pools, ok := repo.(domain.PoolRepository)
+	if !ok {
+		pools = repository.NewMemoryPoolRepository()
+	}
+	sessions, ok := repo.(domain.SessionRepository)
+	if !ok {
+		sessions = repository.NewMemorySessionRepository()
+	}
+	banRepo, ok := repo.(domain.BanRepository)
+	if !ok {
+		banRepo = repository.NewMemoryBanRepository()
+	}
+	bans, err := loadBanRegistry(context.Background(), banRepo)
 	if err != nil {
 		return nil, err
 	}
 
+	var detector *banDetector
+	if opts.BanDetection != nil {
+		if detector, err = newBanDetector(*opts.BanDetection); err != nil {
+			return nil, err
+		}
+	}
+
+	quarantine := DefaultQuarantineConfig()
+	if opts.Quarantine != nil {
+		quarantine = *opts.Quarantine
+	}
+	if quarantine.ProbeURL == "" {
+		quarantine.ProbeURL = opts.TestURL
+	}
+
 	r := &rotator{
-		repo:     repo,
-		strategy: strategy,
-		opts:     opts,
-		metrics:  NewMetricsCollector(repo),
+		repo:           repo,
+		pools:          pools,
+		sessions:       sessions,
+		bans:           bans,
+		detector:       detector,
+		health:         newHealthTracker(quarantine, opts.PassiveHealth),
+		classifier:     opts.ResponseClassifier,
+		strategy:       strategy,
+		opts:           opts,
+		metrics:        NewMetricsCollector(repo),
+		poolStrategies: make(map[string]rotation.Strategy),
 	}
 
+	// Quarantined proxies stay out of rotation across restarts
+	r.health.probe = r.reprobe
+	proxies, err := repo.List(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load proxy health: %w", err)
+	}
+	r.health.load(proxies)
+
+	if opts.CircuitBreaker != nil {
+		r.breakers.Store(NewCircuitBreakerManager(*opts.CircuitBreaker))
+	}
+	if opts.RateLimit != nil {
+		r.limiter.Store(NewProxyRateLimiterFromConfig(*opts.RateLimit))
+	}
+
+	r.transport = client.NewRotatingTransport(&rotatorSource{r: r}, client.Options{
+		Timeout:         r.opts.RequestTimeout,
+		MaxRetries:      r.opts.MaxRetries,
+		VerifyCerts:     true,
+		FollowRedirects: true,
+	}, r.retryPolicy())
+
 	return r, nil
 }
 
+// retryPolicy builds the rotating transport's retry policy from the options
+func (r *rotator) retryPolicy() client.RetryPolicy {
+	backoff := r.opts.Backoff
+	if backoff == nil {
+		backoff = ConstantBackoff{Delay: r.opts.RetryDelay}
+	}
+
+	return client.RetryPolicy{
+		MaxRetries:         r.opts.MaxRetries,
+		Backoff:            backoff,
+		RetryOnStatus:      r.opts.RetryOnStatus,
+		RetryNonIdempotent: r.opts.RetryNonIdempotent,
+		MaxRetryAfter:      r.opts.MaxRetryAfter,
+	}
+}
+
 // GetProxy returns the next proxy according to the strategy
 func (r *rotator) GetProxy(ctx context.Context) (*domain.Proxy, error) {
+	return r.nextProxy(ctx, selection{})
+}
+
+// selection describes the request a proxy is being selected for.
+// It is rotation.Criteria with the rotator's selection helpers.
+type selection rotation.Criteria
+
+// without returns a copy of the selection that also excludes id
+func (s selection) without(id string) selection {
+	s.ExcludeIDs = append(s.ExcludeIDs[:len(s.ExcludeIDs):len(s.ExcludeIDs)], id)
+	return s
+}
+
+// matches reports whether a proxy may be selected at all
+func (s selection) matches(proxy *domain.Proxy) bool {
+	return proxy.GetEnabled() && rotation.Criteria(s).Matches(proxy)
+}
+
+// next asks the strategy for a proxy, describing the request to strategies that use it
+func (s selection) next(ctx context.Context, strategy rotation.Strategy, candidates []*domain.Proxy) (*domain.Proxy, error) {
+	if cs, ok := strategy.(rotation.CriteriaStrategy); ok {
+		return cs.NextFor(ctx, candidates, rotation.Criteria(s))
+	}
+	return strategy.Next(ctx, candidates)
+}
+
+// nextProxy returns the next proxy according to the strategy
+func (r *rotator) nextProxy(ctx context.Context, sel selection) (*domain.Proxy, error) {
 	proxies, err := r.repo.List(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(proxies) == 0 {
-		return nil, ErrNoProxiesAvailable
-	}
+	return r.selectProxy(ctx, r.strategy, proxies, sel)
+}
 
-	// Filter for only enabled proxies
-	var enabledProxies []*domain.Proxy
-	for _, proxy := range proxies {
-		if proxy.GetEnabled() {
-			enabledProxies = append(enabledProxies, proxy)
+// selectProxy picks a proxy from the available candidates using the given strategy
+func (r *rotator) selectProxy(ctx context.Context, strategy rotation.Strategy, proxies []*domain.Proxy, sel selection) (*domain.Proxy, error) {
+	breakers := r.breakers.Load()
+	if breakers != nil {
+		if retryAfter, ready := breakers.globalReady(); !ready {
+			return nil, &CircuitBreakerError{RetryAfter: retryAfter}
 		}
 	}
 
-	if len(enabledProxies) == 0 {
-		return nil, ErrNoProxiesAvailable
-	}
+	limiter := r.limiter.Load()
 
-	proxy, err := r.strategy.Next(ctx, enabledProxies)
-	if err != nil {
-		return nil, err
+	var proxy *domain.Proxy
+	for proxy == nil {
+		candidates, throttled := r.available(proxies, sel)
+		if len(candidates) == 0 {
+			if throttled {
+				return nil, ErrRateLimited
+			}
+			return nil, ErrNoProxiesAvailable
+		}
+
+		var err error
+		if proxy, err = sel.next(ctx, strategy, candidates); err != nil {
+			return nil, err
+		}
+
+		release := func() {}
+		if breakers != nil {
+			var allowed bool
+			if release, allowed = breakers.acquire(proxy.ID); !allowed {
+				// Another request took the breaker's last trial slot first
+				if retryAfter, ready := breakers.globalReady(); !ready {
+					return nil, &CircuitBreakerError{RetryAfter: retryAfter}
+				}
+				sel = sel.without(proxy.ID)
+				proxy = nil
+				continue
+			}
+		}
+
+		if limiter != nil && !limiter.allow(proxy, sel.TargetHost) {
+			// Another request took the proxy's last token first; the
+			// breakers' trial slots go to the next request instead
+			release()
+			sel = sel.without(proxy.ID)
+			proxy = nil
+		}
 	}
 
 	// Update last used timestamp
@@ -105,6 +257,28 @@ func (r *rotator) GetProxy(ctx context.Context) (*domain.Proxy, error) {
 	return proxy, nil
 }
 
+// available filters out proxies that must not be selected.
+// throttled reports whether any proxy was left out only by its rate limit.
+func (r *rotator) available(proxies []*domain.Proxy, sel selection) (candidates []*domain.Proxy, throttled bool) {
+	breakers := r.breakers.Load()
+	limiter := r.limiter.Load()
+
+	for _, proxy := range proxies {
+		if !sel.matches(proxy) || !r.health.inRotation(proxy.ID) || r.bans.banned(proxy.ID, sel.TargetHost) {
+			continue
+		}
+		if breakers != nil && !breakers.Ready(proxy.ID) {
+			continue
+		}
+		if limiter != nil && !limiter.ready(proxy, sel.TargetHost) {
+			throttled = true
+			continue
+		}
+		candidates = append(candidates, proxy)
+	}
+	return candidates, throttled
+}
+
 func (r *rotator) AddProxy(ctx context.Context, proxyURL string, proxyType domain.ProxyType) error {
 	// Use the proper URL parser
 	parsedURL, err := mock.ParseURL(proxyURL)
@@ -155,13 +329,30 @@ func (r *rotator) RemoveProxy(ctx context.Context, proxyURL string) error {
 
 	for _, proxy := range proxies {
 		if proxy.URL == proxyURL {
-			return r.repo.Delete(ctx, proxy.ID)
+			return r.removeProxy(ctx, proxy)
 		}
 	}
 
 	return repository.ErrProxyNotFound
 }
 
+// removeProxy deletes a proxy and drops the state kept for it
+func (r *rotator) removeProxy(ctx context.Context, proxy *domain.Proxy) error {
+	if err := r.repo.Delete(ctx, proxy.ID); err != nil {
+		return err
+	}
+	r.transport.Forget(proxy.ID)
+	if breakers := r.breakers.Load(); breakers != nil {
+		breakers.forget(proxy.ID)
+	}
+	if limiter := r.limiter.Load(); limiter != nil {
+		limiter.forget(proxy.ID)
+	}
+	r.bans.forget(ctx, proxy.ID)
+	r.health.forget(proxy.ID)
+	return nil
+}
+
 func (r *rotator) Client(ctx context.Context) (*http.Client, error) {
 	proxy, err := r.GetProxy(ctx)
 	if err != nil {
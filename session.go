@@ -0,0 +1,249 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+	"github.com/greysquirr3l/lashes/internal/repository"
+	"github.com/greysquirr3l/lashes/internal/rotation"
+)
+
+// DefaultSessionTTL is how long an idle session keeps its proxy
+const DefaultSessionTTL = time.Minute * 30
+
+// SessionManager pins sessions to a proxy so multi-step flows such as a
+// login followed by a checkout keep the same exit IP
+type SessionManager interface {
+	// GetProxyForSession returns the proxy pinned to a session. Unknown and
+	// expired sessions, and sessions whose proxy was removed, disabled or has
+	// an open circuit breaker, are pinned to the next proxy in the rotation.
+	GetProxyForSession(ctx context.Context, sessionKey string) (*Proxy, error)
+
+	// ReleaseSession ends a session so its next request starts afresh
+	ReleaseSession(ctx context.Context, sessionKey string) error
+}
+
+// Session related errors
+var (
+	ErrSessionNotFound   = repository.ErrSessionNotFound
+	ErrInvalidSessionKey = errors.New("session key cannot be empty")
+)
+
+var _ SessionManager = (*rotator)(nil)
+
+// sessionContextKey is the context key for WithSession
+type sessionContextKey struct{}
+
+// WithSession returns a context that makes the rotating client send the
+// request through the proxy pinned to sessionKey
+func WithSession(ctx context.Context, sessionKey string) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, sessionKey)
+}
+
+// SessionFromContext returns the session key set by WithSession
+func SessionFromContext(ctx context.Context) (string, bool) {
+	sessionKey, ok := ctx.Value(sessionContextKey{}).(string)
+	return sessionKey, ok
+}
+
+// GetProxyForSession returns the proxy pinned to a session, pinning one if needed
+func (r *rotator) GetProxyForSession(ctx context.Context, sessionKey string) (*Proxy, error) {
+	return r.sessionProxy(ctx, sessionKey, selection{}, nil, false)
+}
+
+// ReleaseSession ends a session
+func (r *rotator) ReleaseSession(ctx context.Context, sessionKey string) error {
+	if sessionKey == "" {
+		return ErrInvalidSessionKey
+	}
+	return r.sessions.DeleteSession(ctx, sessionKey)
+}
+
+// sessionProxy selects the session's proxy when it can still be used and
+// pins the session to a newly selected proxy otherwise. Proxies in exclude
+// are only avoided when re-pinning, so a retried request keeps its exit IP.
+// With wait set it waits out rate limits the way nextProxyWait does.
+func (r *rotator) sessionProxy(ctx context.Context, sessionKey string, sel selection, exclude []string, wait bool) (*Proxy, error) {
+	if sessionKey == "" {
+		return nil, ErrInvalidSessionKey
+	}
+
+	if proxy, ok, err := r.pinnedSession(ctx, sessionKey, sel, wait); ok {
+		return proxy, err
+	}
+
+	// Only one request pins the session; the others wait and use its proxy
+	unlock, err := r.sessionPins.lock(ctx, sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if proxy, ok, err := r.pinnedSession(ctx, sessionKey, sel, wait); ok {
+		return proxy, err
+	}
+
+	for _, id := range exclude {
+		sel = sel.without(id)
+	}
+
+	var proxy *Proxy
+	if wait {
+		proxy, err = r.nextProxyWait(ctx, sel)
+	} else {
+		proxy, err = r.nextProxy(ctx, sel)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.purgeSessions(ctx)
+
+	session := &domain.Session{Key: sessionKey, ProxyID: proxy.ID, ExpiresAt: time.Now().Add(r.sessionTTL())}
+	if err := r.sessions.SaveSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return proxy, nil
+}
+
+// pinnedSession selects the proxy the session is pinned to. It reports false
+// when the session is unknown or expired, or its proxy can't be used.
+func (r *rotator) pinnedSession(ctx context.Context, sessionKey string, sel selection, wait bool) (*Proxy, bool, error) {
+	session, err := r.sessions.GetSession(ctx, sessionKey)
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		return nil, false, nil
+	case err != nil:
+		return nil, true, err
+	case session.Expired(time.Now()):
+		return nil, false, nil
+	}
+
+	proxy, err := r.pinnedProxy(ctx, session.ProxyID, sel, wait)
+	if errors.Is(err, ErrNoProxiesAvailable) {
+		// The pinned proxy is gone, disabled or its breaker is open
+		return nil, false, nil
+	}
+	if err == nil {
+		err = r.touchSession(ctx, session)
+	}
+	return proxy, true, err
+}
+
+// pinnedProxy runs the usual selection checks against a single proxy.
+// It returns ErrNoProxiesAvailable when the proxy can't be used at all.
+func (r *rotator) pinnedProxy(ctx context.Context, proxyID string, sel selection, wait bool) (*Proxy, error) {
+	list := func(ctx context.Context) ([]*Proxy, error) {
+		proxy, err := r.repo.GetByID(ctx, proxyID)
+		if errors.Is(err, repository.ErrProxyNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []*Proxy{proxy}, nil
+	}
+
+	if wait {
+		return r.selectWait(ctx, pinnedStrategy{}, list, sel)
+	}
+
+	proxies, err := list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.selectProxy(ctx, pinnedStrategy{}, proxies, sel)
+}
+
+// touchSession extends a session once half of its TTL has passed,
+// so an active session doesn't write to the repository on every request
+func (r *rotator) touchSession(ctx context.Context, session *domain.Session) error {
+	ttl := r.sessionTTL()
+	if time.Until(session.ExpiresAt) > ttl/2 {
+		return nil
+	}
+
+	session.ExpiresAt = time.Now().Add(ttl)
+	return r.sessions.SaveSession(ctx, session)
+}
+
+// purgeSessions deletes expired sessions at most once per TTL
+func (r *rotator) purgeSessions(ctx context.Context) {
+	now := time.Now()
+	last := r.sessionPurge.Load()
+	if now.UnixNano()-last < int64(r.sessionTTL()) || !r.sessionPurge.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+
+	// Expired sessions are ignored on lookup, so a failed purge only costs space
+	_ = r.sessions.DeleteExpiredSessions(ctx, now)
+}
+
+// sessionTTL returns the configured session TTL or the default
+func (r *rotator) sessionTTL() time.Duration {
+	if r.opts.SessionTTL > 0 {
+		return r.opts.SessionTTL
+	}
+	return DefaultSessionTTL
+}
+
+// sessionLocks hands out a lock per session key and drops it once no
+// request holds or waits for it
+type sessionLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+}
+
+type sessionLock struct {
+	held chan struct{}
+	refs int
+}
+
+// lock waits for the session key's lock and returns the function that
+// unlocks it. It gives up when ctx is done.
+func (l *sessionLocks) lock(ctx context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*sessionLock)
+	}
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &sessionLock{held: make(chan struct{}, 1)}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	release := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if lock.refs--; lock.refs == 0 {
+			delete(l.locks, key)
+		}
+	}
+
+	select {
+	case lock.held <- struct{}{}:
+		return func() {
+			<-lock.held
+			release()
+		}, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
+
+// pinnedStrategy selects the only candidate it is given
+type pinnedStrategy struct{}
+
+// Next returns the first proxy
+func (pinnedStrategy) Next(ctx context.Context, proxies []*domain.Proxy) (*domain.Proxy, error) {
+	if len(proxies) == 0 {
+		return nil, rotation.ErrNoProxiesAvailable
+	}
+	return proxies[0], nil
+}
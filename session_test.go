@@ -0,0 +1,302 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/greysquirr3l/lashes/internal/domain"
+)
+
+func TestSessionAffinity(t *testing.T) {
+	ctx := context.Background()
+
+	newSessionRotator := func(t *testing.T, opts Options) *rotator {
+		t.Helper()
+		opts.ValidateOnStart = false
+
+		r, err := newRotator(opts)
+		if err != nil {
+			t.Fatalf("newRotator failed: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			if err := r.AddProxy(ctx, fmt.Sprintf("http://proxy%d.example.com:8080", i), HTTP); err != nil {
+				t.Fatalf("AddProxy failed: %v", err)
+			}
+		}
+		return r
+	}
+
+	t.Run("Session keeps its proxy", func(t *testing.T) {
+		r := newSessionRotator(t, DefaultOptions())
+
+		pinned, err := r.GetProxyForSession(ctx, "checkout")
+		if err != nil {
+			t.Fatalf("GetProxyForSession failed: %v", err)
+		}
+		for i := 0; i < 5; i++ {
+			if _, err := r.GetProxy(ctx); err != nil {
+				t.Fatalf("GetProxy failed: %v", err)
+			}
+			proxy, err := r.GetProxyForSession(ctx, "checkout")
+			if err != nil {
+				t.Fatalf("GetProxyForSession failed: %v", err)
+			}
+			if proxy.ID != pinned.ID {
+				t.Fatalf("GetProxyForSession() = %s, want pinned proxy %s", proxy.ID, pinned.ID)
+			}
+		}
+
+		if _, err := r.GetProxyForSession(ctx, ""); !errors.Is(err, ErrInvalidSessionKey) {
+			t.Errorf("GetProxyForSession(\"\") error = %v, want %v", err, ErrInvalidSessionKey)
+		}
+	})
+
+	t.Run("Disabled proxy moves the session", func(t *testing.T) {
+		r := newSessionRotator(t, DefaultOptions())
+
+		pinned, err := r.GetProxyForSession(ctx, "login")
+		if err != nil {
+			t.Fatalf("GetProxyForSession failed: %v", err)
+		}
+		pinned.Enabled = false
+
+		moved, err := r.GetProxyForSession(ctx, "login")
+		if err != nil {
+			t.Fatalf("GetProxyForSession failed: %v", err)
+		}
+		if moved.ID == pinned.ID {
+			t.Fatal("session kept its disabled proxy")
+		}
+
+		// The session stays on its new proxy once the old one is back
+		pinned.Enabled = true
+		if proxy, _ := r.GetProxyForSession(ctx, "login"); proxy.ID != moved.ID {
+			t.Errorf("GetProxyForSession() = %s, want %s", proxy.ID, moved.ID)
+		}
+	})
+
+	t.Run("Open breaker moves the session", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.CircuitBreaker = &CircuitBreakerConfig{MaxFailures: 1, ResetTimeout: time.Minute}
+		r := newSessionRotator(t, opts)
+
+		pinned, err := r.GetProxyForSession(ctx, "cart")
+		if err != nil {
+			t.Fatalf("GetProxyForSession failed: %v", err)
+		}
+		r.breakers.Load().ForceOpen(pinned.ID)
+
+		moved, err := r.GetProxyForSession(ctx, "cart")
+		if err != nil {
+			t.Fatalf("GetProxyForSession failed: %v", err)
+		}
+		if moved.ID == pinned.ID {
+			t.Error("session kept a proxy with an open breaker")
+		}
+	})
+
+	t.Run("Sessions expire", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.SessionTTL = 20 * time.Millisecond
+		r := newSessionRotator(t, opts)
+
+		pinned, err := r.GetProxyForSession(ctx, "browse")
+		if err != nil {
+			t.Fatalf("GetProxyForSession failed: %v", err)
+		}
+
+		time.Sleep(40 * time.Millisecond)
+
+		// Round-robin hands the expired session the next proxy
+		proxy, err := r.GetProxyForSession(ctx, "browse")
+		if err != nil {
+			t.Fatalf("GetProxyForSession failed: %v", err)
+		}
+		if proxy.ID == pinned.ID {
+			t.Error("expired session kept its proxy")
+		}
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		r := newSessionRotator(t, DefaultOptions())
+
+		pinned, err := r.GetProxyForSession(ctx, "search")
+		if err != nil {
+			t.Fatalf("GetProxyForSession failed: %v", err)
+		}
+		if err := r.ReleaseSession(ctx, "search"); err != nil {
+			t.Fatalf("ReleaseSession failed: %v", err)
+		}
+		if err := r.ReleaseSession(ctx, "search"); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("second ReleaseSession() error = %v, want %v", err, ErrSessionNotFound)
+		}
+
+		proxy, err := r.GetProxyForSession(ctx, "search")
+		if err != nil {
+			t.Fatalf("GetProxyForSession failed: %v", err)
+		}
+		if proxy.ID == pinned.ID {
+			t.Error("released session kept its proxy")
+		}
+	})
+
+	t.Run("Concurrent first requests share the pin", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.Strategy = RandomStrategy
+		r := newSessionRotator(t, opts)
+		// Every request misses the lookup before any of them pins the session
+		r.sessions = slowSessionRepository{SessionRepository: r.sessions, delay: 20 * time.Millisecond}
+
+		const workers = 8
+		ids := make([]string, workers)
+		var wg sync.WaitGroup
+		for i := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				proxy, err := r.GetProxyForSession(ctx, "cart")
+				if err != nil {
+					t.Errorf("GetProxyForSession failed: %v", err)
+					return
+				}
+				ids[i] = proxy.ID
+			}()
+		}
+		wg.Wait()
+
+		for _, id := range ids {
+			if id != ids[0] {
+				t.Fatalf("concurrent first requests used proxies %v, want one", ids)
+			}
+		}
+		if len(r.sessionPins.locks) != 0 {
+			t.Errorf("%d session locks left after the requests", len(r.sessionPins.locks))
+		}
+	})
+}
+
+// slowSessionRepository delays the results of session lookups
+type slowSessionRepository struct {
+	domain.SessionRepository
+	delay time.Duration
+}
+
+func (s slowSessionRepository) GetSession(ctx context.Context, key string) (*domain.Session, error) {
+	session, err := s.SessionRepository.GetSession(ctx, key)
+	time.Sleep(s.delay)
+	return session, err
+}
+
+func TestRotatingClientSession(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	var hitsA, hitsB int32
+	for _, srv := range []*httptest.Server{newTestProxyServer(t, "a", &hitsA), newTestProxyServer(t, "b", &hitsB)} {
+		if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+
+	httpClient := r.RotatingClient()
+	sessionCtx := WithSession(ctx, "user-42")
+
+	var exits []string
+	for i := 0; i < 4; i++ {
+		req, err := http.NewRequestWithContext(sessionCtx, http.MethodGet, "http://target.example.com/", nil)
+		if err != nil {
+			t.Fatalf("NewRequest failed: %v", err)
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		exits = append(exits, resp.Header.Get("X-Proxy"))
+	}
+
+	for _, exit := range exits[1:] {
+		if exit != exits[0] {
+			t.Fatalf("session requests left through %v, want a single proxy", exits)
+		}
+	}
+
+	if sessionKey, ok := SessionFromContext(sessionCtx); !ok || sessionKey != "user-42" {
+		t.Errorf("SessionFromContext() = %q, %v; want user-42, true", sessionKey, ok)
+	}
+}
+
+func TestRotatingClientSessionRetry(t *testing.T) {
+	ctx := context.Background()
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.RetryDelay = time.Millisecond
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	// Each proxy answers its first request with a 503
+	hits := make(map[string]*int32)
+	for _, name := range []string{"a", "b"} {
+		count := new(int32)
+		hits[name] = count
+		srv := newBlockingProxyServer(t, name, count, func(w http.ResponseWriter, req *http.Request) {
+			if atomic.LoadInt32(count) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+
+		if err := r.AddProxy(ctx, srv.URL, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+
+	httpClient := r.RotatingClient()
+	sessionCtx := WithSession(ctx, "checkout")
+
+	var exits []string
+	for i := 0; i < 4; i++ {
+		req, err := http.NewRequestWithContext(sessionCtx, http.MethodGet, "http://target.example.com/", nil)
+		if err != nil {
+			t.Fatalf("NewRequest failed: %v", err)
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want the retry to succeed", i, resp.StatusCode)
+		}
+		exits = append(exits, resp.Header.Get("X-Proxy"))
+	}
+
+	for _, exit := range exits[1:] {
+		if exit != exits[0] {
+			t.Fatalf("session requests left through %v, want the retry to keep the pinned proxy", exits)
+		}
+	}
+
+	for name, count := range hits {
+		if name != exits[0] && atomic.LoadInt32(count) != 0 {
+			t.Errorf("proxy %s got %d requests, want none", name, atomic.LoadInt32(count))
+		}
+	}
+}
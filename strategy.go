@@ -0,0 +1,62 @@
+package lashes
+
+import (
+	"fmt"
+
+	"github.com/greysquirr3l/lashes/internal/rotation"
+)
+
+// Strategy picks the next proxy from the candidates that passed selection.
+// Strategies that also implement StrategyObserver are fed the outcome of
+// every rotating-client request, and those implementing CriteriaStrategy are
+// given the selection criteria, including the target host.
+type Strategy = rotation.Strategy
+
+// Strategy related types
+type (
+	StrategyType     = rotation.StrategyType
+	StrategyFactory  = rotation.Factory
+	StrategyFeedback = rotation.Feedback
+	StrategyObserver = rotation.Observer
+	CriteriaStrategy = rotation.CriteriaStrategy
+)
+
+// Built-in rotation strategies
+const (
+	RoundRobinStrategy     = rotation.RoundRobinStrategy
+	RandomStrategy         = rotation.RandomStrategy
+	WeightedStrategy       = rotation.WeightedStrategy
+	SmoothWeightedStrategy = rotation.SmoothWeightedStrategy
+	LeastUsedStrategy      = rotation.LeastUsedStrategy
+	LatencyStrategy        = rotation.LatencyStrategy
+	HashStrategy           = rotation.HashStrategy
+	ThompsonStrategy       = rotation.ThompsonStrategy
+	ThompsonHostStrategy   = rotation.ThompsonHostStrategy
+)
+
+// Strategy related errors
+var (
+	ErrInvalidStrategy = rotation.ErrInvalidStrategy
+	ErrStrategyExists  = rotation.ErrStrategyExists
+)
+
+// RegisterStrategy makes a custom strategy available under name, for
+// Options.Strategy, the strategy setting of LoadConfig and LASHES_STRATEGY.
+// The factory is called for the rotator and for every pool, so each gets its
+// own instance. Register strategies before loading configuration that names them.
+func RegisterStrategy(name StrategyType, factory StrategyFactory) error {
+	return rotation.Register(name, factory)
+}
+
+// newStrategy creates a strategy instance for the rotator or one of its
+// pools, from Options.CustomStrategy when set and Options.Strategy otherwise
+func newStrategy(opts Options) (Strategy, error) {
+	if opts.CustomStrategy == nil {
+		return rotation.NewStrategy(opts.Strategy)
+	}
+
+	if strategy := opts.CustomStrategy(); strategy != nil {
+		return strategy, nil
+	}
+	return nil, fmt.Errorf("%w: CustomStrategy returned nil", ErrInvalidStrategy)
+}
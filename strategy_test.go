@@ -0,0 +1,179 @@
+package lashes
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// lastStrategy always picks the candidate with the greatest URL
+// and counts the outcomes it is fed
+type lastStrategy struct {
+	observed atomic.Int32
+}
+
+func (s *lastStrategy) Next(ctx context.Context, proxies []*Proxy) (*Proxy, error) {
+	if len(proxies) == 0 {
+		return nil, ErrNoProxiesAvailable
+	}
+	last := proxies[0]
+	for _, proxy := range proxies[1:] {
+		if proxy.URL > last.URL {
+			last = proxy
+		}
+	}
+	return last, nil
+}
+
+func (s *lastStrategy) Observe(feedback StrategyFeedback) {
+	s.observed.Add(1)
+}
+
+var lastInstances atomic.Int32
+
+// registerLastStrategy registers the test strategy once per test binary
+func registerLastStrategy(t *testing.T) {
+	t.Helper()
+	err := RegisterStrategy("test-last", func() Strategy {
+		lastInstances.Add(1)
+		return &lastStrategy{}
+	})
+	if err != nil && !errors.Is(err, ErrStrategyExists) {
+		t.Fatalf("RegisterStrategy failed: %v", err)
+	}
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	ctx := context.Background()
+	registerLastStrategy(t)
+
+	if err := RegisterStrategy("test-last", func() Strategy { return &lastStrategy{} }); !errors.Is(err, ErrStrategyExists) {
+		t.Errorf("duplicate RegisterStrategy error = %v, want %v", err, ErrStrategyExists)
+	}
+	if err := RegisterStrategy(RoundRobinStrategy, func() Strategy { return &lastStrategy{} }); !errors.Is(err, ErrStrategyExists) {
+		t.Errorf("built-in RegisterStrategy error = %v, want %v", err, ErrStrategyExists)
+	}
+	if err := RegisterStrategy("test-nil", nil); !errors.Is(err, ErrInvalidStrategy) {
+		t.Errorf("nil factory error = %v, want %v", err, ErrInvalidStrategy)
+	}
+
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.Strategy = "test-last"
+
+	before := lastInstances.Load()
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+
+	for _, u := range []string{"http://a.example.com:8080", "http://b.example.com:8080"} {
+		if err := r.AddProxy(ctx, u, HTTP); err != nil {
+			t.Fatalf("AddProxy failed: %v", err)
+		}
+	}
+	proxies, _ := r.List(ctx)
+	want := "http://b.example.com:8080"
+
+	for i := 0; i < 3; i++ {
+		proxy, err := r.GetProxy(ctx)
+		if err != nil {
+			t.Fatalf("GetProxy failed: %v", err)
+		}
+		if proxy.URL != want {
+			t.Errorf("GetProxy() = %s, want %s", proxy.URL, want)
+		}
+	}
+
+	if err := r.CreatePool(ctx, "eu"); err != nil {
+		t.Fatalf("CreatePool failed: %v", err)
+	}
+	if err := r.AddToPool(ctx, "eu", proxies[0].ID); err != nil {
+		t.Fatalf("AddToPool failed: %v", err)
+	}
+	if _, err := r.GetNextFromPool(ctx, "eu"); err != nil {
+		t.Fatalf("GetNextFromPool failed: %v", err)
+	}
+	if got := lastInstances.Load() - before; got != 2 {
+		t.Errorf("factory called %d times, want once for the rotator and once for the pool", got)
+	}
+}
+
+func TestCustomStrategy(t *testing.T) {
+	ctx := context.Background()
+
+	var instances []*lastStrategy
+	opts := DefaultOptions()
+	opts.ValidateOnStart = false
+	opts.Strategy = "not-registered"
+	opts.CustomStrategy = func() Strategy {
+		strategy := &lastStrategy{}
+		instances = append(instances, strategy)
+		return strategy
+	}
+
+	r, err := newRotator(opts)
+	if err != nil {
+		t.Fatalf("newRotator failed: %v", err)
+	}
+	if err := r.AddProxy(ctx, "http://a.example.com:8080", HTTP); err != nil {
+		t.Fatalf("AddProxy failed: %v", err)
+	}
+	proxies, _ := r.List(ctx)
+	if err := r.CreatePool(ctx, "eu"); err != nil {
+		t.Fatalf("CreatePool failed: %v", err)
+	}
+	if err := r.AddToPool(ctx, "eu", proxies[0].ID); err != nil {
+		t.Fatalf("AddToPool failed: %v", err)
+	}
+	if _, err := r.GetNextFromPool(ctx, "eu"); err != nil {
+		t.Fatalf("GetNextFromPool failed: %v", err)
+	}
+
+	// The rotator and the pool each get their own instance
+	if len(instances) != 2 {
+		t.Fatalf("CustomStrategy called %d times, want once for the rotator and once for the pool", len(instances))
+	}
+
+	r.observe(StrategyFeedback{ProxyID: proxies[0].ID, Success: true})
+	for i, strategy := range instances {
+		if got := strategy.observed.Load(); got != 1 {
+			t.Errorf("instance %d: Observe called %d times, want 1", i, got)
+		}
+	}
+
+	opts.CustomStrategy = func() Strategy { return nil }
+	if _, err := newRotator(opts); !errors.Is(err, ErrInvalidStrategy) {
+		t.Errorf("newRotator with a nil CustomStrategy: error = %v, want %v", err, ErrInvalidStrategy)
+	}
+}
+
+func TestCustomStrategyConfig(t *testing.T) {
+	registerLastStrategy(t)
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"strategy": "test-last"}`), 0o600); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	opts, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if opts.Strategy != "test-last" {
+		t.Errorf("LoadConfig Strategy = %s, want test-last", opts.Strategy)
+	}
+
+	t.Setenv("LASHES_STRATEGY", "test-last")
+	if opts := LoadConfigFromEnv(); opts.Strategy != "test-last" {
+		t.Errorf("LoadConfigFromEnv Strategy = %s, want test-last", opts.Strategy)
+	}
+
+	t.Setenv("LASHES_STRATEGY", "not-registered")
+	if opts := LoadConfigFromEnv(); opts.Strategy != RoundRobinStrategy {
+		t.Errorf("unknown LASHES_STRATEGY gave %s, want the default %s", opts.Strategy, RoundRobinStrategy)
+	}
+}